@@ -0,0 +1,277 @@
+package modulego
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// WithAllowList is a functional option to set the IPs and CIDRs of the requests that are never sent to the Protection API.
+// The client IDs are rejected: they are read from the `x-datadome-clientid` header and the `datadome` cookie,
+// which are controlled by the clients.
+func WithAllowList(entries ...string) Option {
+	return func(c *Client) {
+		c.AllowList = entries
+	}
+}
+
+// WithAllowListFile is a functional option to set the file listing the IPs and CIDRs of the requests
+// that are never sent to the Protection API, in addition to the AllowList.
+// The file contains one entry per line; the empty lines and the lines starting with `#` are ignored.
+// It is read again by [Client.Update] and [Client.WatchAccessLists].
+func WithAllowListFile(path string) Option {
+	return func(c *Client) {
+		c.AllowListFile = path
+	}
+}
+
+// WithDenyList is a functional option to set the IPs, CIDRs and client IDs of the requests that are blocked without calling the Protection API.
+func WithDenyList(entries ...string) Option {
+	return func(c *Client) {
+		c.DenyList = entries
+	}
+}
+
+// WithDenyListFile is a functional option to set the file listing the IPs, CIDRs and client IDs of the requests
+// that are blocked without calling the Protection API, in addition to the DenyList.
+// The file has the format described by [WithAllowListFile], and may also list client IDs.
+func WithDenyListFile(path string) Option {
+	return func(c *Client) {
+		c.DenyListFile = path
+	}
+}
+
+// WithDenyResponse is a functional option to set the response of the requests matching the DenyList.
+func WithDenyResponse(statusCode int, body string) Option {
+	return func(c *Client) {
+		c.DenyStatusCode = statusCode
+		c.DenyBody = body
+	}
+}
+
+// WatchAccessLists checks the AllowListFile and the DenyListFile every interval
+// and reloads the lists with [Client.Update] when one of them changes.
+// An invalid or unreadable file is logged and the current lists are kept.
+//
+// WatchAccessLists blocks until ctx is done and returns its error.
+func (c *Client) WatchAccessLists(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("interval must be a positive duration")
+	}
+	s, err := c.load()
+	if err != nil {
+		return err
+	}
+	last := accessListFilesState(s)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		state := accessListFilesState(c.current())
+		if state == last {
+			continue
+		}
+		last = state
+
+		if err := c.Update(); err != nil {
+			c.current().logger.Error("fail to reload the access lists, keeping the current ones: ", err)
+		}
+	}
+}
+
+// accessListFilesState returns the path, the modification time and the size of the access list files.
+func accessListFilesState(s *settings) string {
+	var state strings.Builder
+	for _, path := range []string{s.allowListFile, s.denyListFile} {
+		if path == "" {
+			continue
+		}
+		state.WriteString(path)
+		if info, err := os.Stat(path); err == nil {
+			fmt.Fprintf(&state, " %d %d", info.ModTime().UnixNano(), info.Size())
+		}
+		state.WriteByte('\n')
+	}
+	return state.String()
+}
+
+// accessList matches the IP and the client ID of the requests against a list of entries.
+type accessList struct {
+	// entries are the entries of the list and of the file.
+	entries   []string
+	networks  *cidrTrie
+	clientIDs map[string]struct{}
+}
+
+// compileAccessList returns the list of the entries and of the lines of the file.
+// The name of the list prefixes the returned errors.
+// The client IDs are rejected unless allowClientIDs is true.
+func compileAccessList(name string, entries []string, path string, allowClientIDs bool) (*accessList, []error) {
+	var errs []error
+	if path != "" {
+		lines, err := readAccessListFile(path)
+		if err != nil {
+			return nil, []error{fmt.Errorf("%sFile must be readable: %w", name, err)}
+		}
+		entries = append(append([]string{}, entries...), lines...)
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	l := &accessList{
+		entries:   entries,
+		networks:  &cidrTrie{},
+		clientIDs: map[string]struct{}{},
+	}
+	for _, entry := range entries {
+		if strings.ContainsAny(entry, "/.:") {
+			network, err := parseCIDR(entry)
+			if err == nil {
+				l.networks.insert(network, entry)
+				continue
+			}
+		}
+		validClientID := entry != "" && !strings.ContainsAny(entry, "/ \t")
+		switch {
+		case allowClientIDs && validClientID:
+			l.clientIDs[entry] = struct{}{}
+		case allowClientIDs:
+			errs = append(errs, fmt.Errorf("%s must contain valid IPs, CIDRs or client IDs: %q", name, entry))
+		case validClientID:
+			errs = append(errs, fmt.Errorf("%s must contain valid IPs or CIDRs, the client IDs being controlled by the clients: %q", name, entry))
+		default:
+			errs = append(errs, fmt.Errorf("%s must contain valid IPs or CIDRs: %q", name, entry))
+		}
+	}
+	return l, errs
+}
+
+// readAccessListFile returns the entries of the file, without the empty lines and the comments.
+func readAccessListFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	return entries, scanner.Err()
+}
+
+// sameEntries reports whether the lists have the same entries, in the same order.
+func (l *accessList) sameEntries(other *accessList) bool {
+	if l == nil || other == nil {
+		return l == other
+	}
+	return reflect.DeepEqual(l.entries, other.entries)
+}
+
+// match returns the entry matching the IP or the client ID of the request.
+// The most specific network is returned when several networks contain the IP.
+func (l *accessList) match(r *http.Request) (string, bool) {
+	if l == nil {
+		return "", false
+	}
+	if ip, err := getIP(r); err == nil {
+		if entry, ok := l.networks.lookup(net.ParseIP(ip)); ok {
+			return entry, true
+		}
+	}
+	if len(l.clientIDs) > 0 {
+		if clientID := getClientId(r); clientID != "" {
+			if _, ok := l.clientIDs[clientID]; ok {
+				return clientID, true
+			}
+		}
+	}
+	return "", false
+}
+
+// accessListDecision returns the decision of the request matching the AllowList or the DenyList, or nil when none matches.
+// The AllowList takes precedence over the DenyList.
+func (s *settings) accessListDecision(r *http.Request) *Decision {
+	if entry, ok := s.allowList.match(r); ok {
+		s.logger.Info("AllowList entry ", entry, " matches request, skipping.")
+		return &Decision{SkipReason: SkipReasonAllowList, ListEntry: entry}
+	}
+	if entry, ok := s.denyList.match(r); ok {
+		s.logger.Info("DenyList entry ", entry, " matches request, blocking.")
+		return &Decision{Blocked: true, StatusCode: s.denyStatusCode, Denied: true, ListEntry: entry, Body: []byte(s.denyBody)}
+	}
+	return nil
+}
+
+// cidrTrie is a binary trie of networks, looked up in a number of steps bounded by the length of the IPs.
+// The IPv4 networks are stored as 4-byte addresses, separately from the IPv6 networks.
+type cidrTrie struct {
+	v4 cidrNode
+	v6 cidrNode
+}
+
+// cidrNode is a node of a [cidrTrie].
+// The entry is not empty when a network ends at the node.
+type cidrNode struct {
+	children [2]*cidrNode
+	entry    string
+}
+
+// insert adds the network identified by the entry.
+func (t *cidrTrie) insert(network *net.IPNet, entry string) {
+	ip, node := network.IP.To4(), &t.v4
+	if ip == nil || len(network.Mask) == net.IPv6len {
+		ip, node = network.IP.To16(), &t.v6
+	}
+	ones, _ := network.Mask.Size()
+	for i := 0; i < ones; i++ {
+		bit := ip[i/8] >> (7 - i%8) & 1
+		if node.children[bit] == nil {
+			node.children[bit] = &cidrNode{}
+		}
+		node = node.children[bit]
+	}
+	if node.entry == "" {
+		node.entry = entry
+	}
+}
+
+// lookup returns the entry of the most specific network containing the IP.
+func (t *cidrTrie) lookup(ip net.IP) (string, bool) {
+	node := &t.v4
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	} else if ip = ip.To16(); ip != nil {
+		node = &t.v6
+	} else {
+		return "", false
+	}
+
+	entry := node.entry
+	for i := 0; i < 8*len(ip) && node != nil; i++ {
+		node = node.children[ip[i/8]>>(7-i%8)&1]
+		if node != nil && node.entry != "" {
+			entry = node.entry
+		}
+	}
+	return entry, entry != ""
+}
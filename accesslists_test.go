@@ -0,0 +1,260 @@
+package modulego
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCIDRTrie(t *testing.T) {
+	trie := &cidrTrie{}
+	for _, entry := range []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.2.3", "2001:db8::/32", "2001:db8:1::/48"} {
+		network, err := parseCIDR(entry)
+		assert.Nil(t, err)
+		trie.insert(network, entry)
+	}
+
+	testCases := []struct {
+		ip       string
+		expected string
+	}{
+		{"10.2.3.4", "10.0.0.0/8"},
+		{"10.1.3.4", "10.1.0.0/16"},
+		{"10.1.2.3", "10.1.2.3"},
+		{"::ffff:10.1.2.3", "10.1.2.3"},
+		{"11.0.0.1", ""},
+		{"2001:db8:2::1", "2001:db8::/32"},
+		{"2001:db8:1::1", "2001:db8:1::/48"},
+		{"2001:db9::1", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.ip, func(t *testing.T) {
+			entry, ok := trie.lookup(net.ParseIP(tc.ip))
+
+			assert.Equal(t, tc.expected, entry)
+			assert.Equal(t, tc.expected != "", ok)
+		})
+	}
+
+	t.Run("Default route", func(t *testing.T) {
+		network, _ := parseCIDR("0.0.0.0/0")
+		trie.insert(network, "0.0.0.0/0")
+
+		entry, ok := trie.lookup(net.ParseIP("192.168.1.1"))
+
+		assert.True(t, ok)
+		assert.Equal(t, "0.0.0.0/0", entry)
+	})
+}
+
+func TestCompileAccessList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "denylist.txt")
+	assert.Nil(t, os.WriteFile(path, []byte("# scrapers\n192.168.0.0/16\n\n  AHrlqAAAAAMA1QGvUmJwyYoAwnd  \n"), 0o600))
+
+	t.Run("Entries and file", func(t *testing.T) {
+		l, errs := compileAccessList("DenyList", []string{"10.0.0.1", "2001:db8::/32"}, path, true)
+
+		assert.Empty(t, errs)
+		assert.Equal(t, map[string]struct{}{"AHrlqAAAAAMA1QGvUmJwyYoAwnd": {}}, l.clientIDs)
+		entry, ok := l.networks.lookup(net.ParseIP("192.168.3.4"))
+		assert.True(t, ok)
+		assert.Equal(t, "192.168.0.0/16", entry)
+	})
+
+	t.Run("Empty list", func(t *testing.T) {
+		l, errs := compileAccessList("AllowList", nil, "", false)
+
+		assert.Empty(t, errs)
+		assert.Nil(t, l)
+	})
+
+	t.Run("Invalid entries", func(t *testing.T) {
+		_, errs := compileAccessList("DenyList", []string{"10.0.0.0/33", "", "client id"}, "", true)
+
+		assert.Equal(t, []string{
+			`DenyList must contain valid IPs, CIDRs or client IDs: "10.0.0.0/33"`,
+			`DenyList must contain valid IPs, CIDRs or client IDs: ""`,
+			`DenyList must contain valid IPs, CIDRs or client IDs: "client id"`,
+		}, errorMessages(errs))
+	})
+
+	t.Run("Client IDs are rejected", func(t *testing.T) {
+		_, errs := compileAccessList("AllowList", []string{"10.0.0.0/8", "trusted-client-id", "10.0.0.0/33"}, "", false)
+
+		assert.Equal(t, []string{
+			`AllowList must contain valid IPs or CIDRs, the client IDs being controlled by the clients: "trusted-client-id"`,
+			`AllowList must contain valid IPs or CIDRs: "10.0.0.0/33"`,
+		}, errorMessages(errs))
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		_, errs := compileAccessList("AllowList", nil, filepath.Join(t.TempDir(), "missing.txt"), false)
+
+		assert.Len(t, errs, 1)
+		assert.ErrorContains(t, errs[0], "AllowListFile must be readable: ")
+	})
+
+	t.Run("Invalid lists are rejected by NewClient", func(t *testing.T) {
+		client, err := NewClient("your-api-key", WithDenyList("10.0.0.0/33"))
+
+		assert.Nil(t, client)
+		var configErr *ConfigError
+		assert.True(t, errors.As(err, &configErr))
+	})
+}
+
+func TestEvaluate_AccessLists(t *testing.T) {
+	logger := &syncLogger{}
+	client, err := NewClient("your-api-key",
+		WithLogger(logger),
+		// the unreachable Protection API fails the requests that are not matched by the lists
+		WithEndpoint("http://127.0.0.1:1/validate-request"),
+		WithAllowList("10.0.0.0/8"),
+		WithDenyList("10.1.0.0/16", "192.168.1.1", "abusive-client-id"),
+		WithDenyResponse(http.StatusTooManyRequests, "denied"),
+	)
+	assert.Nil(t, err)
+
+	newRequest := func(remoteAddr string, clientID string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		r.RemoteAddr = remoteAddr
+		if clientID != "" {
+			r.Header.Set("X-DataDome-ClientID", clientID)
+		}
+		return r
+	}
+
+	t.Run("Allowed IP", func(t *testing.T) {
+		decision, err := client.Evaluate(newRequest("10.2.0.1:1234", ""))
+
+		assert.Nil(t, err)
+		assert.Equal(t, &Decision{SkipReason: SkipReasonAllowList, ListEntry: "10.0.0.0/8"}, decision)
+		assert.Equal(t, "INFO AllowList entry 10.0.0.0/8 matches request, skipping.", logger.last())
+	})
+
+	t.Run("Allowed IP in a denied network", func(t *testing.T) {
+		decision, err := client.Evaluate(newRequest("10.1.0.1:1234", ""))
+
+		assert.Nil(t, err)
+		assert.Equal(t, SkipReasonAllowList, decision.SkipReason)
+	})
+
+	t.Run("Denied IP", func(t *testing.T) {
+		decision, err := client.Evaluate(newRequest("192.168.1.1:1234", ""))
+
+		assert.Nil(t, err)
+		assert.Equal(t, &Decision{Blocked: true, StatusCode: http.StatusTooManyRequests, Denied: true, ListEntry: "192.168.1.1", Body: []byte("denied")}, decision)
+		assert.Equal(t, "INFO DenyList entry 192.168.1.1 matches request, blocking.", logger.last())
+	})
+
+	t.Run("Denied client ID", func(t *testing.T) {
+		r := newRequest("172.16.0.1:1234", "")
+		r.AddCookie(&http.Cookie{Name: "datadome", Value: "abusive-client-id"})
+
+		decision, err := client.Evaluate(r)
+
+		assert.Nil(t, err)
+		assert.True(t, decision.Denied)
+		assert.Equal(t, "abusive-client-id", decision.ListEntry)
+	})
+
+	t.Run("Denied request is blocked by the handler", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		blocked, err := client.DatadomeProtect(rr, newRequest("192.168.1.1:1234", ""))
+
+		assert.Nil(t, err)
+		assert.True(t, blocked)
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "denied", rr.Body.String())
+	})
+
+	t.Run("Other request", func(t *testing.T) {
+		_, err := client.Evaluate(newRequest("172.16.0.1:1234", ""))
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestWatchAccessLists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "denylist.txt")
+	assert.Nil(t, os.WriteFile(path, []byte("192.168.1.1\n"), 0o600))
+	logger := &syncLogger{}
+	client, err := NewClient("your-api-key", WithLogger(logger), WithDenyListFile(path))
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- client.WatchAccessLists(ctx, 10*time.Millisecond)
+	}()
+
+	r := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	r.RemoteAddr = "192.168.1.2:1234"
+	modTime := time.Now()
+	assert.Eventually(t, func() bool {
+		// the file is rewritten until the watcher notices it
+		modTime = modTime.Add(time.Second)
+		if err := os.WriteFile(path, []byte("192.168.1.0/24\n"), 0o600); err != nil {
+			return false
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			return false
+		}
+		entry, ok := client.current().denyList.match(r)
+		return ok && entry == "192.168.1.0/24"
+	}, time.Second, 20*time.Millisecond)
+
+	t.Run("Invalid file", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(path, []byte("192.168.1.0/33\n"), 0o600))
+		assert.Nil(t, os.Chtimes(path, modTime.Add(time.Hour), modTime.Add(time.Hour)))
+
+		assert.Eventually(t, func() bool {
+			return strings.HasPrefix(logger.last(), "ERROR fail to reload the access lists, keeping the current ones: ")
+		}, time.Second, 10*time.Millisecond)
+		_, ok := client.current().denyList.match(r)
+		assert.True(t, ok)
+	})
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	t.Run("Invalid interval", func(t *testing.T) {
+		assert.EqualError(t, client.WatchAccessLists(context.Background(), 0), "interval must be a positive duration")
+	})
+}
+
+func TestConfigAccessLists(t *testing.T) {
+	c := DefaultConfig()
+	c.ServerSideKey = "your-api-key"
+	err := c.Decode(strings.NewReader(`{"allowList": ["10.0.0.0/8"], "denyList": ["abusive-client-id"], "denyStatusCode": 429, "denyBody": "denied"}`))
+	assert.Nil(t, err)
+
+	client, err := NewClientFromConfig(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"10.0.0.0/8"}, client.AllowList)
+	assert.Equal(t, []string{"abusive-client-id"}, client.DenyList)
+	assert.Equal(t, http.StatusTooManyRequests, client.DenyStatusCode)
+	assert.Equal(t, "denied", client.DenyBody)
+
+	t.Run("Invalid configuration", func(t *testing.T) {
+		c := &Config{ServerSideKey: "your-api-key", Endpoint: DefaultEndpointValue, Timeout: 150, MaximumBodySize: 1024, DenyStatusCode: 42, AllowList: []string{"10.0.0.0/33"}}
+
+		var configErr *ConfigError
+		assert.ErrorAs(t, c.Validate(), &configErr)
+		assert.Equal(t, []string{
+			"DenyStatusCode must be a valid HTTP status code",
+			`AllowList must contain valid IPs or CIDRs: "10.0.0.0/33"`,
+		}, errorMessages(configErr.Errors))
+	})
+}
@@ -0,0 +1,100 @@
+package awslambda
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	modulego "github.com/andynuge/datadome-go"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ALBTargetGroupHandler is a Lambda handler processing Application Load Balancer events.
+type ALBTargetGroupHandler func(context.Context, events.ALBTargetGroupRequest) (events.ALBTargetGroupResponse, error)
+
+// NewALBTargetGroupRequest converts an Application Load Balancer event into an [http.Request].
+// The events do not contain the client IP: it is read from the last entry of the `X-Forwarded-For` header, added by the load balancer.
+func NewALBTargetGroupRequest(ctx context.Context, event events.ALBTargetGroupRequest) (*http.Request, error) {
+	header := newHeader(event.Headers, event.MultiValueHeaders)
+	return newRequest(
+		ctx,
+		event.HTTPMethod,
+		event.Path,
+		encodeQuery(event.QueryStringParameters, event.MultiValueQueryStringParameters, false),
+		"",
+		header,
+		lastForwardedFor(header),
+		event.Body,
+		event.IsBase64Encoded,
+	)
+}
+
+// NewALBTargetGroupResponse converts the [modulego.Decision] of a blocked request into an Application Load Balancer response.
+// multiValueHeaders must match the multi-value headers setting of the target group.
+func NewALBTargetGroupResponse(decision *modulego.Decision, multiValueHeaders bool) events.ALBTargetGroupResponse {
+	body, isBase64Encoded := blockedBody(decision.Body)
+	resp := events.ALBTargetGroupResponse{
+		StatusCode:        decision.StatusCode,
+		StatusDescription: fmt.Sprintf("%d %s", decision.StatusCode, http.StatusText(decision.StatusCode)),
+		Body:              body,
+		IsBase64Encoded:   isBase64Encoded,
+	}
+	if multiValueHeaders {
+		resp.MultiValueHeaders = setMultiValueHeaders(nil, decision.ResponseHeaders)
+	} else {
+		resp.Headers = setHeaders(nil, decision.ResponseHeaders)
+	}
+	return resp
+}
+
+// lastForwardedFor returns the last IP of the `X-Forwarded-For` header.
+func lastForwardedFor(header http.Header) string {
+	values := header.Values("X-Forwarded-For")
+	if len(values) == 0 {
+		return ""
+	}
+	ips := strings.Split(values[len(values)-1], ",")
+	return strings.TrimSpace(ips[len(ips)-1])
+}
+
+// ProtectALBTargetGroup returns a handler validating the events with the client before calling next.
+// Blocked requests receive the response returned by the Protection API and next is not called.
+// Errors are logged and the request is allowed (fail open).
+//
+// When the multi-value headers setting of the target group is disabled, a response can contain a single `Set-Cookie` header:
+// the cookie returned by the Protection API replaces the one set by next.
+func ProtectALBTargetGroup(client *modulego.Client, next ALBTargetGroupHandler) ALBTargetGroupHandler {
+	return func(ctx context.Context, event events.ALBTargetGroupRequest) (events.ALBTargetGroupResponse, error) {
+		multiValueHeaders := event.MultiValueHeaders != nil
+
+		r, err := NewALBTargetGroupRequest(ctx, event)
+		if err != nil {
+			client.CurrentLogger().Error("fail to convert ALB event: ", err)
+			return next(ctx, event)
+		}
+		decision, err := client.Evaluate(r)
+		if err != nil {
+			return next(ctx, event)
+		}
+		if decision.Blocked {
+			return NewALBTargetGroupResponse(decision, multiValueHeaders), nil
+		}
+
+		if multiValueHeaders {
+			event.MultiValueHeaders = setMultiValueHeaders(event.MultiValueHeaders, decision.RequestHeaders)
+		} else {
+			event.Headers = setHeaders(event.Headers, decision.RequestHeaders)
+		}
+		resp, err := next(ctx, event)
+		if err != nil {
+			return resp, err
+		}
+		if multiValueHeaders {
+			resp.MultiValueHeaders = setMultiValueHeaders(resp.MultiValueHeaders, decision.ResponseHeaders)
+		} else {
+			resp.Headers = setHeaders(resp.Headers, decision.ResponseHeaders)
+		}
+		return resp, nil
+	}
+}
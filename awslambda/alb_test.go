@@ -0,0 +1,67 @@
+package awslambda
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewALBTargetGroupRequest(t *testing.T) {
+	var event events.ALBTargetGroupRequest
+	loadEvent(t, "alb-request.json", &event)
+
+	r, err := NewALBTargetGroupRequest(context.Background(), event)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "GET", r.Method)
+	assert.Equal(t, "/search", r.URL.Path)
+	assert.Equal(t, "q=red%20shoes", r.URL.RawQuery)
+	assert.Equal(t, "www.example.com", r.Host)
+	assert.Equal(t, "198.51.100.7:0", r.RemoteAddr)
+}
+
+func TestProtectALBTargetGroup(t *testing.T) {
+	t.Run("Blocked request with single-value headers", func(t *testing.T) {
+		var event events.ALBTargetGroupRequest
+		loadEvent(t, "alb-request.json", &event)
+		client, payload := setupClient(t, true)
+		handler := ProtectALBTargetGroup(client, func(ctx context.Context, event events.ALBTargetGroupRequest) (events.ALBTargetGroupResponse, error) {
+			t.Error("next must not be called")
+			return events.ALBTargetGroupResponse{}, nil
+		})
+
+		resp, err := handler(context.Background(), event)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 403, resp.StatusCode)
+		assert.Equal(t, "403 Forbidden", resp.StatusDescription)
+		assert.Equal(t, "datadome=new-client-id", resp.Headers["Set-Cookie"])
+		assert.Nil(t, resp.MultiValueHeaders)
+		assert.Equal(t, "198.51.100.7", payload.Get("IP"))
+	})
+
+	t.Run("Allowed request with multi-value headers", func(t *testing.T) {
+		var event events.ALBTargetGroupRequest
+		loadEvent(t, "alb-request.json", &event)
+		event.MultiValueHeaders = map[string][]string{}
+		for name, value := range event.Headers {
+			event.MultiValueHeaders[name] = []string{value}
+		}
+		event.Headers = nil
+
+		client, payload := setupClient(t, false)
+		handler := ProtectALBTargetGroup(client, func(ctx context.Context, event events.ALBTargetGroupRequest) (events.ALBTargetGroupResponse, error) {
+			assert.Equal(t, []string{"none"}, event.MultiValueHeaders["X-Datadome-Botname"])
+			return events.ALBTargetGroupResponse{StatusCode: 200}, nil
+		})
+
+		resp, err := handler(context.Background(), event)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, []string{"datadome=new-client-id"}, resp.MultiValueHeaders["Set-Cookie"])
+		assert.Equal(t, "198.51.100.7", payload.Get("IP"))
+	})
+}
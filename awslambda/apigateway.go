@@ -0,0 +1,73 @@
+package awslambda
+
+import (
+	"context"
+	"net/http"
+
+	modulego "github.com/andynuge/datadome-go"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// APIGatewayProxyHandler is a Lambda handler processing API Gateway REST API (v1) events.
+type APIGatewayProxyHandler func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
+// NewAPIGatewayProxyRequest converts an API Gateway REST API (v1) event into an [http.Request].
+// The client IP is read from the `requestContext.identity.sourceIp` field.
+func NewAPIGatewayProxyRequest(ctx context.Context, event events.APIGatewayProxyRequest) (*http.Request, error) {
+	return newRequest(
+		ctx,
+		event.HTTPMethod,
+		event.Path,
+		encodeQuery(event.QueryStringParameters, event.MultiValueQueryStringParameters, true),
+		event.RequestContext.DomainName,
+		newHeader(event.Headers, event.MultiValueHeaders),
+		event.RequestContext.Identity.SourceIP,
+		event.Body,
+		event.IsBase64Encoded,
+	)
+}
+
+// NewAPIGatewayProxyResponse converts the [modulego.Decision] of a blocked request into an API Gateway REST API (v1) response.
+func NewAPIGatewayProxyResponse(decision *modulego.Decision) events.APIGatewayProxyResponse {
+	body, isBase64Encoded := blockedBody(decision.Body)
+	return events.APIGatewayProxyResponse{
+		StatusCode:        decision.StatusCode,
+		MultiValueHeaders: setMultiValueHeaders(nil, decision.ResponseHeaders),
+		Body:              body,
+		IsBase64Encoded:   isBase64Encoded,
+	}
+}
+
+// ProtectAPIGatewayProxy returns a handler validating the events with the client before calling next.
+// Blocked requests receive the response returned by the Protection API and next is not called.
+// Errors are logged and the request is allowed (fail open).
+func ProtectAPIGatewayProxy(client *modulego.Client, next APIGatewayProxyHandler) APIGatewayProxyHandler {
+	return func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		r, err := NewAPIGatewayProxyRequest(ctx, event)
+		if err != nil {
+			client.CurrentLogger().Error("fail to convert API Gateway event: ", err)
+			return next(ctx, event)
+		}
+		decision, err := client.Evaluate(r)
+		if err != nil {
+			return next(ctx, event)
+		}
+		if decision.Blocked {
+			return NewAPIGatewayProxyResponse(decision), nil
+		}
+
+		event.Headers = setHeaders(event.Headers, decision.RequestHeaders)
+		if event.MultiValueHeaders != nil {
+			event.MultiValueHeaders = setMultiValueHeaders(event.MultiValueHeaders, decision.RequestHeaders)
+		}
+		resp, err := next(ctx, event)
+		if err != nil {
+			return resp, err
+		}
+		for name := range decision.ResponseHeaders {
+			deleteHeader(resp.Headers, name)
+		}
+		resp.MultiValueHeaders = setMultiValueHeaders(resp.MultiValueHeaders, decision.ResponseHeaders)
+		return resp, nil
+	}
+}
@@ -0,0 +1,82 @@
+package awslambda
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAPIGatewayProxyRequest(t *testing.T) {
+	var event events.APIGatewayProxyRequest
+	loadEvent(t, "apigateway-request.json", &event)
+
+	r, err := NewAPIGatewayProxyRequest(context.Background(), event)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "POST", r.Method)
+	assert.Equal(t, "/login", r.URL.Path)
+	assert.Equal(t, "redirect=%2Fhome+page", r.URL.RawQuery)
+	assert.Equal(t, "www.example.com", r.Host)
+	assert.Equal(t, "198.51.100.7:0", r.RemoteAddr)
+	assert.Equal(t, "Mozilla/5.0", r.Header.Get("User-Agent"))
+	assert.Equal(t, int64(27), r.ContentLength)
+	body, _ := io.ReadAll(r.Body)
+	assert.Equal(t, "username=john&password=doe", string(body))
+
+	t.Run("Invalid source IP", func(t *testing.T) {
+		event.RequestContext.Identity.SourceIP = "test-invoke-source-ip"
+
+		_, err := NewAPIGatewayProxyRequest(context.Background(), event)
+
+		assert.ErrorContains(t, err, "invalid source IP")
+	})
+}
+
+func TestProtectAPIGatewayProxy(t *testing.T) {
+	var event events.APIGatewayProxyRequest
+	loadEvent(t, "apigateway-request.json", &event)
+
+	t.Run("Blocked request", func(t *testing.T) {
+		client, payload := setupClient(t, true)
+		called := false
+		handler := ProtectAPIGatewayProxy(client, func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+			called = true
+			return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+		})
+
+		resp, err := handler(context.Background(), event)
+
+		assert.Nil(t, err)
+		assert.False(t, called)
+		assert.Equal(t, 403, resp.StatusCode)
+		assert.Equal(t, "<html>blocked</html>", resp.Body)
+		assert.Equal(t, []string{"datadome=new-client-id"}, resp.MultiValueHeaders["Set-Cookie"])
+		assert.Equal(t, []string{"1"}, resp.MultiValueHeaders["X-Dd-B"])
+		assert.Equal(t, "198.51.100.7", payload.Get("IP"))
+		assert.Equal(t, "https", payload.Get("Protocol"))
+		assert.Equal(t, "client-id", payload.Get("ClientID"))
+	})
+
+	t.Run("Allowed request", func(t *testing.T) {
+		client, _ := setupClient(t, false)
+		handler := ProtectAPIGatewayProxy(client, func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+			assert.Equal(t, "none", event.Headers["X-Datadome-Botname"])
+			assert.Equal(t, []string{"none"}, event.MultiValueHeaders["X-Datadome-Botname"])
+			return events.APIGatewayProxyResponse{
+				StatusCode:        200,
+				MultiValueHeaders: map[string][]string{"Set-Cookie": {"session=abc"}},
+				Body:              "welcome",
+			}, nil
+		})
+
+		resp, err := handler(context.Background(), event)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, "welcome", resp.Body)
+		assert.Equal(t, []string{"session=abc", "datadome=new-client-id"}, resp.MultiValueHeaders["Set-Cookie"])
+	})
+}
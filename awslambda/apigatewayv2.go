@@ -0,0 +1,90 @@
+package awslambda
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	modulego "github.com/andynuge/datadome-go"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// APIGatewayV2HTTPHandler is a Lambda handler processing API Gateway HTTP API (v2) events.
+type APIGatewayV2HTTPHandler func(context.Context, events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error)
+
+// NewAPIGatewayV2HTTPRequest converts an API Gateway HTTP API (v2) event into an [http.Request].
+// The client IP is read from the `requestContext.http.sourceIp` field.
+func NewAPIGatewayV2HTTPRequest(ctx context.Context, event events.APIGatewayV2HTTPRequest) (*http.Request, error) {
+	header := newHeader(event.Headers, nil)
+	if len(event.Cookies) > 0 {
+		header.Set("Cookie", strings.Join(event.Cookies, "; "))
+	}
+	return newRequest(
+		ctx,
+		event.RequestContext.HTTP.Method,
+		event.RawPath,
+		event.RawQueryString,
+		event.RequestContext.DomainName,
+		header,
+		event.RequestContext.HTTP.SourceIP,
+		event.Body,
+		event.IsBase64Encoded,
+	)
+}
+
+// NewAPIGatewayV2HTTPResponse converts the [modulego.Decision] of a blocked request into an API Gateway HTTP API (v2) response.
+func NewAPIGatewayV2HTTPResponse(decision *modulego.Decision) events.APIGatewayV2HTTPResponse {
+	body, isBase64Encoded := blockedBody(decision.Body)
+	resp := events.APIGatewayV2HTTPResponse{
+		StatusCode:      decision.StatusCode,
+		Body:            body,
+		IsBase64Encoded: isBase64Encoded,
+	}
+	setV2ResponseHeaders(&resp, decision.ResponseHeaders)
+	return resp
+}
+
+// setV2ResponseHeaders adds the headers to the response.
+// The `Set-Cookie` headers are added to the cookies of the response, the other headers are replaced.
+func setV2ResponseHeaders(resp *events.APIGatewayV2HTTPResponse, headers http.Header) {
+	for name, values := range headers {
+		if strings.EqualFold(name, "Set-Cookie") {
+			resp.Cookies = append(resp.Cookies, values...)
+			continue
+		}
+		if resp.Headers == nil {
+			resp.Headers = map[string]string{}
+		}
+		deleteHeader(resp.Headers, name)
+		deleteHeader(resp.MultiValueHeaders, name)
+		resp.Headers[name] = strings.Join(values, ",")
+	}
+}
+
+// ProtectAPIGatewayV2HTTP returns a handler validating the events with the client before calling next.
+// Blocked requests receive the response returned by the Protection API and next is not called.
+// Errors are logged and the request is allowed (fail open).
+func ProtectAPIGatewayV2HTTP(client *modulego.Client, next APIGatewayV2HTTPHandler) APIGatewayV2HTTPHandler {
+	return func(ctx context.Context, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		r, err := NewAPIGatewayV2HTTPRequest(ctx, event)
+		if err != nil {
+			client.CurrentLogger().Error("fail to convert API Gateway event: ", err)
+			return next(ctx, event)
+		}
+		decision, err := client.Evaluate(r)
+		if err != nil {
+			return next(ctx, event)
+		}
+		if decision.Blocked {
+			return NewAPIGatewayV2HTTPResponse(decision), nil
+		}
+
+		event.Headers = setHeaders(event.Headers, decision.RequestHeaders)
+		resp, err := next(ctx, event)
+		if err != nil {
+			return resp, err
+		}
+		setV2ResponseHeaders(&resp, decision.ResponseHeaders)
+		return resp, nil
+	}
+}
@@ -0,0 +1,64 @@
+package awslambda
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAPIGatewayV2HTTPRequest(t *testing.T) {
+	var event events.APIGatewayV2HTTPRequest
+	loadEvent(t, "apigatewayv2-request.json", &event)
+
+	r, err := NewAPIGatewayV2HTTPRequest(context.Background(), event)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "GET", r.Method)
+	assert.Equal(t, "/products/42", r.URL.Path)
+	assert.Equal(t, "color=red&size=m", r.URL.RawQuery)
+	assert.Equal(t, "api.example.com", r.Host)
+	assert.Equal(t, "198.51.100.7:0", r.RemoteAddr)
+	assert.Equal(t, "datadome=client-id; session=abc", r.Header.Get("Cookie"))
+}
+
+func TestProtectAPIGatewayV2HTTP(t *testing.T) {
+	var event events.APIGatewayV2HTTPRequest
+	loadEvent(t, "apigatewayv2-request.json", &event)
+
+	t.Run("Blocked request", func(t *testing.T) {
+		client, payload := setupClient(t, true)
+		called := false
+		handler := ProtectAPIGatewayV2HTTP(client, func(ctx context.Context, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+			called = true
+			return events.APIGatewayV2HTTPResponse{StatusCode: 200}, nil
+		})
+
+		resp, err := handler(context.Background(), event)
+
+		assert.Nil(t, err)
+		assert.False(t, called)
+		assert.Equal(t, 403, resp.StatusCode)
+		assert.Equal(t, "<html>blocked</html>", resp.Body)
+		assert.Equal(t, []string{"datadome=new-client-id"}, resp.Cookies)
+		assert.Equal(t, "1", resp.Headers["X-Dd-B"])
+		assert.Equal(t, "198.51.100.7", payload.Get("IP"))
+		assert.Equal(t, "/products/42?color=red&size=m", payload.Get("Request"))
+		assert.Equal(t, "datadome,session", payload.Get("CookiesList"))
+	})
+
+	t.Run("Allowed request", func(t *testing.T) {
+		client, _ := setupClient(t, false)
+		handler := ProtectAPIGatewayV2HTTP(client, func(ctx context.Context, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+			assert.Equal(t, "none", event.Headers["X-Datadome-Botname"])
+			return events.APIGatewayV2HTTPResponse{StatusCode: 200, Cookies: []string{"session=abc"}}, nil
+		})
+
+		resp, err := handler(context.Background(), event)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, []string{"session=abc", "datadome=new-client-id"}, resp.Cookies)
+	})
+}
@@ -0,0 +1,172 @@
+// Package awslambda protects AWS Lambda functions invoked by API Gateway or by an Application Load Balancer.
+//
+// The events are converted into [http.Request] values evaluated by a [modulego.Client].
+// The client IP is read from the event (`sourceIp` for API Gateway) instead of the RemoteAddr of a connection.
+// When a request is blocked, the [modulego.Decision] is converted into the response event expected by the integration.
+//
+// Example:
+//
+//	client, _ := modulego.NewClient("your-server-side-key")
+//	lambda.Start(awslambda.ProtectAPIGatewayV2HTTP(client, handler))
+package awslambda
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// newRequest builds the [http.Request] matching the attributes of an event.
+func newRequest(ctx context.Context, method, path, rawQuery, host string, header http.Header, sourceIP, body string, isBase64Encoded bool) (*http.Request, error) {
+	if net.ParseIP(sourceIP) == nil {
+		return nil, fmt.Errorf("invalid source IP: %q", sourceIP)
+	}
+
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("fail to parse path: %w", err)
+	}
+	u.RawQuery = rawQuery
+
+	decodedBody := []byte(body)
+	if isBase64Encoded {
+		decodedBody, err = base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return nil, fmt.Errorf("fail to decode body: %w", err)
+		}
+	}
+
+	r, err := http.NewRequestWithContext(ctx, method, u.String(), strings.NewReader(string(decodedBody)))
+	if err != nil {
+		return nil, err
+	}
+	r.Header = header
+	r.Host = header.Get("Host")
+	if r.Host == "" {
+		r.Host = host
+	}
+	header.Del("Host")
+	r.RemoteAddr = net.JoinHostPort(sourceIP, "0")
+	r.RequestURI = u.RequestURI()
+	if contentLength, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64); err == nil {
+		r.ContentLength = contentLength
+	}
+
+	return r, nil
+}
+
+// newHeader returns the [http.Header] matching the headers of an event.
+// The multi-value headers are used when they are defined.
+func newHeader(headers map[string]string, multiValueHeaders map[string][]string) http.Header {
+	header := http.Header{}
+	if len(multiValueHeaders) > 0 {
+		for name, values := range multiValueHeaders {
+			for _, value := range values {
+				header.Add(name, value)
+			}
+		}
+		return header
+	}
+	for name, value := range headers {
+		header.Set(name, value)
+	}
+	return header
+}
+
+// encodeQuery returns the query string matching the query parameters of an event.
+// When escape is false, the parameters are considered as already URL-encoded.
+func encodeQuery(params map[string]string, multiValueParams map[string][]string, escape bool) string {
+	values := url.Values{}
+	if len(multiValueParams) > 0 {
+		values = multiValueParams
+	} else {
+		for key, value := range params {
+			values.Set(key, value)
+		}
+	}
+	if escape {
+		return values.Encode()
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		for _, value := range values[key] {
+			parts = append(parts, key+"="+value)
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// deleteHeader removes the header from the map, regardless of the case of its name.
+func deleteHeader[V any](m map[string]V, name string) {
+	for key := range m {
+		if strings.EqualFold(key, name) {
+			delete(m, key)
+		}
+	}
+}
+
+// setHeaders sets the headers in the single-value map of an event, replacing the existing values.
+// Only the last value of each header is kept.
+func setHeaders(m map[string]string, headers http.Header) map[string]string {
+	if len(headers) == 0 {
+		return m
+	}
+	if m == nil {
+		m = map[string]string{}
+	}
+	for name, values := range headers {
+		deleteHeader(m, name)
+		m[name] = values[len(values)-1]
+	}
+	return m
+}
+
+// setMultiValueHeaders sets the headers in the multi-value map of an event.
+// The `Set-Cookie` headers are appended to the existing ones, the other headers are replaced.
+func setMultiValueHeaders(m map[string][]string, headers http.Header) map[string][]string {
+	if len(headers) == 0 {
+		return m
+	}
+	if m == nil {
+		m = map[string][]string{}
+	}
+	for name, values := range headers {
+		if strings.EqualFold(name, "Set-Cookie") {
+			m[name] = append(m[name], values...)
+			continue
+		}
+		deleteHeader(m, name)
+		m[name] = values
+	}
+	return m
+}
+
+// blockedBody returns the body of a blocked response and indicates if it had to be base64-encoded.
+func blockedBody(body []byte) (string, bool) {
+	if isText(body) {
+		return string(body), false
+	}
+	return base64.StdEncoding.EncodeToString(body), true
+}
+
+// isText indicates if the body can be sent as is in a response event.
+func isText(body []byte) bool {
+	for _, b := range body {
+		if b < 0x09 || (b > 0x0D && b < 0x20) {
+			return false
+		}
+	}
+	return strings.ToValidUTF8(string(body), "") == string(body)
+}
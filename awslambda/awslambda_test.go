@@ -0,0 +1,74 @@
+package awslambda
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	modulego "github.com/andynuge/datadome-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// loadEvent decodes a sample event from the testdata directory.
+func loadEvent(t *testing.T, name string, event any) {
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	assert.Nil(t, err)
+	assert.Nil(t, json.Unmarshal(data, event))
+}
+
+// setupClient returns a client sending its requests to a fake Protection API.
+// The API blocks the requests when blocked is true and records the payloads it receives.
+func setupClient(t *testing.T, blocked bool) (*modulego.Client, *url.Values) {
+	payload := &url.Values{}
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		*payload = r.PostForm
+		if blocked {
+			w.Header().Set("X-Datadomeresponse", "403")
+			w.Header().Set("X-Datadome-Headers", "Set-Cookie X-DD-B")
+			w.Header().Set("Set-Cookie", "datadome=new-client-id")
+			w.Header().Set("X-DD-B", "1")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = io.WriteString(w, "<html>blocked</html>")
+			return
+		}
+		w.Header().Set("X-Datadomeresponse", "200")
+		w.Header().Set("X-Datadome-Headers", "Set-Cookie")
+		w.Header().Set("Set-Cookie", "datadome=new-client-id")
+		w.Header().Set("X-Datadome-Request-Headers", "X-Datadome-Botname")
+		w.Header().Set("X-Datadome-Botname", "none")
+	}))
+	t.Cleanup(api.Close)
+
+	client, err := modulego.NewClient("your-api-key", modulego.WithEndpoint(api.URL+"/validate-request"), modulego.WithLogger(nopLogger{}))
+	assert.Nil(t, err)
+	return client, payload
+}
+
+func TestEncodeQuery(t *testing.T) {
+	assert.Equal(t, "a=1&b=x+y", encodeQuery(map[string]string{"b": "x y", "a": "1"}, nil, true))
+	assert.Equal(t, "a=1&a=2", encodeQuery(map[string]string{"a": "2"}, map[string][]string{"a": {"1", "2"}}, true))
+	assert.Equal(t, "a=1&b=x%20y", encodeQuery(map[string]string{"b": "x%20y", "a": "1"}, nil, false))
+}
+
+func TestBlockedBody(t *testing.T) {
+	body, isBase64Encoded := blockedBody([]byte("<html>blocked</html>"))
+	assert.Equal(t, "<html>blocked</html>", body)
+	assert.False(t, isBase64Encoded)
+
+	body, isBase64Encoded = blockedBody([]byte{0x89, 0x50, 0x4E, 0x47})
+	assert.Equal(t, "iVBORw==", body)
+	assert.True(t, isBase64Encoded)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(args ...interface{}) {}
+func (nopLogger) Info(args ...interface{})  {}
+func (nopLogger) Warn(args ...interface{})  {}
+func (nopLogger) Error(args ...interface{}) {}
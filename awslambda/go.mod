@@ -0,0 +1,19 @@
+module github.com/andynuge/datadome-go/awslambda
+
+go 1.24.1
+
+require (
+	github.com/andynuge/datadome-go v1.4.0
+	github.com/aws/aws-lambda-go v1.47.0
+	github.com/stretchr/testify v1.10.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// The local builds use the parent directory: the builds depending on this module ignore the replace directive and resolve the required release tag of the root module.
+// Tag the root module first, then tag this module with the awslambda/ prefix (e.g. awslambda/v1.4.0) on the same commit.
+replace github.com/andynuge/datadome-go => ../
@@ -0,0 +1,149 @@
+package modulego
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithBypassClientCAs is a functional option to skip the Protection API for the requests presenting a client certificate issued by one of the CAs.
+// The [http.Server] must request the client certificates, with [tls.VerifyClientCertIfGiven] for instance.
+func WithBypassClientCAs(pool *x509.CertPool) Option {
+	return func(c *Client) {
+		c.BypassClientCAs = pool
+	}
+}
+
+// WithBypassHeader is a functional option to set the name of the header containing the bypass signature.
+func WithBypassHeader(bypassHeader string) Option {
+	return func(c *Client) {
+		c.BypassHeader = bypassHeader
+	}
+}
+
+// WithBypassSecret is a functional option to skip the Protection API for the requests signed with the secret by [SignBypass].
+func WithBypassSecret(bypassSecret string) Option {
+	return func(c *Client) {
+		c.BypassSecret = bypassSecret
+	}
+}
+
+// WithBypassWindow is a functional option to set the validity in seconds of the bypass signatures.
+// The signatures of the requests are accepted during this duration to tolerate the clock skew between servers.
+func WithBypassWindow(bypassWindow int) Option {
+	return func(c *Client) {
+		c.BypassWindow = bypassWindow
+	}
+}
+
+// SignBypass adds to the request the header signing it with the secret, so that the [Client] configured with [WithBypassSecret] skips the Protection API.
+// The signature covers the time, the method, the host and the URI of the request:
+// the host and the URI must not be rewritten between the services.
+// The signature has no nonce: a signed request can be replayed during the bypass window,
+// so it must only be sent over connections that cannot be intercepted.
+// The default header is used when header is empty.
+func SignBypass(r *http.Request, header string, secret string) {
+	if header == "" {
+		header = DefaultBypassHeaderValue
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	r.Header.Set(header, "t="+timestamp+",s="+bypassSignature(secret, timestamp, r))
+}
+
+// bypassSignature returns the hexadecimal HMAC-SHA256 of the timestamp, the method, the host and the URI of the request.
+// The host of the URL is used when the Host field of a client request is empty.
+func bypassSignature(secret string, timestamp string, r *http.Request) string {
+	host := r.Host
+	if host == "" {
+		host = r.URL.Host
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + r.Method + "\n" + strings.ToLower(host) + "\n" + r.URL.RequestURI()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyBypassSignature verifies the `t=<timestamp>,s=<signature>` header value of the request.
+// The returned errors do not contain the secret nor the expected signature.
+func (s *settings) verifyBypassSignature(value string, r *http.Request, now time.Time) error {
+	var timestamp, signature string
+	for _, part := range strings.Split(value, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			timestamp = v
+		case "s":
+			signature = v
+		}
+	}
+	t, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || signature == "" {
+		return fmt.Errorf("malformed signature header")
+	}
+	// the bounds of the window are compared in seconds so that no timestamp overflows the computation
+	if unix, window := now.Unix(), int64(s.bypassWindow); t < unix-window || t > unix+window {
+		return fmt.Errorf("signature expired")
+	}
+	if !hmac.Equal([]byte(signature), []byte(bypassSignature(s.bypassSecret, timestamp, r))) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+// verifyClientCertificate reports whether the client certificate of the request is issued by one of the bypass CAs.
+func (s *settings) verifyClientCertificate(r *http.Request) bool {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return false
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range r.TLS.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := r.TLS.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         s.bypassClientCAs,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	return err == nil
+}
+
+// bypass reports whether the request is authenticated as an internal request.
+// The bypass header is removed from the request so that it does not reach the application.
+func (s *settings) bypass(r *http.Request) bool {
+	if s.bypassSecret != "" {
+		value := r.Header.Get(s.bypassHeader)
+		r.Header.Del(s.bypassHeader)
+		if value != "" {
+			err := s.verifyBypassSignature(value, r, time.Now())
+			if err == nil {
+				s.logger.Info("Bypass signature verified, skipping.")
+				return true
+			}
+			s.logger.Warn("invalid bypass signature: ", err)
+		}
+	}
+	if s.bypassClientCAs != nil && s.verifyClientCertificate(r) {
+		s.logger.Info("Bypass client certificate verified, skipping.")
+		return true
+	}
+	return false
+}
+
+// loadCertPool returns the pool of the PEM certificates of the file.
+func loadCertPool(path string) (*x509.CertPool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("BypassClientCAFile must be readable: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("BypassClientCAFile must contain PEM certificates")
+	}
+	return pool, nil
+}
@@ -0,0 +1,254 @@
+package modulego
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newCertificate returns a certificate signed by the parent, or self-signed when parent is nil.
+func newCertificate(t *testing.T, name string, isCA bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	return cert, key
+}
+
+func TestSignBypass(t *testing.T) {
+	c, err := NewClient("your-api-key", WithBypassSecret("secret"))
+	assert.Nil(t, err)
+	s := c.current()
+
+	newSignedRequest := func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/api/orders?id=1", nil)
+		SignBypass(r, "", "secret")
+		return r
+	}
+
+	t.Run("Valid signature", func(t *testing.T) {
+		r := newSignedRequest()
+
+		assert.Regexp(t, `^t=\d+,s=[0-9a-f]{64}$`, r.Header.Get(DefaultBypassHeaderValue))
+		assert.Nil(t, s.verifyBypassSignature(r.Header.Get(DefaultBypassHeaderValue), r, time.Now()))
+	})
+
+	t.Run("Clock skew within the window", func(t *testing.T) {
+		r := newSignedRequest()
+
+		assert.Nil(t, s.verifyBypassSignature(r.Header.Get(DefaultBypassHeaderValue), r, time.Now().Add(-29*time.Second)))
+	})
+
+	testCases := []struct {
+		name     string
+		update   func(r *http.Request, value string) string
+		now      time.Time
+		expected string
+	}{
+		{"Expired signature", func(r *http.Request, value string) string { return value }, time.Now().Add(DefaultBypassWindowValue*time.Second + time.Minute), "signature expired"},
+		{"Other method", func(r *http.Request, value string) string { r.Method = http.MethodDelete; return value }, time.Now(), "signature mismatch"},
+		{"Far future timestamp", func(r *http.Request, value string) string {
+			return "t=" + strconv.FormatInt(1<<40*1000, 10) + value[strings.Index(value, ","):]
+		}, time.Now(), "signature expired"},
+		{"Minimum timestamp", func(r *http.Request, value string) string {
+			return "t=" + strconv.FormatInt(math.MinInt64, 10) + value[strings.Index(value, ","):]
+		}, time.Now(), "signature expired"},
+		{"Other host", func(r *http.Request, value string) string { r.Host = "other.example.com"; return value }, time.Now(), "signature mismatch"},
+		{"Other URI", func(r *http.Request, value string) string { r.URL.RawQuery = "id=2"; return value }, time.Now(), "signature mismatch"},
+		{"Other timestamp", func(r *http.Request, value string) string {
+			return "t=" + strconv.FormatInt(time.Now().Unix()+1, 10) + value[strings.Index(value, ","):]
+		}, time.Now(), "signature mismatch"},
+		{"Other secret", func(r *http.Request, value string) string {
+			SignBypass(r, "", "other")
+			return r.Header.Get(DefaultBypassHeaderValue)
+		}, time.Now(), "signature mismatch"},
+		{"Missing signature", func(r *http.Request, value string) string { return value[:strings.Index(value, ",")] }, time.Now(), "malformed signature header"},
+		{"Malformed timestamp", func(r *http.Request, value string) string { return "t=now" + value[strings.Index(value, ","):] }, time.Now(), "malformed signature header"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newSignedRequest()
+			value := tc.update(r, r.Header.Get(DefaultBypassHeaderValue))
+
+			err := s.verifyBypassSignature(value, r, tc.now)
+
+			assert.EqualError(t, err, tc.expected)
+		})
+	}
+}
+
+func TestEvaluate_Bypass(t *testing.T) {
+	ca, caKey := newCertificate(t, "Internal CA", true, nil, nil)
+	cert, _ := newCertificate(t, "internal-service", false, ca, caKey)
+	otherCA, otherCAKey := newCertificate(t, "Other CA", true, nil, nil)
+	otherCert, _ := newCertificate(t, "other-service", false, otherCA, otherCAKey)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	logger := &syncLogger{}
+	client, err := NewClient("your-api-key",
+		WithLogger(logger),
+		// the unreachable Protection API fails the requests that are not bypassed
+		WithEndpoint("http://127.0.0.1:1/validate-request"),
+		WithBypassSecret("secret"),
+		WithBypassHeader("x-internal-signature"),
+		WithBypassClientCAs(pool),
+	)
+	assert.Nil(t, err)
+
+	t.Run("Signed request", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		SignBypass(r, "X-Internal-Signature", "secret")
+
+		decision, err := client.Evaluate(r)
+
+		assert.Nil(t, err)
+		assert.Equal(t, &Decision{SkipReason: SkipReasonBypass}, decision)
+		assert.Empty(t, r.Header.Values("X-Internal-Signature"))
+		assert.Equal(t, "INFO Bypass signature verified, skipping.", logger.last())
+	})
+
+	t.Run("Invalid signature", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		SignBypass(r, "X-Internal-Signature", "other")
+
+		_, err := client.Evaluate(r)
+
+		assert.NotNil(t, err)
+		assert.Empty(t, r.Header.Values("X-Internal-Signature"))
+		assert.Contains(t, logger.messages, "WARN invalid bypass signature: signature mismatch")
+	})
+
+	t.Run("Client certificate", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+
+		decision, err := client.Evaluate(r)
+
+		assert.Nil(t, err)
+		assert.Equal(t, &Decision{SkipReason: SkipReasonBypass}, decision)
+		assert.Equal(t, "INFO Bypass client certificate verified, skipping.", logger.last())
+	})
+
+	t.Run("Client certificate of another CA", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{otherCert}}
+
+		_, err := client.Evaluate(r)
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Self-signed certificate", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{otherCA}}
+
+		_, err := client.Evaluate(r)
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Bypass header does not reach the application", func(t *testing.T) {
+		var received http.Header
+		handler := client.DatadomeHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header.Clone()
+		}))
+		r := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		SignBypass(r, "X-Internal-Signature", "secret")
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, r)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.NotNil(t, received)
+		assert.Empty(t, received.Values("X-Internal-Signature"))
+	})
+}
+
+func TestBypassConfig(t *testing.T) {
+	ca, _ := newCertificate(t, "Internal CA", true, nil, nil)
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	assert.Nil(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}), 0o600))
+
+	t.Run("Valid configuration", func(t *testing.T) {
+		c := DefaultConfig()
+		c.ServerSideKey = "your-api-key"
+		c.BypassSecret = "secret"
+		c.BypassClientCAFile = path
+
+		client, err := NewClientFromConfig(c)
+
+		assert.Nil(t, err)
+		s := client.current()
+		assert.Equal(t, "X-Datadome-Bypass", s.bypassHeader)
+		assert.Equal(t, "secret", s.bypassSecret)
+		assert.Equal(t, DefaultBypassWindowValue, s.bypassWindow)
+		assert.NotNil(t, s.bypassClientCAs)
+	})
+
+	t.Run("Invalid configuration", func(t *testing.T) {
+		c := DefaultConfig()
+		c.ServerSideKey = "your-api-key"
+		c.BypassSecret = "secret"
+		c.BypassHeader = ""
+		c.BypassWindow = 0
+		c.BypassClientCAFile = filepath.Join(t.TempDir(), "missing.pem")
+
+		err := c.Validate()
+
+		var configErr *ConfigError
+		assert.ErrorAs(t, err, &configErr)
+		messages := errorMessages(configErr.Errors)
+		assert.Contains(t, messages, "BypassHeader must be defined with BypassSecret")
+		assert.Contains(t, messages, "BypassWindow must be a positive integer")
+		assert.Len(t, messages, 3)
+	})
+
+	t.Run("CA file without certificates", func(t *testing.T) {
+		empty := filepath.Join(t.TempDir(), "empty.pem")
+		assert.Nil(t, os.WriteFile(empty, []byte("not a certificate"), 0o600))
+
+		_, err := loadCertPool(empty)
+
+		assert.EqualError(t, err, "BypassClientCAFile must contain PEM certificates")
+	})
+
+	t.Run("Invalid window", func(t *testing.T) {
+		client, err := NewClient("your-api-key", WithBypassSecret("secret"), WithBypassWindow(-1))
+
+		assert.Nil(t, client)
+		assert.EqualError(t, err, "BypassWindow must be a positive integer")
+	})
+}
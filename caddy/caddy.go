@@ -0,0 +1,236 @@
+// Package caddy provides the `http.handlers.datadome` Caddy module protecting the sites with DataDome.
+//
+// Build Caddy with the module:
+//
+//	xcaddy build --with github.com/andynuge/datadome-go/caddy
+//
+// Caddyfile syntax:
+//
+//	datadome [<server_side_key>] {
+//	    server_side_key       <key>
+//	    endpoint              <endpoint>
+//	    timeout               <duration>
+//	    maximum_body_size     <bytes>
+//	    url_pattern_exclusion <regexp>
+//	    url_pattern_inclusion <regexp>
+//	    graphql_support       [<bool>]
+//	    referrer_restoration  [<bool>]
+//	    use_x_forwarded_host  [<bool>]
+//	}
+//
+// The directive is ordered before `basic_auth`.
+// Blocked requests receive the response of the Protection API and the rest of the chain is not executed.
+package caddy
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	modulego "github.com/andynuge/datadome-go"
+	caddyv2 "github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/caddyserver/caddy/v2/caddyconfig/httpcaddyfile"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+	"go.uber.org/zap"
+)
+
+func init() {
+	caddyv2.RegisterModule(Handler{})
+	httpcaddyfile.RegisterHandlerDirective("datadome", parseCaddyfile)
+	httpcaddyfile.RegisterDirectiveOrder("datadome", httpcaddyfile.Before, "basic_auth")
+}
+
+// Handler is a Caddy HTTP handler validating the requests with DataDome.
+// Each field matches the [modulego.Option] of the same name.
+type Handler struct {
+	// ServerSideKey is the DataDome server-side key. Placeholders are supported.
+	ServerSideKey string `json:"server_side_key,omitempty"`
+	// Endpoint of the Protection API.
+	Endpoint string `json:"endpoint,omitempty"`
+	// Timeout of the calls to the Protection API.
+	Timeout caddyv2.Duration `json:"timeout,omitempty"`
+	// MaximumBodySize is the maximum amount of bytes read on GraphQL requests.
+	MaximumBodySize int `json:"maximum_body_size,omitempty"`
+	// UrlPatternExclusion is the regular expression of the excluded URLs.
+	// The default exclusion is used when nil, an empty string disables the exclusion.
+	UrlPatternExclusion *string `json:"url_pattern_exclusion,omitempty"`
+	// UrlPatternInclusion is the regular expression of the included URLs.
+	UrlPatternInclusion string `json:"url_pattern_inclusion,omitempty"`
+	// EnableGraphQLSupport enables the GraphQL support.
+	EnableGraphQLSupport bool `json:"enable_graphql_support,omitempty"`
+	// EnableReferrerRestoration enables the referrer restoration.
+	EnableReferrerRestoration bool `json:"enable_referrer_restoration,omitempty"`
+	// UseXForwardedHost uses the X-Forwarded-Host header as host.
+	UseXForwardedHost bool `json:"use_x_forwarded_host,omitempty"`
+
+	client *modulego.Client
+}
+
+// CaddyModule returns the Caddy module information.
+func (Handler) CaddyModule() caddyv2.ModuleInfo {
+	return caddyv2.ModuleInfo{
+		ID:  "http.handlers.datadome",
+		New: func() caddyv2.Module { return new(Handler) },
+	}
+}
+
+// options returns the [modulego.Option] list matching the handler configuration.
+func (h *Handler) options(logger *zap.Logger) []modulego.Option {
+	options := []modulego.Option{
+		modulego.WithGraphQLSupport(h.EnableGraphQLSupport),
+		modulego.WithLogger(&zapLogger{logger: logger.Sugar()}),
+		modulego.WithReferrerRestoration(h.EnableReferrerRestoration),
+		modulego.WithUrlPatternInclusion(h.UrlPatternInclusion),
+		modulego.WithXForwardedHost(h.UseXForwardedHost),
+	}
+	if h.Endpoint != "" {
+		options = append(options, modulego.WithEndpoint(h.Endpoint))
+	}
+	if h.Timeout != 0 {
+		options = append(options, modulego.WithTimeout(int(time.Duration(h.Timeout).Milliseconds())))
+	}
+	if h.MaximumBodySize != 0 {
+		options = append(options, modulego.WithMaximumBodySize(h.MaximumBodySize))
+	}
+	if h.UrlPatternExclusion != nil {
+		options = append(options, modulego.WithUrlPatternExclusion(*h.UrlPatternExclusion))
+	}
+	return options
+}
+
+// Provision instantiates the DataDome client.
+func (h *Handler) Provision(ctx caddyv2.Context) error {
+	repl := caddyv2.NewReplacer()
+	client, err := modulego.NewClient(repl.ReplaceAll(h.ServerSideKey, ""), h.options(ctx.Logger())...)
+	if err != nil {
+		return fmt.Errorf("fail to instantiate the DataDome client: %w", err)
+	}
+	h.client = client
+	return nil
+}
+
+// ServeHTTP validates the request with DataDome.
+// The chain is stopped when the request is blocked.
+// Errors are logged by the client and the request is allowed (fail open).
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request, next caddyhttp.Handler) error {
+	isBlocked, _ := h.client.DatadomeProtect(w, r)
+	if isBlocked {
+		return nil
+	}
+	return next.ServeHTTP(w, r)
+}
+
+// UnmarshalCaddyfile sets up the handler from the Caddyfile tokens.
+func (h *Handler) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
+	d.Next() // consume directive name
+
+	if d.NextArg() {
+		h.ServerSideKey = d.Val()
+	}
+	if d.NextArg() {
+		return d.ArgErr()
+	}
+
+	for d.NextBlock(0) {
+		switch d.Val() {
+		case "server_side_key":
+			if !d.AllArgs(&h.ServerSideKey) {
+				return d.ArgErr()
+			}
+		case "endpoint":
+			if !d.AllArgs(&h.Endpoint) {
+				return d.ArgErr()
+			}
+		case "timeout":
+			var value string
+			if !d.AllArgs(&value) {
+				return d.ArgErr()
+			}
+			timeout, err := caddyv2.ParseDuration(value)
+			if err != nil {
+				return d.Errf("invalid timeout: %v", err)
+			}
+			h.Timeout = caddyv2.Duration(timeout)
+		case "maximum_body_size":
+			var value string
+			if !d.AllArgs(&value) {
+				return d.ArgErr()
+			}
+			size, err := strconv.Atoi(value)
+			if err != nil {
+				return d.Errf("invalid maximum_body_size: %v", err)
+			}
+			h.MaximumBodySize = size
+		case "url_pattern_exclusion":
+			var value string
+			if !d.AllArgs(&value) {
+				return d.ArgErr()
+			}
+			h.UrlPatternExclusion = &value
+		case "url_pattern_inclusion":
+			if !d.AllArgs(&h.UrlPatternInclusion) {
+				return d.ArgErr()
+			}
+		case "graphql_support":
+			if err := parseBool(d, &h.EnableGraphQLSupport); err != nil {
+				return err
+			}
+		case "referrer_restoration":
+			if err := parseBool(d, &h.EnableReferrerRestoration); err != nil {
+				return err
+			}
+		case "use_x_forwarded_host":
+			if err := parseBool(d, &h.UseXForwardedHost); err != nil {
+				return err
+			}
+		default:
+			return d.Errf("unrecognized subdirective '%s'", d.Val())
+		}
+	}
+
+	return nil
+}
+
+// parseBool reads an optional boolean argument. The value is true when the argument is omitted.
+func parseBool(d *caddyfile.Dispenser, field *bool) error {
+	name := d.Val()
+	if !d.NextArg() {
+		*field = true
+		return nil
+	}
+	b, err := strconv.ParseBool(d.Val())
+	if err != nil {
+		return d.Errf("invalid %s: %v", name, err)
+	}
+	*field = b
+	if d.NextArg() {
+		return d.ArgErr()
+	}
+	return nil
+}
+
+// parseCaddyfile unmarshals the tokens of the `datadome` directive into a new handler.
+func parseCaddyfile(h httpcaddyfile.Helper) (caddyhttp.MiddlewareHandler, error) {
+	var handler Handler
+	err := handler.UnmarshalCaddyfile(h.Dispenser)
+	return &handler, err
+}
+
+// zapLogger implements the [modulego.Logger] interface with the logger of Caddy.
+type zapLogger struct {
+	logger *zap.SugaredLogger
+}
+
+func (l *zapLogger) Debug(args ...interface{}) { l.logger.Debug(args...) }
+func (l *zapLogger) Info(args ...interface{})  { l.logger.Info(args...) }
+func (l *zapLogger) Warn(args ...interface{})  { l.logger.Warn(args...) }
+func (l *zapLogger) Error(args ...interface{}) { l.logger.Error(args...) }
+
+// Interface guards
+var (
+	_ caddyv2.Provisioner         = (*Handler)(nil)
+	_ caddyhttp.MiddlewareHandler = (*Handler)(nil)
+	_ caddyfile.Unmarshaler       = (*Handler)(nil)
+)
@@ -0,0 +1,167 @@
+package caddy
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	caddyv2 "github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmarshalCaddyfile(t *testing.T) {
+	t.Run("With every subdirective", func(t *testing.T) {
+		d := caddyfile.NewTestDispenser(`datadome {
+			server_side_key your-api-key
+			endpoint api-eu.datadome.co
+			timeout 300ms
+			maximum_body_size 1024
+			url_pattern_exclusion "(?i)\.css$"
+			url_pattern_inclusion "(?i)^www\.example\.com/"
+			graphql_support
+			referrer_restoration true
+			use_x_forwarded_host false
+		}`)
+
+		var h Handler
+		err := h.UnmarshalCaddyfile(d)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "your-api-key", h.ServerSideKey)
+		assert.Equal(t, "api-eu.datadome.co", h.Endpoint)
+		assert.Equal(t, caddyv2.Duration(300*time.Millisecond), h.Timeout)
+		assert.Equal(t, 1024, h.MaximumBodySize)
+		assert.Equal(t, `(?i)\.css$`, *h.UrlPatternExclusion)
+		assert.Equal(t, `(?i)^www\.example\.com/`, h.UrlPatternInclusion)
+		assert.True(t, h.EnableGraphQLSupport)
+		assert.True(t, h.EnableReferrerRestoration)
+		assert.False(t, h.UseXForwardedHost)
+	})
+
+	t.Run("With the key as argument", func(t *testing.T) {
+		var h Handler
+		err := h.UnmarshalCaddyfile(caddyfile.NewTestDispenser(`datadome your-api-key`))
+
+		assert.Nil(t, err)
+		assert.Equal(t, "your-api-key", h.ServerSideKey)
+		assert.Nil(t, h.UrlPatternExclusion)
+	})
+
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{name: "Unknown subdirective", input: "datadome key {\n foo bar\n}", err: "unrecognized subdirective 'foo'"},
+		{name: "Invalid timeout", input: "datadome key {\n timeout fast\n}", err: "invalid timeout"},
+		{name: "Invalid boolean", input: "datadome key {\n graphql_support maybe\n}", err: "invalid graphql_support"},
+		{name: "Too many arguments", input: "datadome key other", err: "wrong argument count"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var h Handler
+			err := h.UnmarshalCaddyfile(caddyfile.NewTestDispenser(tc.input))
+
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestCaddyfileAdapter(t *testing.T) {
+	input := `:8080 {
+		respond "ok"
+		datadome {env.DATADOME_SERVER_SIDE_KEY} {
+			timeout 300ms
+			graphql_support
+		}
+	}`
+
+	adapted, _, err := caddyconfig.GetAdapter("caddyfile").Adapt([]byte(input), nil)
+	assert.Nil(t, err)
+
+	var cfg struct {
+		Apps struct {
+			HTTP struct {
+				Servers map[string]struct {
+					Routes []struct {
+						Handle []map[string]any `json:"handle"`
+					} `json:"routes"`
+				} `json:"servers"`
+			} `json:"http"`
+		} `json:"apps"`
+	}
+	assert.Nil(t, json.Unmarshal(adapted, &cfg))
+
+	handlers := cfg.Apps.HTTP.Servers["srv0"].Routes[0].Handle
+	assert.Len(t, handlers, 2)
+	// the datadome directive is ordered before respond
+	datadome := handlers[0]
+	assert.Equal(t, "datadome", datadome["handler"])
+	assert.Equal(t, "{env.DATADOME_SERVER_SIDE_KEY}", datadome["server_side_key"])
+	assert.Equal(t, float64(300*time.Millisecond), datadome["timeout"])
+	assert.Equal(t, true, datadome["enable_graphql_support"])
+	assert.Equal(t, "static_response", handlers[1]["handler"])
+}
+
+func TestServeHTTP(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.PostForm.Get("Request") == "/blocked" {
+			w.Header().Set("X-Datadomeresponse", "403")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = io.WriteString(w, "blocked")
+			return
+		}
+		w.Header().Set("X-Datadomeresponse", "200")
+	}))
+	defer api.Close()
+
+	t.Setenv("DATADOME_SERVER_SIDE_KEY", "your-api-key")
+	h := &Handler{ServerSideKey: "{env.DATADOME_SERVER_SIDE_KEY}", Endpoint: api.URL + "/validate-request"}
+	ctx, cancel := caddyv2.NewContext(caddyv2.Context{Context: context.Background()})
+	defer cancel()
+	assert.Nil(t, h.Provision(ctx))
+
+	nextCalled := false
+	next := caddyhttp.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		nextCalled = true
+		_, err := io.WriteString(w, "next")
+		return err
+	})
+
+	t.Run("Allowed request continues the chain", func(t *testing.T) {
+		nextCalled = false
+		rw := httptest.NewRecorder()
+
+		err := h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/ping", nil), next)
+
+		assert.Nil(t, err)
+		assert.True(t, nextCalled)
+		assert.Equal(t, "next", rw.Body.String())
+	})
+
+	t.Run("Blocked request stops the chain", func(t *testing.T) {
+		nextCalled = false
+		rw := httptest.NewRecorder()
+
+		err := h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/blocked", nil), next)
+
+		assert.Nil(t, err)
+		assert.False(t, nextCalled)
+		assert.Equal(t, http.StatusForbidden, rw.Code)
+		assert.Equal(t, "blocked", rw.Body.String())
+	})
+
+	t.Run("Invalid configuration", func(t *testing.T) {
+		err := (&Handler{}).Provision(ctx)
+
+		assert.ErrorContains(t, err, "ServerSideKey must be defined")
+	})
+}
@@ -1,11 +1,12 @@
 package modulego
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"regexp"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
@@ -16,9 +17,13 @@ import (
 // It returns an error in case of [incorrect / invalid] inputs in the options.
 func NewClient(serverSideKey string, options ...Option) (*Client, error) {
 	c := &Client{
+		BypassHeader:              DefaultBypassHeaderValue,
+		BypassWindow:              DefaultBypassWindowValue,
+		DenyStatusCode:            DefaultDenyStatusCodeValue,
 		EnableGraphQLSupport:      DefaultEnableGraphQLSupportValue,
 		EnableReferrerRestoration: DefaultEnableReferrerRestorationValue,
 		Endpoint:                  DefaultEndpointValue,
+		GraphQLEndpointPattern:    DefaultGraphQLEndpointPatternValue,
 		Logger:                    NewDefaultLogger(),
 		MaximumBodySize:           DefaultMaximumBodySizeValue,
 		ModuleName:                DefaultModuleNameValue,
@@ -34,6 +39,108 @@ func NewClient(serverSideKey string, options ...Option) (*Client, error) {
 		opt(c)
 	}
 
+	s, err := newSettings(c)
+	if err != nil {
+		return nil, err
+	}
+	c.settings.Store(s)
+
+	return c, nil
+}
+
+// Update applies the options on top of the current settings of the client and switches to the resulting settings atomically.
+// The requests being processed complete with the previous settings.
+// Nothing is changed nor logged when the options and the content of the access list files are unchanged.
+// An error is returned in case of [incorrect / invalid] inputs in the options, and the current settings are kept.
+func (c *Client) Update(options ...Option) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	previous, err := c.load()
+	if err != nil {
+		return err
+	}
+	draft := previous.client()
+	for _, opt := range options {
+		opt(draft)
+	}
+
+	s, err := newSettings(draft)
+	if err != nil {
+		return err
+	}
+	if sameOptions(draft, previous.client()) && s.allowList.sameEntries(previous.allowList) && s.denyList.sameEntries(previous.denyList) {
+		// nothing changed, the list files included
+		return nil
+	}
+	if s.rateLimiter != nil && previous.rateLimiter != nil && *s.fallbackRateLimit == *previous.fallbackRateLimit {
+		// the sources keep their tokens when the limit does not change
+		s.rateLimiter = previous.rateLimiter
+	}
+	c.settings.Store(s)
+	s.logger.Info("DataDome settings updated")
+	if s.serverSideKey != previous.serverSideKey {
+		s.logger.Info("DataDome server-side key rotated")
+		s.keyEvent(KeyEventRotated)
+	}
+	return nil
+}
+
+// sameOptions reports whether the exported fields of the clients are equal.
+// The functions are compared by pointer, the other fields with [reflect.DeepEqual].
+func sameOptions(a, b *Client) bool {
+	va, vb := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	for i := 0; i < va.NumField(); i++ {
+		if !va.Type().Field(i).IsExported() {
+			continue
+		}
+		fa, fb := va.Field(i), vb.Field(i)
+		if fa.Kind() == reflect.Func {
+			if fa.Pointer() != fb.Pointer() {
+				return false
+			}
+			continue
+		}
+		if !reflect.DeepEqual(fa.Interface(), fb.Interface()) {
+			return false
+		}
+	}
+	return true
+}
+
+// ErrClientNotInitialized is returned by the methods of a [Client] which is not created with [NewClient] or [NewClientFromConfig].
+var ErrClientNotInitialized = errors.New("the Client must be created with NewClient or NewClientFromConfig")
+
+// load returns the settings used to process a request, or [ErrClientNotInitialized].
+func (c *Client) load() (*settings, error) {
+	s, ok := c.settings.Load().(*settings)
+	if !ok {
+		return nil, ErrClientNotInitialized
+	}
+	return s, nil
+}
+
+// current returns the settings used to process a request, once the client is known to be initialized.
+func (c *Client) current() *settings {
+	return c.settings.Load().(*settings)
+}
+
+// CurrentLogger returns the [Logger] of the current settings of the client, for the integrations logging on its behalf.
+// Unlike the Logger field, it reflects the settings changed with [Client.Update].
+// The Logger field, or a default logger, is returned when the client is not initialized.
+func (c *Client) CurrentLogger() Logger {
+	s, err := c.load()
+	if err != nil {
+		if c.Logger != nil {
+			return c.Logger
+		}
+		return NewDefaultLogger()
+	}
+	return s.logger
+}
+
+// newSettings validates the exported fields of c and returns the matching settings.
+func newSettings(c *Client) (*settings, error) {
 	// error management
 	if c.ServerSideKey == "" {
 		return nil, fmt.Errorf("ServerSideKey must be defined")
@@ -44,77 +151,275 @@ func NewClient(serverSideKey string, options ...Option) (*Client, error) {
 	if c.MaximumBodySize <= 0 {
 		return nil, fmt.Errorf("MaximumBodySize must be a positive integer")
 	}
+	if c.DenyStatusCode < 100 || c.DenyStatusCode > 999 {
+		return nil, fmt.Errorf("DenyStatusCode must be a valid HTTP status code")
+	}
+	if c.BypassSecret != "" && c.BypassHeader == "" {
+		return nil, fmt.Errorf("BypassHeader must be defined with BypassSecret")
+	}
+	if c.BypassSecret != "" && c.BypassWindow <= 0 {
+		return nil, fmt.Errorf("BypassWindow must be a positive integer")
+	}
+	if c.GraphQLWebSocketMessages < 0 {
+		return nil, fmt.Errorf("GraphQLWebSocketMessages must be a positive integer")
+	}
+	if err := validateEndpoint(c.Endpoint); err != nil {
+		return nil, err
+	}
+
+	s := &settings{
+		allowListFile:               c.AllowListFile,
+		bypassClientCAs:             c.BypassClientCAs,
+		bypassHeader:                http.CanonicalHeaderKey(c.BypassHeader),
+		bypassSecret:                c.BypassSecret,
+		bypassWindow:                c.BypassWindow,
+		denyBody:                    c.DenyBody,
+		denyListFile:                c.DenyListFile,
+		denyStatusCode:              c.DenyStatusCode,
+		enableGraphQLSupport:        c.EnableGraphQLSupport,
+		enableReferrerRestoration:   c.EnableReferrerRestoration,
+		enableServerSideKeyFallback: c.EnableServerSideKeyFallback,
+		fallbackRateLimit:           c.FallbackRateLimit,
+		graphQLWebSocketMessages:    c.GraphQLWebSocketMessages,
+		keyEventHandler:             c.KeyEventHandler,
+		logger:                      c.Logger,
+		maximumBodySize:             c.MaximumBodySize,
+		moduleName:                  c.ModuleName,
+		moduleVersion:               c.ModuleVersion,
+		rawAllowList:                c.AllowList,
+		rawDenyList:                 c.DenyList,
+		rawEndpoint:                 c.Endpoint,
+		rawRoutes:                   c.Routes,
+		rawSkipRules:                c.SkipRules,
+		secondaryServerSideKey:      c.SecondaryServerSideKey,
+		serverSideKey:               c.ServerSideKey,
+		timeout:                     c.Timeout,
+		transport:                   c.Transport,
+		useXForwardedHost:           c.UseXForwardedHost,
+	}
 
 	// set not exported values
-	c.httpClient = &http.Client{
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		},
-		Timeout: time.Millisecond * time.Duration(c.Timeout),
-	}
+	s.httpClient = newHTTPClient(c.Timeout, c.Transport)
 	if c.UrlPatternExclusion != "" {
-		r, err := regexp.Compile(c.UrlPatternExclusion)
+		r, err := compileURIPattern(c.UrlPatternExclusion)
 		if err != nil {
 			return nil, fmt.Errorf("UrlPatternExclusion must be a valid RegExp: %w", err)
 		}
-		c.urlPatternExclusion = r
+		s.urlPatternExclusion = r
 	}
 	if c.UrlPatternInclusion != "" {
-		r, err := regexp.Compile(c.UrlPatternInclusion)
+		r, err := compileURIPattern(c.UrlPatternInclusion)
 		if err != nil {
 			return nil, fmt.Errorf("UrlPatternInclusion must be a valid RegExp: %w", err)
 		}
-		c.urlPatternInclusion = r
+		s.urlPatternInclusion = r
+	}
+	graphQLEndpointPattern := c.GraphQLEndpointPattern
+	if graphQLEndpointPattern == "" {
+		graphQLEndpointPattern = DefaultGraphQLEndpointPatternValue
+	}
+	graphQLEndpoint, err := compileURIPattern(graphQLEndpointPattern)
+	if err != nil {
+		return nil, fmt.Errorf("GraphQLEndpointPattern must be a valid RegExp: %w", err)
 	}
-	c.endpoint = c.Endpoint
+	s.graphQLEndpoint = graphQLEndpoint
+	s.endpoint = c.Endpoint
 	if !strings.HasPrefix(c.Endpoint, "http") && !strings.HasPrefix(c.Endpoint, "/") {
-		c.endpoint = fmt.Sprintf("https://%s/validate-request", c.Endpoint)
+		s.endpoint = fmt.Sprintf("https://%s/validate-request", c.Endpoint)
+	}
+	rateLimiter, errs := newRateLimiter(c.FallbackRateLimit)
+	s.rateLimiter = rateLimiter
+	skipRules, skipRuleErrs := compileSkipRules(c.SkipRules)
+	errs = append(errs, skipRuleErrs...)
+	s.skipRules = skipRules
+	allowList, allowListErrs := compileAccessList("AllowList", c.AllowList, c.AllowListFile, false)
+	errs = append(errs, allowListErrs...)
+	s.allowList = allowList
+	denyList, denyListErrs := compileAccessList("DenyList", c.DenyList, c.DenyListFile, true)
+	errs = append(errs, denyListErrs...)
+	s.denyList = denyList
+	persistedQueries, persistedQueryErrs := compilePersistedQueries(c.GraphQLPersistedQueries)
+	errs = append(errs, persistedQueryErrs...)
+	s.graphQLPersistedQueries = persistedQueries
+	// the routes are compiled last: their settings are copies of the complete settings
+	routes, routeErrs := compileRoutes(c.Routes, s)
+	errs = append(errs, routeErrs...)
+	s.routes = newRouteIndex(routes)
+	if len(errs) > 0 {
+		return nil, &ConfigError{Errors: errs}
+	}
+
+	return s, nil
+}
+
+// newHTTPClient returns the [http.Client] performing the calls to the Protection API with the timeout in milliseconds.
+func newHTTPClient(timeout int, transport http.RoundTripper) *http.Client {
+	return &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Timeout:   time.Millisecond * time.Duration(timeout),
+		Transport: transport,
 	}
+}
 
-	return c, nil
+// client returns a [Client] whose exported fields match the settings, on which options can be applied.
+func (s *settings) client() *Client {
+	c := &Client{
+		AllowList:                   s.rawAllowList,
+		AllowListFile:               s.allowListFile,
+		BypassClientCAs:             s.bypassClientCAs,
+		BypassHeader:                s.bypassHeader,
+		BypassSecret:                s.bypassSecret,
+		BypassWindow:                s.bypassWindow,
+		DenyBody:                    s.denyBody,
+		DenyList:                    s.rawDenyList,
+		DenyListFile:                s.denyListFile,
+		DenyStatusCode:              s.denyStatusCode,
+		EnableGraphQLSupport:        s.enableGraphQLSupport,
+		EnableReferrerRestoration:   s.enableReferrerRestoration,
+		EnableServerSideKeyFallback: s.enableServerSideKeyFallback,
+		Endpoint:                    s.rawEndpoint,
+		FallbackRateLimit:           s.fallbackRateLimit,
+		GraphQLEndpointPattern:      s.graphQLEndpoint.String(),
+		GraphQLPersistedQueries:     s.graphQLPersistedQueries,
+		GraphQLWebSocketMessages:    s.graphQLWebSocketMessages,
+		KeyEventHandler:             s.keyEventHandler,
+		Logger:                      s.logger,
+		MaximumBodySize:             s.maximumBodySize,
+		ModuleName:                  s.moduleName,
+		ModuleVersion:               s.moduleVersion,
+		Routes:                      s.rawRoutes,
+		SkipRules:                   s.rawSkipRules,
+		SecondaryServerSideKey:      s.secondaryServerSideKey,
+		ServerSideKey:               s.serverSideKey,
+		Timeout:                     s.timeout,
+		Transport:                   s.transport,
+		UseXForwardedHost:           s.useXForwardedHost,
+	}
+	if s.urlPatternExclusion != nil {
+		c.UrlPatternExclusion = s.urlPatternExclusion.String()
+	}
+	if s.urlPatternInclusion != nil {
+		c.UrlPatternInclusion = s.urlPatternInclusion.String()
+	}
+	return c
 }
 
-// handler is used to validate incoming requests
+// Evaluate validates the incoming request with the Protection API and returns the resulting [Decision].
 // This function will:
-// 1. Verifies the request URL does not match the UrlPatternExclusion
-// 2. Verifies the request URL match the UrlPatternInclusion (if set)
-// 3. Builds the request payload for the Protection API
-// 4. Performs the call to the Protection API and interpret the response
-func (c *Client) handler(w http.ResponseWriter, r *http.Request, next http.Handler) (bool, error) {
-	sendNext := func(res bool, err error, response http.ResponseWriter) (bool, error) {
-		if next != nil {
-			next.ServeHTTP(response, r)
-		} else {
-			return res, err
+// 1. Verifies the request is not an internal request signed with the bypass secret or presenting a client certificate of the bypass CAs
+// 2. Verifies the IP of the request does not match the AllowList, nor its IP and client ID the DenyList
+// 3. Verifies the request does not match a [SkipRule]
+// 4. Applies the first [Route] matching the request, if any
+// 5. Verifies the request URL does not match the UrlPatternExclusion, when no route matches
+// 6. Verifies the request URL match the UrlPatternInclusion (if set), when no route matches
+// 7. Builds the request payload for the Protection API
+// 8. Performs the call to the Protection API and interpret the response
+// 9. Performs the call again with the secondary key if the server-side key is rejected (see [WithServerSideKeyFallback])
+//
+// When the Protection API call fails, the requests exceeding the [FallbackRateLimit] (if set) are blocked,
+// including on the routes with the [RouteMonitor] action.
+//
+// Evaluate does not write anything, except that it removes the bypass header from the request:
+// the [Decision] must be applied by the caller.
+// An error is returned when the payload cannot be built or when the Protection API call fails,
+// unless the matching route has the [FailClosed] policy.
+func (c *Client) Evaluate(r *http.Request) (*Decision, error) {
+	s, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+
+	if s.bypass(r) {
+		return &Decision{SkipReason: SkipReasonBypass}, nil
+	}
+
+	if decision := s.accessListDecision(r); decision != nil {
+		return decision, nil
+	}
+
+	if rule := s.skipRule(r); rule != nil {
+		s.logger.Info("SkipRule ", rule.name, " matches request, skipping.")
+		return &Decision{SkipReason: SkipReasonSkipRule, SkipRule: rule.name}, nil
+	}
+
+	route := s.route(r)
+	if route == nil {
+		uri := getURI(r)
+		// Test exclusion regex
+		if s.urlPatternExclusion != nil && s.urlPatternExclusion.MatchString(uri) {
+			s.logger.Info("UrlPatternExclusion matches requested URI, skipping.")
+			return &Decision{SkipReason: SkipReasonUrlPatternExclusion}, nil
+		}
+
+		// Test inclusion regex
+		if s.urlPatternInclusion != nil && !s.urlPatternInclusion.MatchString(uri) {
+			s.logger.Info("UrlPatternInclusion does not match requested URI, skipping.")
+			return &Decision{SkipReason: SkipReasonUrlPatternInclusion}, nil
+		}
+		decision, err := s.evaluate(r)
+		if err != nil {
+			return s.fallback(r, err)
 		}
-		return res, nil
+		return decision, nil
 	}
 
-	uri := getURI(r)
-	// Test exclusion regex
-	if c.urlPatternExclusion != nil && c.urlPatternExclusion.MatchString(uri) {
-		c.Logger.Info("UrlPatternExclusion matches requested URI, skipping.")
-		return false, nil
+	if route.action == RouteSkip {
+		s.logger.Info("Route skips requested URI, skipping.")
+		return &Decision{SkipReason: SkipReasonRoute}, nil
 	}
+	decision, err := route.settings.evaluate(r)
+	if err != nil {
+		if route.failurePolicy == FailClosed {
+			s.logger.Warn("Route fails closed, blocking.")
+			return &Decision{Blocked: true, StatusCode: s.denyStatusCode, Body: []byte(s.denyBody)}, nil
+		}
+		return s.fallback(r, err)
+	}
+	if route.action == RouteMonitor && decision.Blocked {
+		s.logger.Info("Route monitors requested URI, not blocking.")
+		decision.Blocked = false
+		decision.Monitored = true
+	}
+	return decision, nil
+}
 
-	// Test inclusion regex
-	if c.urlPatternInclusion != nil && !c.urlPatternInclusion.MatchString(uri) {
-		c.Logger.Info("UrlPatternInclusion does not match requested URI, skipping.")
-		return false, nil
+// evaluate builds the payload of the request and performs the call to the Protection API.
+func (s *settings) evaluate(r *http.Request) (*Decision, error) {
+	queryStr, err := s.buildRequest(r)
+	if err != nil {
+		s.logger.Error("error when building request payload: %v", err)
+		return nil, err
 	}
 
-	queryStr, err := c.buildRequest(r)
+	decision, err := s.datadomeCall(queryStr, r)
+	if errors.Is(err, ErrServerSideKeyRejected) && s.enableServerSideKeyFallback && s.secondaryServerSideKey != "" {
+		s.logger.Warn("server-side key rejected by the Protection API, retrying with the secondary key")
+		s.keyEvent(KeyEventFallback)
+		decision, err = s.datadomeCall(withKey(queryStr, s.secondaryServerSideKey), r)
+	}
 	if err != nil {
-		c.Logger.Error("error when building request payload: %v", err)
-		return sendNext(false, err, w)
+		s.logger.Error("error when performing call to Protection API: %v", err)
+		return nil, err
 	}
+	return decision, nil
+}
 
-	err, resp, isBlocked := c.datadomeCall(queryStr, r, w)
+// handler is used to validate incoming requests.
+// The request is evaluated with [Client.Evaluate] and the resulting [Decision] is applied to the response.
+// The messages of the GraphQL WebSocket connections are inspected when next is defined (see [WithGraphQLWebSocketMessages]).
+func (c *Client) handler(w http.ResponseWriter, r *http.Request, next http.Handler) (bool, error) {
+	s, err := c.load()
 	if err != nil {
-		c.Logger.Error("error when performing call to Protection API: %v", err)
-		return sendNext(isBlocked, err, resp)
+		logger := c.CurrentLogger()
+		logger.Error("fail to protect the request: ", err)
+		return protect(c.Evaluate, logger, w, r, next)
+	}
+	if next != nil && s.isGraphQLWebSocket(r) {
+		return c.protectGraphQLWebSocket(s, w, r, next)
 	}
-	return sendNext(isBlocked, nil, resp)
+	return protect(c.Evaluate, s.logger, w, r, next)
 }
 
 // DatadomeHandler implements the [http.Handler] interface
@@ -132,9 +437,36 @@ func (c *Client) DatadomeProtect(rw http.ResponseWriter, r *http.Request) (isBlo
 	return c.handler(rw, r, nil)
 }
 
+// Payload returns the fields of the payload sent to the Protection API for the request, without performing the call.
+// The exclusion and inclusion patterns are not applied.
+// An error may be returned if the IP cannot be retrieved.
+func (c *Client) Payload(r *http.Request) (url.Values, error) {
+	s, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+	p, err := s.buildPayload(r)
+	if err != nil {
+		return nil, err
+	}
+	return buildQuery(p), nil
+}
+
 // buildRequest extracts information from the request and build the payload to be sent to the Protection API.
 // An error may be returned if the IP cannot be retrieved or if it fails to URL-encode the payload.
-func (c *Client) buildRequest(r *http.Request) (string, error) {
+func (s *settings) buildRequest(r *http.Request) (string, error) {
+	p, err := s.buildPayload(r)
+	if err != nil {
+		return "", err
+	}
+	queryStr := buildQuery(p)
+
+	return queryStr.Encode(), nil
+}
+
+// buildPayload extracts information from the request and build the [ProtectionAPIRequestPayload].
+// An error may be returned if the IP cannot be retrieved.
+func (s *settings) buildPayload(r *http.Request) (*ProtectionAPIRequestPayload, error) {
 	// Build DataDome request with the original request
 	contentLength := "0"
 	if r.Header.Get("content-length") != "" {
@@ -158,7 +490,7 @@ func (c *Client) buildRequest(r *http.Request) (string, error) {
 	}
 
 	host := r.Host
-	if c.UseXForwardedHost {
+	if s.useXForwardedHost {
 		host = getHost(r)
 	}
 
@@ -171,23 +503,23 @@ func (c *Client) buildRequest(r *http.Request) (string, error) {
 
 	ip, err := getIP(r)
 	if err != nil {
-		return "", fmt.Errorf("fail to parse request's IP: %w", err)
+		return nil, fmt.Errorf("fail to parse request's IP: %w", err)
 	}
 
-	if c.EnableReferrerRestoration {
+	if s.enableReferrerRestoration {
 		isMatching, err := isMatchingReferrer(r)
 		if err != nil {
-			c.Logger.Warn("fail to check if the referrer matches: %v", err)
+			s.logger.Warn("fail to check if the referrer matches: %v", err)
 		} else if isMatching {
 			err = restoreReferrer(r)
 			if err != nil {
-				c.Logger.Warn("fail to restore the referrer: %v", err)
+				s.logger.Warn("fail to restore the referrer: %v", err)
 			}
 		}
 	}
 
 	ddRequestParams := ProtectionAPIRequestPayload{
-		Key:                    c.ServerSideKey,
+		Key:                    s.serverSideKey,
 		IP:                     ip,
 		Accept:                 truncateValue(Accept, r.Header.Get("accept")),
 		AcceptCharset:          truncateValue(AcceptCharset, r.Header.Get("accept-charset")),
@@ -205,7 +537,7 @@ func (c *Client) buildRequest(r *http.Request) (string, error) {
 		HeadersList:            truncateValue(HeadersList, getHeaderList(r)),
 		Host:                   truncateValue(Host, host),
 		Method:                 r.Method,
-		ModuleVersion:          c.ModuleVersion,
+		ModuleVersion:          s.moduleVersion,
 		Origin:                 truncateValue(Origin, r.Header.Get("origin")),
 		Port:                   port,
 		PostParamLen:           contentLength,
@@ -213,7 +545,7 @@ func (c *Client) buildRequest(r *http.Request) (string, error) {
 		Protocol:               proto,
 		Referer:                truncateValue(Referer, r.Header.Get("referer")),
 		Request:                truncateValue(Request, getURL(r)),
-		RequestModuleName:      c.ModuleName,
+		RequestModuleName:      s.moduleName,
 		SecChDeviceMemory:      truncateValue(SecCHDeviceMemory, r.Header.Get("sec-ch-device-memory")),
 		SecChUA:                truncateValue(SecCHUA, r.Header.Get("sec-ch-ua")),
 		SecChUAArch:            truncateValue(SecCHUAArch, r.Header.Get("sec-ch-ua-arch")),
@@ -236,30 +568,31 @@ func (c *Client) buildRequest(r *http.Request) (string, error) {
 		XRequestedWith:         truncateValue(XRequestedWith, r.Header.Get("x-requested-with")),
 	}
 
-	if c.EnableGraphQLSupport && isGraphQLRequest(r) {
-		gqlData, err := getGraphQLData(r, c.MaximumBodySize)
+	// the operation parsed elsewhere, such as the one of a GraphQL WebSocket message, is reported through the context
+	gqlData, _ := r.Context().Value(graphQLDataKey{}).(*GraphQLData)
+	if gqlData == nil && s.enableGraphQLSupport && isGraphQLRequest(r, s.graphQLEndpoint) {
+		var err error
+		gqlData, err = getGraphQLData(r, s.maximumBodySize, s.graphQLPersistedQueries)
 		if err != nil {
-			c.Logger.Warn("fail to retrieve GraphQL data: %v", err)
-		}
-		if gqlData != nil && gqlData.Count != 0 {
-			operationName := truncateValue(GraphQLOperationName, gqlData.Name)
-			ddRequestParams.GraphQLOperationName = &operationName
-			ddRequestParams.GraphQLOperationType = gqlData.Type
-			ddRequestParams.GraphQLOperationCount = strconv.Itoa(gqlData.Count)
+			s.logger.Warn("fail to retrieve GraphQL data: %v", err)
 		}
 	}
+	if gqlData != nil && gqlData.Count != 0 {
+		operationName := truncateValue(GraphQLOperationName, gqlData.Name)
+		ddRequestParams.GraphQLOperationName = &operationName
+		ddRequestParams.GraphQLOperationType = gqlData.Type
+		ddRequestParams.GraphQLOperationCount = strconv.Itoa(gqlData.Count)
+	}
 
-	queryStr := buildQuery(&ddRequestParams)
-
-	return queryStr.Encode(), nil
+	return &ddRequestParams, nil
 }
 
-// datadomeCall performs a request to the Protection API
-func (c *Client) datadomeCall(jsonStr string, origReq *http.Request, origResp http.ResponseWriter) (err error, rw http.ResponseWriter, isBlocked bool) {
+// datadomeCall performs a request to the Protection API and returns the [Decision] matching its response.
+func (s *settings) datadomeCall(jsonStr string, origReq *http.Request) (*Decision, error) {
 	body := strings.NewReader(jsonStr)
-	req, err := http.NewRequestWithContext(origReq.Context(), "POST", c.endpoint, body)
+	req, err := http.NewRequestWithContext(origReq.Context(), "POST", s.endpoint, body)
 	if err != nil {
-		return fmt.Errorf("error when instancing new DataDome request %w", err), nil, false
+		return nil, fmt.Errorf("error when instancing new DataDome request %w", err)
 	}
 	req.Header.Set("content-type", "application/x-www-form-urlencoded")
 	req.Header.Set("user-agent", "DataDome")
@@ -268,87 +601,111 @@ func (c *Client) datadomeCall(jsonStr string, origReq *http.Request, origResp ht
 		req.Header.Set("x-datadome-x-set-cookie", "true")
 	}
 
-	response, err := c.httpClient.Do(req)
+	response, err := s.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("error when performing DataDome request: %w", err), nil, false
+		return nil, fmt.Errorf("error when performing DataDome request: %w", err)
 	}
 
 	responseBody, err := io.ReadAll(response.Body)
 	if err != nil {
-		return fmt.Errorf("error when reading DataDome response %w", err), nil, false
+		return nil, fmt.Errorf("error when reading DataDome response %w", err)
 	}
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
 		if err != nil {
-			c.Logger.Warn("error when closing the Body: %v", err)
+			s.logger.Warn("error when closing the Body: %v", err)
 		}
 	}(response.Body)
 
 	ddStatus := response.Header.Get("x-datadomeresponse")
 	ddRespStatus := strconv.Itoa(response.StatusCode)
 
+	if ddStatus == "" && isKeyRejection(response.StatusCode) {
+		return nil, fmt.Errorf("%w: %d response from Protection API", ErrServerSideKeyRejected, response.StatusCode)
+	}
 	if ddStatus == "" || (ddRespStatus != ddStatus) {
-		c.Logger.Debug("fail to get status code and response headers from Protection API response. reason: %s", string(responseBody))
-		return fmt.Errorf("fails to get status code and response headers from Protection API response. Bypass DataDome. Full DataDome response: %v", response), nil, false
+		s.logger.Debug("fail to get status code and response headers from Protection API response. reason: %s", string(responseBody))
+		return nil, fmt.Errorf("fails to get status code and response headers from Protection API response. Bypass DataDome. Full DataDome response: %v", response)
 	}
 
 	// Handler DataDome status code
 	if ddStatus == "400" {
-		return nil, origResp, false
+		return &Decision{StatusCode: response.StatusCode}, nil
 	} else if ddStatus == "301" || ddStatus == "302" || ddStatus == "401" || ddStatus == "403" {
-		origResp = addDataDomeHeaders(response, origResp)
-		origResp.WriteHeader(response.StatusCode)
-		_, err = origResp.Write(responseBody)
-		if err != nil {
-			return err, nil, false
-		}
-		return nil, origResp, true
+		return &Decision{
+			Blocked:         true,
+			StatusCode:      response.StatusCode,
+			Body:            responseBody,
+			ResponseHeaders: getDataDomeHeaders(response, "x-datadome-headers"),
+		}, nil
 
 	} else if ddStatus == "200" {
-		addDataDomeRequestHeaders(response, origReq)
-		origResp = addDataDomeHeaders(response, origResp)
-		return nil, origResp, false
+		return &Decision{
+			StatusCode:      response.StatusCode,
+			RequestHeaders:  getDataDomeHeaders(response, "x-datadome-request-headers"),
+			ResponseHeaders: getDataDomeHeaders(response, "x-datadome-headers"),
+		}, nil
 
 	} else {
-		return fmt.Errorf("%s response from Protection API - Unexpected error. If the error remains, please contact us at support@datadome.co. Full response: %v", ddStatus, response.Header), origResp, false
+		return nil, fmt.Errorf("%s response from Protection API - Unexpected error. If the error remains, please contact us at support@datadome.co. Full response: %v", ddStatus, response.Header)
 	}
 }
 
-// addDataDomeRequestHeaders add the headers listed in the `X-datadome-request-headers`
-// header of the Protection API response to the original request.
-func addDataDomeRequestHeaders(ddResp *http.Response, origReq *http.Request) {
-	datadomeHeadersStr := ddResp.Header.Get("x-datadome-request-headers")
+// getDataDomeHeaders returns the headers of the Protection API response listed in the given header.
+// The `x-datadome-headers` header lists the headers to add to the response while
+// the `x-datadome-request-headers` header lists the headers to add to the request.
+func getDataDomeHeaders(ddResp *http.Response, listHeaderName string) http.Header {
+	headers := http.Header{}
+	datadomeHeadersStr := ddResp.Header.Get(listHeaderName)
 	if datadomeHeadersStr != "" {
 		datadomeHeaders := strings.Fields(datadomeHeadersStr)
 		for _, datadomeHeaderName := range datadomeHeaders {
 			datadomeHeaderValue := ddResp.Header.Get(datadomeHeaderName)
 			if datadomeHeaderValue != "" {
-				origReq.Header.Add(datadomeHeaderName, datadomeHeaderValue)
+				headers.Add(datadomeHeaderName, datadomeHeaderValue)
 			}
 		}
 	}
+	return headers
 }
 
-// addDataDomeHeaders add the headers listed in the `x-datadome-headers` header
-// of the Protection API response to the original response.
-func addDataDomeHeaders(ddResp *http.Response, origResp http.ResponseWriter) http.ResponseWriter {
-	datadomeHeadersStr := ddResp.Header.Get("x-datadome-headers")
-	if datadomeHeadersStr != "" {
-		datadomeHeaders := strings.Fields(datadomeHeadersStr)
-		for _, datadomeHeaderName := range datadomeHeaders {
-			datadomeHeaderValue := ddResp.Header.Get(datadomeHeaderName)
-			if datadomeHeaderValue != "" {
-				if strings.EqualFold(datadomeHeaderName, "set-cookie") {
-					origResp.Header().Add(datadomeHeaderName, datadomeHeaderValue)
-				} else {
-					origResp.Header().Set(datadomeHeaderName, datadomeHeaderValue)
-				}
+// addDataDomeRequestHeaders adds the headers returned by the Protection API to the original request.
+func addDataDomeRequestHeaders(headers http.Header, origReq *http.Request) {
+	for name, values := range headers {
+		for _, value := range values {
+			origReq.Header.Add(name, value)
+		}
+	}
+}
+
+// addDataDomeHeaders adds the headers returned by the Protection API to the original response.
+// The `Set-Cookie` headers are appended to the existing ones, the other headers are replaced.
+func addDataDomeHeaders(headers http.Header, origResp http.ResponseWriter) http.ResponseWriter {
+	for name, values := range headers {
+		for _, value := range values {
+			if strings.EqualFold(name, "set-cookie") {
+				origResp.Header().Add(name, value)
+			} else {
+				origResp.Header().Set(name, value)
 			}
 		}
 	}
 	return origResp
 }
 
+// apply adds the headers of the [Decision] to the request and to the response.
+// When the request is blocked, the status code and the body are written to the response.
+func (d *Decision) apply(w http.ResponseWriter, r *http.Request) error {
+	addDataDomeRequestHeaders(d.RequestHeaders, r)
+	addDataDomeHeaders(d.ResponseHeaders, w)
+	if !d.Blocked {
+		return nil
+	}
+	w.WriteHeader(d.StatusCode)
+	_, err := w.Write(d.Body)
+	return err
+}
+
 // getClientId retrieves the ClientID from the incoming request.
 // It uses the value of the `X-DataDome-ClientID` if the session by header feature is used.
 // It reads the `DataDome` cookie value otherwise.
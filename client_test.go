@@ -1,11 +1,16 @@
 package modulego
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/jarcoal/httpmock"
 	"github.com/stretchr/testify/assert"
@@ -69,9 +74,9 @@ func TestNewClient(t *testing.T) {
 		assert.Equal(t, DefaultUrlPatternExclusionValue, c.UrlPatternExclusion)
 		assert.Equal(t, DefaultUseXForwardedHostValue, c.UseXForwardedHost)
 
-		assert.NotNil(t, c.httpClient)
-		assert.NotNil(t, c.urlPatternExclusion)
-		assert.Nil(t, c.urlPatternInclusion)
+		assert.NotNil(t, c.current().httpClient)
+		assert.NotNil(t, c.current().urlPatternExclusion)
+		assert.Nil(t, c.current().urlPatternInclusion)
 	})
 
 	t.Run("Error is returned when passing an empty string for the server-side key", func(t *testing.T) {
@@ -81,6 +86,13 @@ func TestNewClient(t *testing.T) {
 		assert.NotNil(t, err)
 		assert.Equal(t, "ServerSideKey must be defined", err.Error())
 	})
+
+	t.Run("Error is returned when passing an invalid endpoint", func(t *testing.T) {
+		c, err := NewClient("your-api-key", WithEndpoint("ftp://api.datadome.co"))
+
+		assert.Nil(t, c)
+		assert.EqualError(t, err, `Endpoint must be a valid URL or host: "ftp://api.datadome.co"`)
+	})
 }
 
 func TestBuildRequest(t *testing.T) {
@@ -88,7 +100,7 @@ func TestBuildRequest(t *testing.T) {
 	assert.Nil(t, err)
 
 	request := setupRequest()
-	result, err := dd.buildRequest(request)
+	result, err := dd.current().buildRequest(request)
 
 	assert.Equal(t, nil, err)
 	expectedResult := fmt.Sprintf("APIConnectionState=new&Accept=application%%2Fjson&AcceptCharset=utf8&AcceptEncoding=fr-FR&AuthorizationLen=0&CacheControl=max-age%%3D604800&Connection=new&CookiesLen=0&HeadersList=Accept-Encoding%%2COrigin%%2CX-Requested-With%%2CHello%%2CUser-Agent%%2CReferer%%2CAccept%%2CCache-Control%%2CX-Real-Ip%%2CAccept-Charset%%2CX-Forwarded-For%%2CConnection%%2CPragma&Host=www.example.com&IP=127.0.0.1&Key=Ob1w4n+K3n0by&Method=GET&ModuleVersion=%s&Origin=www.example.com&PostParamLen=0&Pragma=no-cache&Protocol=http&Referer=www.example2.com&Request=%%2Fping&RequestModuleName=%s&ServerHostname=www.example.com&Port=80&ServerName=www.example.com&TimeRequest=1695386441016659&UserAgent=%%C3%%BCber+cool+mozilla&X-Real-IP=127.0.0.1&X-Requested-With=%%C3%%BCber_script&XForwardedForIP=192.168.10.10%%2C+127.0.0.1", DefaultModuleVersionValue, DefaultModuleNameValue)
@@ -99,6 +111,28 @@ func TestBuildRequest(t *testing.T) {
 	}
 }
 
+func TestPayload(t *testing.T) {
+	dd, err := NewClient("Ob1w4n K3n0by")
+	assert.Nil(t, err)
+
+	// the exclusion pattern is not applied
+	request := httptest.NewRequest(http.MethodGet, "http://www.example.com/picture.jpg?size=1", nil)
+	request.Header.Set("User-Agent", "Mozilla")
+	payload, err := dd.Payload(request)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "Ob1w4n K3n0by", payload.Get("Key"))
+	assert.Equal(t, "/picture.jpg?size=1", payload.Get("Request"))
+	assert.Equal(t, "Mozilla", payload.Get("UserAgent"))
+	assert.Equal(t, "192.0.2.1", payload.Get("IP"))
+
+	request.RemoteAddr = "invalid"
+	payload, err = dd.Payload(request)
+
+	assert.Nil(t, payload)
+	assert.ErrorContains(t, err, "fail to parse request's IP")
+}
+
 func TestAddDataDomeHeaders(t *testing.T) {
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
@@ -131,7 +165,7 @@ func TestAddDataDomeHeaders(t *testing.T) {
 	ddResp, _, err := DoCall(t, "/validate-request", http.MethodPost)
 	assert.Equal(t, nil, err)
 
-	origResp = addDataDomeHeaders(ddResp, origResp)
+	origResp = addDataDomeHeaders(getDataDomeHeaders(ddResp, "x-datadome-headers"), origResp)
 
 	assert.Equal(t, "", origResp.Header().Get("X-Datadome-Headers"))
 	assert.Equal(t, "protected", origResp.Header().Get("X-Datadome"))
@@ -291,7 +325,7 @@ func TestAddDataDomeRequestHeaders(t *testing.T) {
 	request, _ := http.NewRequest(http.MethodPost, "/validate-request", nil)
 	response, _ := client.Do(request)
 
-	addDataDomeRequestHeaders(response, request)
+	addDataDomeRequestHeaders(getDataDomeHeaders(response, "x-datadome-request-headers"), request)
 
 	assert.Equal(t, "1", request.Header.Get("X-Datadome-isbot"))
 	assert.Equal(t, "", request.Header.Get("X-DataDome-Obiwan"))
@@ -305,3 +339,274 @@ func TestGetClientId(t *testing.T) {
 
 	assert.Equal(t, "123456", result)
 }
+
+func TestDatadomeHandler(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Next", "called")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	t.Run("Next handler is not called when the request is blocked", func(t *testing.T) {
+		httpmock.RegisterResponder("POST", "https://api.datadome.co/validate-request",
+			func(req *http.Request) (*http.Response, error) {
+				resp := httpmock.NewStringResponse(403, "blocked")
+				resp.Header.Add("X-Datadomeresponse", "403")
+				return resp, nil
+			},
+		)
+		client, err := NewClient("azerty")
+		assert.Nil(t, err)
+
+		rw := httptest.NewRecorder()
+		client.DatadomeHandler(next).ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+		assert.Equal(t, http.StatusForbidden, rw.Code)
+		assert.Equal(t, "blocked", rw.Body.String())
+		assert.Equal(t, "", rw.Header().Get("X-Next"))
+	})
+
+	t.Run("Next handler is called for excluded paths", func(t *testing.T) {
+		client, err := NewClient("azerty")
+		assert.Nil(t, err)
+
+		rw := httptest.NewRecorder()
+		client.DatadomeHandler(next).ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/picture.jpg", nil))
+
+		assert.Equal(t, "called", rw.Header().Get("X-Next"))
+	})
+
+	t.Run("Next handler is called when the Protection API fails", func(t *testing.T) {
+		httpmock.RegisterResponder("POST", "https://api.datadome.co/validate-request",
+			httpmock.NewErrorResponder(fmt.Errorf("connection reset")),
+		)
+		client, err := NewClient("azerty", WithLogger(&MockLogger{}))
+		assert.Nil(t, err)
+
+		rw := httptest.NewRecorder()
+		client.DatadomeHandler(next).ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+		assert.Equal(t, http.StatusOK, rw.Code)
+		assert.Equal(t, "called", rw.Header().Get("X-Next"))
+	})
+}
+
+func TestEvaluate(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	t.Run("Decision of an allowed request", func(t *testing.T) {
+		httpmock.RegisterResponder("POST", "https://api.datadome.co/validate-request",
+			func(req *http.Request) (*http.Response, error) {
+				resp := httpmock.NewStringResponse(200, "")
+				resp.Header.Add("X-Datadomeresponse", "200")
+				resp.Header.Add("X-Datadome-Headers", "X-Datadome")
+				resp.Header.Add("X-Datadome", "protected")
+				resp.Header.Add("X-Datadome-Request-Headers", "X-Datadome-Botname")
+				resp.Header.Add("X-Datadome-Botname", "none")
+				return resp, nil
+			},
+		)
+		client, err := NewClient("azerty")
+		assert.Nil(t, err)
+
+		r := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		decision, err := client.Evaluate(r)
+
+		assert.Nil(t, err)
+		assert.False(t, decision.Blocked)
+		assert.Equal(t, 200, decision.StatusCode)
+		assert.Equal(t, "protected", decision.ResponseHeaders.Get("X-Datadome"))
+		assert.Equal(t, "none", decision.RequestHeaders.Get("X-Datadome-Botname"))
+		assert.Equal(t, "", r.Header.Get("X-Datadome-Botname"))
+	})
+
+	t.Run("Decision of a blocked request", func(t *testing.T) {
+		httpmock.RegisterResponder("POST", "https://api.datadome.co/validate-request",
+			func(req *http.Request) (*http.Response, error) {
+				resp := httpmock.NewStringResponse(403, "blocked")
+				resp.Header.Add("X-Datadomeresponse", "403")
+				resp.Header.Add("X-Datadome-Headers", "Set-Cookie")
+				resp.Header.Add("Set-Cookie", "datadome=value")
+				return resp, nil
+			},
+		)
+		client, err := NewClient("azerty")
+		assert.Nil(t, err)
+
+		decision, err := client.Evaluate(httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+		assert.Nil(t, err)
+		assert.True(t, decision.Blocked)
+		assert.Equal(t, 403, decision.StatusCode)
+		assert.Equal(t, "blocked", string(decision.Body))
+		assert.Equal(t, "datadome=value", decision.ResponseHeaders.Get("Set-Cookie"))
+	})
+
+	t.Run("Decision of an excluded request", func(t *testing.T) {
+		client, err := NewClient("azerty")
+		assert.Nil(t, err)
+
+		decision, err := client.Evaluate(httptest.NewRequest(http.MethodGet, "/picture.jpg", nil))
+
+		assert.Nil(t, err)
+		assert.False(t, decision.Blocked)
+		assert.Equal(t, SkipReasonUrlPatternExclusion, decision.SkipReason)
+		assert.Equal(t, 0, decision.StatusCode)
+	})
+}
+
+func TestUpdate(t *testing.T) {
+	t.Run("Next requests use the updated settings", func(t *testing.T) {
+		client, err := NewClient("azerty", WithTimeout(300))
+		assert.Nil(t, err)
+
+		err = client.Update(WithUrlPatternInclusion(`^/api/`), WithServerSideKey("qwerty"))
+
+		assert.Nil(t, err)
+		decision, err := client.Evaluate(httptest.NewRequest(http.MethodGet, "/ping", nil))
+		assert.Nil(t, err)
+		assert.Equal(t, SkipReasonUrlPatternInclusion, decision.SkipReason)
+		payload, err := client.Payload(setupRequest())
+		assert.Nil(t, err)
+		assert.Equal(t, "qwerty", payload.Get("Key"))
+
+		// the settings which were not updated are kept
+		s := client.current()
+		assert.Equal(t, 300, s.timeout)
+		assert.Equal(t, DefaultUrlPatternExclusionValue, s.urlPatternExclusion.String())
+		// the exported fields keep the values of the creation
+		assert.Equal(t, "azerty", client.ServerSideKey)
+		assert.Equal(t, DefaultUrlPatternInclusionValue, client.UrlPatternInclusion)
+	})
+
+	t.Run("Current logger", func(t *testing.T) {
+		logger := &MockLogger{}
+		client, err := NewClient("azerty")
+		assert.Nil(t, err)
+
+		assert.Nil(t, client.Update(WithLogger(logger)))
+
+		assert.Same(t, logger, client.CurrentLogger())
+		assert.NotSame(t, logger, client.Logger)
+	})
+
+	t.Run("Updates without change are ignored", func(t *testing.T) {
+		logger := &syncLogger{}
+		path := filepath.Join(t.TempDir(), "denylist.txt")
+		assert.Nil(t, os.WriteFile(path, []byte("192.0.2.1\n"), 0o600))
+		client, err := NewClient("azerty", WithLogger(logger), WithTimeout(300), WithDenyListFile(path), WithKeyEventHandler(func(KeyEvent) {}))
+		assert.Nil(t, err)
+		s := client.current()
+
+		assert.Nil(t, client.Update())
+		assert.Nil(t, client.Update(WithTimeout(300), WithServerSideKey("azerty")))
+
+		assert.Same(t, s, client.current())
+		assert.Empty(t, logger.last())
+
+		// the list files are read again
+		assert.Nil(t, os.WriteFile(path, []byte("192.0.2.2\n"), 0o600))
+		assert.Nil(t, client.Update())
+
+		assert.NotSame(t, s, client.current())
+		assert.Equal(t, "INFO DataDome settings updated", logger.last())
+	})
+
+	t.Run("Invalid updates are rejected", func(t *testing.T) {
+		client, err := NewClient("azerty")
+		assert.Nil(t, err)
+		s := client.current()
+
+		err = client.Update(WithUrlPatternInclusion(`^/api/`), WithUrlPatternExclusion(`(invalid`))
+
+		assert.ErrorContains(t, err, "UrlPatternExclusion must be a valid RegExp")
+		assert.Same(t, s, client.current())
+		decision, err := client.Evaluate(httptest.NewRequest(http.MethodGet, "/picture.jpg", nil))
+		assert.Nil(t, err)
+		assert.Equal(t, SkipReasonUrlPatternExclusion, decision.SkipReason)
+
+		err = client.Update(WithEndpoint("ftp://api.datadome.co"))
+
+		assert.EqualError(t, err, `Endpoint must be a valid URL or host: "ftp://api.datadome.co"`)
+		assert.Same(t, s, client.current())
+	})
+
+	t.Run("In-flight requests keep their settings", func(t *testing.T) {
+		received := make(chan string)
+		release := make(chan struct{})
+		api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = r.ParseForm()
+			received <- r.PostForm.Get("Key")
+			<-release
+			w.Header().Set("X-Datadomeresponse", "200")
+		}))
+		defer api.Close()
+		client, err := NewClient("azerty", WithEndpoint(api.URL), WithTimeout(5000))
+		assert.Nil(t, err)
+
+		done := make(chan error)
+		go func() {
+			_, err := client.Evaluate(httptest.NewRequest(http.MethodGet, "/ping", nil))
+			done <- err
+		}()
+		assert.Equal(t, "azerty", <-received)
+		assert.Nil(t, client.Update(WithServerSideKey("qwerty"), WithTimeout(1)))
+		close(release)
+
+		assert.Nil(t, <-done)
+	})
+
+	t.Run("Concurrent updates and requests", func(t *testing.T) {
+		client, err := NewClient("azerty", WithUrlPatternExclusion(`^/`))
+		assert.Nil(t, err)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					decision, err := client.Evaluate(httptest.NewRequest(http.MethodGet, "/ping", nil))
+					assert.Nil(t, err)
+					assert.Equal(t, SkipReasonUrlPatternExclusion, decision.SkipReason)
+				}
+			}()
+		}
+		for i := 0; i < 100; i++ {
+			assert.Nil(t, client.Update(WithTimeout(100+i)))
+		}
+		wg.Wait()
+
+		assert.Equal(t, 199, client.current().timeout)
+	})
+}
+
+func TestClientNotInitialized(t *testing.T) {
+	logger := &syncLogger{}
+	client := &Client{ServerSideKey: "azerty", Logger: logger}
+	r := httptest.NewRequest(http.MethodGet, "/ping", nil)
+
+	_, err := client.Evaluate(r)
+	assert.ErrorIs(t, err, ErrClientNotInitialized)
+
+	isBlocked, err := client.DatadomeProtect(httptest.NewRecorder(), r)
+	assert.False(t, isBlocked)
+	assert.ErrorIs(t, err, ErrClientNotInitialized)
+
+	// the requests are allowed (fail open)
+	nextCalled := false
+	client.DatadomeHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nextCalled = true
+	})).ServeHTTP(httptest.NewRecorder(), r)
+	assert.True(t, nextCalled)
+	assert.Equal(t, "ERROR fail to protect the request: the Client must be created with NewClient or NewClientFromConfig", logger.last())
+
+	_, err = client.Payload(r)
+	assert.ErrorIs(t, err, ErrClientNotInitialized)
+	assert.ErrorIs(t, client.Update(WithTimeout(100)), ErrClientNotInitialized)
+	assert.ErrorIs(t, client.WatchAccessLists(context.Background(), time.Second), ErrClientNotInitialized)
+	assert.Same(t, logger, client.CurrentLogger())
+}
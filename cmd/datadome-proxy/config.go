@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+
+	modulego "github.com/andynuge/datadome-go"
+)
+
+const (
+	defaultHealthPath      = "/healthz"
+	defaultListen          = ":8080"
+	defaultShutdownTimeout = 10
+)
+
+// config describes the settings of the proxy.
+// The values are read from a JSON file first, then overridden by the environment variables.
+type config struct {
+	// Proxy settings
+	HealthPath      string `json:"healthPath"`
+	Listen          string `json:"listen"`
+	PreserveHost    bool   `json:"preserveHost"`
+	ShutdownTimeout int    `json:"shutdownTimeout"`
+	TLSCertFile     string `json:"tlsCertFile"`
+	TLSKeyFile      string `json:"tlsKeyFile"`
+	Upstream        string `json:"upstream"`
+
+	// DataDome settings
+	modulego.Config
+}
+
+// defaultConfig returns a config filled with the default values of the proxy and of the module.
+func defaultConfig() *config {
+	return &config{
+		HealthPath:      defaultHealthPath,
+		Listen:          defaultListen,
+		ShutdownTimeout: defaultShutdownTimeout,
+
+		Config: *modulego.DefaultConfig(),
+	}
+}
+
+// loadConfig reads the configuration file (if path is not empty) and applies the environment variables on top of it.
+func loadConfig(path string, lookupEnv func(string) (string, bool)) (*config, error) {
+	cfg := defaultConfig()
+
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("fail to open configuration file: %w", err)
+		}
+		defer f.Close()
+
+		decoder := json.NewDecoder(f)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(cfg); err != nil {
+			return nil, fmt.Errorf("fail to decode configuration file: %w", err)
+		}
+	}
+
+	if err := cfg.applyEnv(lookupEnv); err != nil {
+		return nil, err
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// applyEnv overrides the configuration with the `DATADOME_PROXY_*` and `DATADOME_*` environment variables.
+func (cfg *config) applyEnv(lookupEnv func(string) (string, bool)) error {
+	stringFields := map[string]*string{
+		"DATADOME_PROXY_HEALTH_PATH":   &cfg.HealthPath,
+		"DATADOME_PROXY_LISTEN":        &cfg.Listen,
+		"DATADOME_PROXY_TLS_CERT_FILE": &cfg.TLSCertFile,
+		"DATADOME_PROXY_TLS_KEY_FILE":  &cfg.TLSKeyFile,
+		"DATADOME_PROXY_UPSTREAM":      &cfg.Upstream,
+	}
+	for name, field := range stringFields {
+		if value, ok := lookupEnv(name); ok {
+			*field = value
+		}
+	}
+
+	if value, ok := lookupEnv("DATADOME_PROXY_PRESERVE_HOST"); ok {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("DATADOME_PROXY_PRESERVE_HOST must be a boolean: %w", err)
+		}
+		cfg.PreserveHost = b
+	}
+
+	if value, ok := lookupEnv("DATADOME_PROXY_SHUTDOWN_TIMEOUT"); ok {
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("DATADOME_PROXY_SHUTDOWN_TIMEOUT must be an integer: %w", err)
+		}
+		cfg.ShutdownTimeout = i
+	}
+
+	return cfg.Config.ApplyEnv(lookupEnv)
+}
+
+// validate checks the proxy settings.
+// The DataDome settings are validated by [modulego.NewClientFromConfig].
+func (cfg *config) validate() error {
+	if cfg.Upstream == "" {
+		return fmt.Errorf("upstream must be defined")
+	}
+	u, err := url.Parse(cfg.Upstream)
+	if err != nil {
+		return fmt.Errorf("upstream must be a valid URL: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("upstream must be an absolute http or https URL")
+	}
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		return fmt.Errorf("tlsCertFile and tlsKeyFile must be defined together")
+	}
+	if cfg.ShutdownTimeout < 0 {
+		return fmt.Errorf("shutdownTimeout must be a positive integer")
+	}
+	if cfg.HealthPath != "" && cfg.HealthPath[0] != '/' {
+		return fmt.Errorf("healthPath must start with a slash")
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	modulego "github.com/andynuge/datadome-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func lookupEnvFrom(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("Default values are used when not overridden", func(t *testing.T) {
+		cfg, err := loadConfig("", lookupEnvFrom(map[string]string{
+			"DATADOME_PROXY_UPSTREAM":  "http://localhost:3000",
+			"DATADOME_SERVER_SIDE_KEY": "your-api-key",
+		}))
+
+		assert.Nil(t, err)
+		assert.Equal(t, defaultListen, cfg.Listen)
+		assert.Equal(t, defaultHealthPath, cfg.HealthPath)
+		assert.Equal(t, modulego.DefaultTimeoutValue, cfg.Timeout)
+		assert.Equal(t, modulego.DefaultUrlPatternExclusionValue, cfg.UrlPatternExclusion)
+		assert.Equal(t, "your-api-key", cfg.ServerSideKey)
+	})
+
+	t.Run("Environment variables override the configuration file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		err := os.WriteFile(path, []byte(`{"upstream": "http://localhost:3000", "timeout": 300, "listen": ":9000", "enableGraphQLSupport": true}`), 0o600)
+		assert.Nil(t, err)
+
+		cfg, err := loadConfig(path, lookupEnvFrom(map[string]string{
+			"DATADOME_TIMEOUT":         "500",
+			"DATADOME_SERVER_SIDE_KEY": "your-api-key",
+		}))
+
+		assert.Nil(t, err)
+		assert.Equal(t, ":9000", cfg.Listen)
+		assert.Equal(t, 500, cfg.Timeout)
+		assert.True(t, cfg.EnableGraphQLSupport)
+	})
+
+	t.Run("Unknown fields are rejected", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		err := os.WriteFile(path, []byte(`{"upstream": "http://localhost:3000", "timeoutMs": 300}`), 0o600)
+		assert.Nil(t, err)
+
+		cfg, err := loadConfig(path, lookupEnvFrom(nil))
+
+		assert.Nil(t, cfg)
+		assert.ErrorContains(t, err, "unknown field")
+	})
+
+	t.Run("Invalid environment values are rejected", func(t *testing.T) {
+		cfg, err := loadConfig("", lookupEnvFrom(map[string]string{
+			"DATADOME_PROXY_UPSTREAM": "http://localhost:3000",
+			"DATADOME_TIMEOUT":        "fast",
+		}))
+
+		assert.Nil(t, cfg)
+		assert.ErrorContains(t, err, "DATADOME_TIMEOUT must be an integer")
+	})
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		update func(*config)
+		err    string
+	}{
+		{name: "Missing upstream", update: func(c *config) { c.Upstream = "" }, err: "upstream must be defined"},
+		{name: "Relative upstream", update: func(c *config) { c.Upstream = "/app" }, err: "upstream must be an absolute http or https URL"},
+		{name: "Certificate without key", update: func(c *config) { c.TLSCertFile = "cert.pem" }, err: "tlsCertFile and tlsKeyFile must be defined together"},
+		{name: "Relative health path", update: func(c *config) { c.HealthPath = "healthz" }, err: "healthPath must start with a slash"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := defaultConfig()
+			cfg.Upstream = "http://localhost:3000"
+			tc.update(cfg)
+
+			assert.EqualError(t, cfg.validate(), tc.err)
+		})
+	}
+}
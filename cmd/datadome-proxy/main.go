@@ -0,0 +1,116 @@
+// Command datadome-proxy is a reverse proxy protecting an upstream application with DataDome.
+//
+// Every request is validated by the Protection API before being forwarded to the upstream,
+// so that applications written in any language can be protected without code changes.
+//
+// Usage:
+//
+//	datadome-proxy [-config path/to/config.json]
+//
+// The configuration file is a JSON document whose keys match the fields of the config structure.
+// Each setting may be overridden by an environment variable:
+//
+//	DATADOME_PROXY_UPSTREAM                   URL of the protected application (required)
+//	DATADOME_PROXY_LISTEN                     listen address (default ":8080")
+//	DATADOME_PROXY_TLS_CERT_FILE              TLS certificate file
+//	DATADOME_PROXY_TLS_KEY_FILE               TLS private key file
+//	DATADOME_PROXY_HEALTH_PATH                path of the health endpoint (default "/healthz")
+//	DATADOME_PROXY_PRESERVE_HOST              forward the incoming Host header to the upstream
+//	DATADOME_PROXY_SHUTDOWN_TIMEOUT           graceful shutdown timeout in seconds (default 10)
+//	DATADOME_SERVER_SIDE_KEY                  DataDome server-side key (required)
+//	DATADOME_SERVER_SIDE_KEY_FILE             file containing the server-side key
+//	DATADOME_ENDPOINT                         Protection API endpoint
+//	DATADOME_TIMEOUT                          Protection API timeout in milliseconds
+//	DATADOME_MAXIMUM_BODY_SIZE                maximum body size read for GraphQL requests
+//	DATADOME_ENABLE_GRAPHQL_SUPPORT           enable the GraphQL support
+//	DATADOME_ENABLE_REFERRER_RESTORATION      enable the referrer restoration
+//	DATADOME_ENABLE_SERVER_SIDE_KEY_FALLBACK  use the secondary key when the server-side key is rejected
+//	DATADOME_SECONDARY_SERVER_SIDE_KEY        key used when the server-side key is rejected
+//	DATADOME_URL_PATTERN_EXCLUSION            regular expression of the excluded URLs
+//	DATADOME_URL_PATTERN_INCLUSION            regular expression of the included URLs
+//	DATADOME_USE_X_FORWARDED_HOST             use the X-Forwarded-Host header as host
+//	DATADOME_BYPASS_SECRET                    secret of the bypass signatures of the internal requests
+//	DATADOME_BYPASS_HEADER                    header containing the bypass signature
+//	DATADOME_BYPASS_WINDOW                    validity of the bypass signatures in seconds
+//	DATADOME_BYPASS_CLIENT_CA_FILE            file containing the CAs of the bypass client certificates
+//	DATADOME_ALLOW_LIST_FILE                  file listing the allowed IPs and CIDRs
+//	DATADOME_DENY_BODY                        body of the denied requests
+//	DATADOME_DENY_LIST_FILE                   file listing the denied IPs, CIDRs and client IDs
+//	DATADOME_DENY_STATUS_CODE                 status code of the denied requests
+//	DATADOME_GRAPHQL_ENDPOINT_PATTERN         regular expression of the GraphQL endpoint paths
+//	DATADOME_GRAPHQL_WEBSOCKET_MESSAGES       number of GraphQL WebSocket messages evaluated
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	modulego "github.com/andynuge/datadome-go"
+)
+
+// keyFileCheckInterval is the interval between two reads of the server-side key file and of the access list files.
+const keyFileCheckInterval = 30 * time.Second
+
+func main() {
+	configPath := flag.String("config", "", "path to the JSON configuration file")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, *configPath); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(ctx context.Context, configPath string) error {
+	cfg, err := loadConfig(configPath, os.LookupEnv)
+	if err != nil {
+		return err
+	}
+
+	client, err := modulego.NewClientFromConfig(&cfg.Config)
+	if err != nil {
+		return err
+	}
+	if cfg.ServerSideKeyFile != "" {
+		// the key file is checked periodically so that a rotated secret is used without restarting
+		go func() {
+			_ = client.WatchServerSideKey(ctx, modulego.FileKeySource(cfg.ServerSideKeyFile), keyFileCheckInterval)
+		}()
+	}
+	if cfg.AllowListFile != "" || cfg.DenyListFile != "" {
+		go func() {
+			_ = client.WatchAccessLists(ctx, keyFileCheckInterval)
+		}()
+	}
+
+	handler, err := newHandler(cfg, client)
+	if err != nil {
+		return err
+	}
+
+	ln, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		return err
+	}
+	log.Printf("listening on %s, forwarding to %s", ln.Addr(), cfg.Upstream)
+
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if cfg.BypassClientCAFile != "" {
+		// the client certificates are verified by the client against the bypass CAs
+		srv.TLSConfig = &tls.Config{ClientAuth: tls.RequestClientCert}
+	}
+	return serve(ctx, cfg, srv, ln)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"time"
+
+	modulego "github.com/andynuge/datadome-go"
+)
+
+// newHandler returns the handler of the proxy.
+// Requests are validated by the DataDome [modulego.Client] before being forwarded to the upstream.
+// The health endpoint is answered directly and is never sent to the Protection API.
+func newHandler(cfg *config, client *modulego.Client) (http.Handler, error) {
+	upstream, err := url.Parse(cfg.Upstream)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(upstream)
+			pr.SetXForwarded()
+			if cfg.PreserveHost {
+				pr.Out.Host = pr.In.Host
+			}
+		},
+	}
+
+	protected := client.DatadomeHandler(proxy)
+
+	// the paths are not cleaned like a ServeMux does: the requests are forwarded unchanged instead of redirected
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cfg.HealthPath != "" && r.URL.Path == cfg.HealthPath {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("ok"))
+			return
+		}
+		protected.ServeHTTP(w, r)
+	}), nil
+}
+
+// serve accepts connections on the listener until the context is done.
+// The in-flight requests are then given ShutdownTimeout seconds to complete.
+func serve(ctx context.Context, cfg *config, srv *http.Server, ln net.Listener) error {
+	errCh := make(chan error, 1)
+	go func() {
+		if cfg.TLSCertFile != "" {
+			errCh <- srv.ServeTLS(ln, cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			errCh <- srv.Serve(ln)
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("shutting down, waiting up to %ds for in-flight requests", cfg.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeout)*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
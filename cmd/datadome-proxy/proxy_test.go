@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	modulego "github.com/andynuge/datadome-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupProxy(t *testing.T, apiStatus int) (http.Handler, *int) {
+	upstreamCalls := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamCalls++
+		w.Header().Set("X-Upstream-Host", r.Host)
+		w.Header().Set("X-Upstream-URI", r.RequestURI)
+		_, _ = io.WriteString(w, "upstream")
+	}))
+	t.Cleanup(upstream.Close)
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Datadomeresponse", strconv.Itoa(apiStatus))
+		w.WriteHeader(apiStatus)
+		_, _ = io.WriteString(w, "blocked by datadome")
+	}))
+	t.Cleanup(api.Close)
+
+	cfg := defaultConfig()
+	cfg.Upstream = upstream.URL
+	cfg.Endpoint = api.URL + "/validate-request"
+	cfg.PreserveHost = true
+
+	client, err := modulego.NewClient("your-api-key", append(cfg.Config.Options(), modulego.WithLogger(nopLogger{}))...)
+	assert.Nil(t, err)
+
+	handler, err := newHandler(cfg, client)
+	assert.Nil(t, err)
+
+	return handler, &upstreamCalls
+}
+
+func TestNewHandler(t *testing.T) {
+	t.Run("Allowed requests are forwarded to the upstream", func(t *testing.T) {
+		handler, upstreamCalls := setupProxy(t, http.StatusOK)
+
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "http://www.example.com/ping", nil))
+
+		assert.Equal(t, http.StatusOK, rw.Code)
+		assert.Equal(t, "upstream", rw.Body.String())
+		assert.Equal(t, "www.example.com", rw.Header().Get("X-Upstream-Host"))
+		assert.Equal(t, 1, *upstreamCalls)
+	})
+
+	t.Run("Paths are forwarded unchanged", func(t *testing.T) {
+		handler, upstreamCalls := setupProxy(t, http.StatusOK)
+
+		for _, uri := range []string{"//a", "/a/../b", "/a/./b?c=d"} {
+			rw := httptest.NewRecorder()
+			handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, uri, nil))
+
+			assert.Equal(t, http.StatusOK, rw.Code, uri)
+			assert.Equal(t, uri, rw.Header().Get("X-Upstream-URI"))
+		}
+		assert.Equal(t, 3, *upstreamCalls)
+	})
+
+	t.Run("Blocked requests are not forwarded to the upstream", func(t *testing.T) {
+		handler, upstreamCalls := setupProxy(t, http.StatusForbidden)
+
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "http://www.example.com/ping", nil))
+
+		assert.Equal(t, http.StatusForbidden, rw.Code)
+		assert.Equal(t, "blocked by datadome", rw.Body.String())
+		assert.Equal(t, 0, *upstreamCalls)
+	})
+
+	t.Run("Health endpoint is not protected nor forwarded", func(t *testing.T) {
+		handler, upstreamCalls := setupProxy(t, http.StatusForbidden)
+
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, defaultHealthPath, nil))
+
+		assert.Equal(t, http.StatusOK, rw.Code)
+		assert.Equal(t, "ok", rw.Body.String())
+		assert.Equal(t, 0, *upstreamCalls)
+	})
+}
+
+func TestServe(t *testing.T) {
+	cfg := defaultConfig()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	started := make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		_, _ = io.WriteString(w, "done")
+	})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- serve(ctx, cfg, srv, ln)
+	}()
+
+	respCh := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err != nil {
+			respCh <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		respCh <- string(body)
+	}()
+
+	<-started
+	cancel()
+
+	assert.Equal(t, "done", <-respCh)
+	assert.Nil(t, <-errCh)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(args ...interface{}) {}
+func (nopLogger) Info(args ...interface{})  {}
+func (nopLogger) Warn(args ...interface{})  {}
+func (nopLogger) Error(args ...interface{}) {}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Access log formats.
+const (
+	formatAuto     = "auto"
+	formatCombined = "combined"
+	formatJSON     = "json"
+)
+
+// combinedLogPattern matches the Common and Combined Log Formats.
+var combinedLogPattern = regexp.MustCompile(`^(\S+) \S+ \S+ \[[^\]]*\] "(\S+) (\S+)(?: (\S+))?" (\d{3}) \S+(?: "((?:[^"\\]|\\.)*)" "((?:[^"\\]|\\.)*)")?`)
+
+// jsonLogFields lists the keys read from the JSON logs, by order of preference.
+var jsonLogFields = map[string][]string{
+	"ip":        {"remote_addr", "client_ip", "ip", "remote_ip", "clientip"},
+	"method":    {"method", "request_method", "verb"},
+	"uri":       {"request_uri", "uri", "path", "url"},
+	"protocol":  {"protocol", "server_protocol", "http_version"},
+	"host":      {"host", "http_host", "server_name", "vhost"},
+	"status":    {"status", "status_code", "response_status"},
+	"userAgent": {"user_agent", "http_user_agent", "useragent", "agent"},
+	"referer":   {"referer", "http_referer", "referrer"},
+	"request":   {"request"},
+}
+
+// logEntry is a request read from an access log.
+type logEntry struct {
+	IP       string
+	Method   string
+	URI      string
+	Protocol string
+	Host     string
+	Status   int
+	Header   http.Header
+}
+
+// parseLogLine parses a line of access log in the given format.
+func parseLogLine(line, format string) (*logEntry, error) {
+	line = strings.TrimSpace(line)
+	if format == formatAuto {
+		format = formatCombined
+		if strings.HasPrefix(line, "{") {
+			format = formatJSON
+		}
+	}
+	switch format {
+	case formatCombined:
+		return parseCombinedLine(line)
+	case formatJSON:
+		return parseJSONLine(line)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+}
+
+// parseCombinedLine parses a line in the Common or Combined Log Format.
+func parseCombinedLine(line string) (*logEntry, error) {
+	m := combinedLogPattern.FindStringSubmatch(line)
+	if m == nil {
+		return nil, errors.New("line does not match the Combined Log Format")
+	}
+	status, _ := strconv.Atoi(m[5])
+	e := &logEntry{
+		IP:       m[1],
+		Method:   m[2],
+		URI:      m[3],
+		Protocol: m[4],
+		Status:   status,
+		Header:   http.Header{},
+	}
+	if referer := unescapeLogValue(m[6]); referer != "" && referer != "-" {
+		e.Header.Set("Referer", referer)
+	}
+	if userAgent := unescapeLogValue(m[7]); userAgent != "" && userAgent != "-" {
+		e.Header.Set("User-Agent", userAgent)
+	}
+	return e, nil
+}
+
+// unescapeLogValue removes the escaping of the quoted values of the Combined Log Format.
+func unescapeLogValue(v string) string {
+	if !strings.Contains(v, `\`) {
+		return v
+	}
+	return strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(v)
+}
+
+// parseJSONLine parses a JSON log line.
+// The well-known keys of the nginx, Apache, Caddy and Traefik logs are read,
+// and the `http_*` keys are converted to headers (`http_accept_language` becomes `Accept-Language`).
+func parseJSONLine(line string) (*logEntry, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+		return nil, fmt.Errorf("invalid JSON log line: %w", err)
+	}
+	get := func(name string) string {
+		for _, key := range jsonLogFields[name] {
+			if v, ok := fields[key]; ok && v != nil {
+				return fmt.Sprint(v)
+			}
+		}
+		return ""
+	}
+
+	e := &logEntry{
+		IP:       get("ip"),
+		Method:   get("method"),
+		URI:      get("uri"),
+		Protocol: get("protocol"),
+		Host:     get("host"),
+		Header:   http.Header{},
+	}
+	e.Status, _ = strconv.Atoi(get("status"))
+	if request := get("request"); request != "" && (e.Method == "" || e.URI == "") {
+		parts := strings.Fields(request)
+		if len(parts) >= 2 {
+			e.Method, e.URI = parts[0], parts[1]
+		}
+		if len(parts) >= 3 {
+			e.Protocol = parts[2]
+		}
+	}
+	for key, v := range fields {
+		if name, ok := strings.CutPrefix(key, "http_"); ok && key != "http_host" && key != "http_version" && v != nil {
+			e.Header.Set(strings.ReplaceAll(name, "_", "-"), fmt.Sprint(v))
+		}
+	}
+	if userAgent := get("userAgent"); userAgent != "" {
+		e.Header.Set("User-Agent", userAgent)
+	}
+	if referer := get("referer"); referer != "" && referer != "-" {
+		e.Header.Set("Referer", referer)
+	}
+
+	if e.Method == "" || e.URI == "" {
+		return nil, errors.New("JSON log line without method or URI")
+	}
+	return e, nil
+}
+
+// newRequest builds the synthetic request of the entry.
+// The host of the entry is used when present, defaultHost otherwise.
+func (e *logEntry) newRequest(scheme, defaultHost string) (*http.Request, error) {
+	if net.ParseIP(e.IP) == nil {
+		return nil, fmt.Errorf("invalid IP %q", e.IP)
+	}
+	if !strings.HasPrefix(e.URI, "/") {
+		return nil, fmt.Errorf("invalid URI %q", e.URI)
+	}
+	host := e.Host
+	if host == "" || host == "-" {
+		host = defaultHost
+	}
+	u, err := url.ParseRequestURI(e.URI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URI: %w", err)
+	}
+
+	// the URL of the request holds the path and the query only, like the requests received by a server
+	r, err := http.NewRequest(e.Method, "/", http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	r.URL = u
+	r.Host = host
+	r.RequestURI = e.URI
+	r.Header = e.Header.Clone()
+	if scheme == "https" {
+		r.TLS = &tls.ConnectionState{}
+	}
+	r.RemoteAddr = net.JoinHostPort(e.IP, "0")
+	if e.Protocol != "" {
+		if major, minor, ok := http.ParseHTTPVersion(e.Protocol); ok {
+			r.Proto, r.ProtoMajor, r.ProtoMinor = e.Protocol, major, minor
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLogLine(t *testing.T) {
+	t.Run("Combined Log Format", func(t *testing.T) {
+		e, err := parseLogLine(`192.0.2.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif?a=1 HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08 \"quoted\""`, formatAuto)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "192.0.2.1", e.IP)
+		assert.Equal(t, http.MethodGet, e.Method)
+		assert.Equal(t, "/apache_pb.gif?a=1", e.URI)
+		assert.Equal(t, "HTTP/1.0", e.Protocol)
+		assert.Equal(t, http.StatusOK, e.Status)
+		assert.Equal(t, "http://www.example.com/start.html", e.Header.Get("Referer"))
+		assert.Equal(t, `Mozilla/4.08 "quoted"`, e.Header.Get("User-Agent"))
+	})
+
+	t.Run("Common Log Format", func(t *testing.T) {
+		e, err := parseLogLine(`192.0.2.1 - - [10/Oct/2000:13:55:36 -0700] "POST /login HTTP/1.1" 302 -`, formatCombined)
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.MethodPost, e.Method)
+		assert.Equal(t, http.StatusFound, e.Status)
+		assert.Empty(t, e.Header)
+	})
+
+	t.Run("JSON with separate fields", func(t *testing.T) {
+		e, err := parseLogLine(`{"client_ip":"192.0.2.1","method":"PUT","uri":"/api","host":"api.example.com","status":"201","user_agent":"Go-http-client/1.1","referer":"-"}`, formatAuto)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "192.0.2.1", e.IP)
+		assert.Equal(t, http.MethodPut, e.Method)
+		assert.Equal(t, "/api", e.URI)
+		assert.Equal(t, "api.example.com", e.Host)
+		assert.Equal(t, http.StatusCreated, e.Status)
+		assert.Equal(t, "Go-http-client/1.1", e.Header.Get("User-Agent"))
+		assert.Equal(t, "", e.Header.Get("Referer"))
+	})
+
+	t.Run("JSON with the request line and http_ fields", func(t *testing.T) {
+		e, err := parseLogLine(`{"remote_addr":"2001:db8::1","request":"GET /a?b=c HTTP/2.0","status":200,"http_user_agent":"curl/8.0","http_host":"shop.example.com","http_accept_language":"fr-FR"}`, formatJSON)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "2001:db8::1", e.IP)
+		assert.Equal(t, http.MethodGet, e.Method)
+		assert.Equal(t, "/a?b=c", e.URI)
+		assert.Equal(t, "HTTP/2.0", e.Protocol)
+		assert.Equal(t, "shop.example.com", e.Host)
+		assert.Equal(t, "curl/8.0", e.Header.Get("User-Agent"))
+		assert.Equal(t, "fr-FR", e.Header.Get("Accept-Language"))
+		assert.Equal(t, "", e.Header.Get("Host"))
+	})
+
+	tests := []struct {
+		name   string
+		line   string
+		format string
+		err    string
+	}{
+		{name: "Invalid combined line", line: "not a log line", format: formatCombined, err: "does not match"},
+		{name: "Invalid JSON", line: "{", format: formatJSON, err: "invalid JSON log line"},
+		{name: "JSON without URI", line: `{"method":"GET"}`, format: formatJSON, err: "without method or URI"},
+		{name: "Unknown format", line: "", format: "w3c", err: `unknown log format "w3c"`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := parseLogLine(tc.line, tc.format)
+
+			assert.Nil(t, e)
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestLogEntryNewRequest(t *testing.T) {
+	e := &logEntry{IP: "192.0.2.1", Method: http.MethodGet, URI: "/a?b=c", Protocol: "HTTP/2.0", Header: http.Header{"User-Agent": {"Mozilla"}}}
+
+	r, err := e.newRequest("https", "www.example.com")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "www.example.com", r.Host)
+	assert.Equal(t, "/a", r.URL.Path)
+	assert.Equal(t, "b=c", r.URL.RawQuery)
+	assert.Empty(t, r.URL.Host)
+	assert.Equal(t, "/a?b=c", r.RequestURI)
+	assert.Equal(t, "192.0.2.1:0", r.RemoteAddr)
+	assert.Equal(t, 2, r.ProtoMajor)
+	assert.Equal(t, "Mozilla", r.Header.Get("User-Agent"))
+	assert.NotNil(t, r.TLS)
+
+	e.Host = "shop.example.com"
+	r, err = e.newRequest("http", "www.example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "shop.example.com", r.Host)
+	assert.Nil(t, r.TLS)
+
+	_, err = (&logEntry{IP: "-", Method: http.MethodGet, URI: "/"}).newRequest("http", "localhost")
+	assert.ErrorContains(t, err, "invalid IP")
+	_, err = (&logEntry{IP: "192.0.2.1", Method: http.MethodConnect, URI: "example.com:443"}).newRequest("http", "localhost")
+	assert.ErrorContains(t, err, "invalid URI")
+}
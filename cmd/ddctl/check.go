@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	modulego "github.com/andynuge/datadome-go"
+)
+
+// checkResult is the JSON representation of a [modulego.Decision].
+type checkResult struct {
+	Decision        string      `json:"decision"`
+	StatusCode      int         `json:"statusCode,omitempty"`
+	SkipReason      string      `json:"skipReason,omitempty"`
+	RequestHeaders  http.Header `json:"requestHeaders,omitempty"`
+	ResponseHeaders http.Header `json:"responseHeaders,omitempty"`
+	Body            string      `json:"body,omitempty"`
+}
+
+// newCheckResult returns the [checkResult] of the decision.
+func newCheckResult(d *modulego.Decision) checkResult {
+	result := checkResult{
+		Decision:        "allowed",
+		StatusCode:      d.StatusCode,
+		SkipReason:      string(d.SkipReason),
+		RequestHeaders:  d.RequestHeaders,
+		ResponseHeaders: d.ResponseHeaders,
+	}
+	switch {
+	case d.Blocked:
+		result.Decision = "blocked"
+		result.Body = string(d.Body)
+	case d.SkipReason != "":
+		result.Decision = "skipped"
+	}
+	return result
+}
+
+// runCheck sends the payload of the request to the Protection API and prints the decision.
+func runCheck(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var cf clientFlags
+	cf.register(fs)
+	var rf requestFlags
+	rf.register(fs)
+	format := fs.String("format", "text", "output format: text or json")
+	verbose := fs.Bool("v", false, "print the debug logs of the module")
+
+	rawURL, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return usageError(fmt.Sprintf("invalid format %q", *format))
+	}
+
+	client, err := cf.newClient(true, modulego.WithLogger(&stderrLogger{w: stderr, verbose: *verbose}))
+	if err != nil {
+		return err
+	}
+	r, err := rf.newRequest(rawURL, stdin)
+	if err != nil {
+		return err
+	}
+
+	decision, err := client.Evaluate(r)
+	if err != nil {
+		return err
+	}
+	result := newCheckResult(decision)
+
+	if *format == "json" {
+		out, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(stdout, string(out))
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Decision: %s\n", result.Decision)
+	if result.SkipReason != "" {
+		fmt.Fprintf(stdout, "Skip reason: %s\n", result.SkipReason)
+	}
+	if result.StatusCode != 0 {
+		fmt.Fprintf(stdout, "Status code: %d\n", result.StatusCode)
+	}
+	printHeaders(stdout, "Request headers", result.RequestHeaders)
+	printHeaders(stdout, "Response headers", result.ResponseHeaders)
+	if result.Body != "" {
+		fmt.Fprintf(stdout, "Body:\n%s\n", result.Body)
+	}
+	return nil
+}
+
+// printHeaders prints the headers sorted by name.
+func printHeaders(w io.Writer, title string, headers http.Header) {
+	if len(headers) == 0 {
+		return
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "%s:\n", title)
+	for _, name := range names {
+		for _, value := range headers[name] {
+			fmt.Fprintf(w, "  %s: %s\n", name, value)
+		}
+	}
+}
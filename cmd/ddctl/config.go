@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	modulego "github.com/andynuge/datadome-go"
+)
+
+// runConfigValidate validates the module settings and prints every invalid one.
+// The settings are read from the -config file and the `DATADOME_*` environment variables when -config is set,
+// from the flags otherwise.
+func runConfigValidate(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var cf clientFlags
+	cf.register(fs)
+	configPath := fs.String("config", "", "JSON configuration file, see modulego.Config")
+
+	rawURL, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if rawURL != "" {
+		return usageError(fmt.Sprintf("unexpected argument %q", rawURL))
+	}
+
+	config := &cf.config
+	if *configPath != "" {
+		config, err = modulego.LoadConfig(*configPath)
+	}
+	if err == nil {
+		err = config.Validate()
+	}
+
+	var configErr *modulego.ConfigError
+	if errors.As(err, &configErr) {
+		for _, err := range configErr.Errors {
+			fmt.Fprintln(stdout, "-", err)
+		}
+		return fmt.Errorf("invalid configuration: %d error(s)", len(configErr.Errors))
+	}
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, "configuration is valid")
+	return err
+}
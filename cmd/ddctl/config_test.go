@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigValidateCommandWithFile(t *testing.T) {
+	t.Setenv("DATADOME_SERVER_SIDE_KEY", "")
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "key")
+	assert.Nil(t, os.WriteFile(keyPath, []byte("secret-key\n"), 0o600))
+
+	t.Run("Valid file", func(t *testing.T) {
+		path := filepath.Join(dir, "valid.json")
+		assert.Nil(t, os.WriteFile(path, []byte(`{"serverSideKeyFile": "`+keyPath+`", "endpoint": "api-eu.datadome.co"}`), 0o600))
+
+		code, stdout, stderr := runCommand("config", "validate", "-config", path)
+
+		assert.Equal(t, 0, code, stderr)
+		assert.Equal(t, "configuration is valid\n", stdout)
+	})
+
+	t.Run("Environment variables are applied", func(t *testing.T) {
+		path := filepath.Join(dir, "valid.json")
+		t.Setenv("DATADOME_TIMEOUT", "0")
+
+		code, stdout, _ := runCommand("config", "validate", "-config", path)
+
+		assert.Equal(t, 1, code)
+		assert.Equal(t, "- Timeout must be a positive integer\n", stdout)
+	})
+
+	t.Run("Invalid file", func(t *testing.T) {
+		path := filepath.Join(dir, "invalid.json")
+		assert.Nil(t, os.WriteFile(path, []byte(`{"timeoutMs": 300}`), 0o600))
+
+		code, _, stderr := runCommand("config", "validate", "-config", path)
+
+		assert.Equal(t, 1, code)
+		assert.Contains(t, stderr, "unknown field")
+	})
+}
@@ -0,0 +1,97 @@
+// Command ddctl inspects and tests the payloads sent to the DataDome Protection API.
+//
+// Usage:
+//
+//	ddctl payload [flags] [URL]          print the payload built for a request
+//	ddctl check [flags] [URL]            send the payload to the Protection API and print the decision
+//	ddctl replay-logs [flags] [file...]  replay access logs and print a summary of the outcomes
+//	ddctl config validate [flags]        validate the module settings
+//
+// The request is read from a raw HTTP request file (-request, "-" for the standard input)
+// or built from curl-like flags:
+//
+//	ddctl payload -X POST -H 'Accept: text/html' -A 'Mozilla/5.0' -ip 203.0.113.7 https://www.example.com/login
+//	ddctl check -key "$DATADOME_SERVER_SIDE_KEY" -request blocked-request.txt
+//
+// The replay-logs command reads access logs in the Common, Combined or JSON formats (from the files or
+// the standard input) and evaluates each line as a synthetic request, to estimate the block rate and the
+// coverage of the exclusion pattern before onboarding a site:
+//
+//	ddctl replay-logs -offline -exclusion '(?i)\.(css|js|png)$' access.log
+//	ddctl replay-logs -endpoint http://localhost:9000/validate-request -rate 50 -concurrency 8 access.log
+//
+// The server-side key defaults to the DATADOME_SERVER_SIDE_KEY environment variable
+// and is redacted from the printed payloads unless -show-key is set.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `Usage:
+  ddctl payload [flags] [URL]          print the payload built for a request
+  ddctl check [flags] [URL]            send the payload to the Protection API and print the decision
+  ddctl replay-logs [flags] [file...]  replay access logs and print a summary of the outcomes
+  ddctl config validate [flags]        validate the module settings
+
+Run "ddctl <command> -h" for the flags of a command.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command line and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "payload":
+		err = runPayload(args[1:], stdin, stdout, stderr)
+	case "check":
+		err = runCheck(args[1:], stdin, stdout, stderr)
+	case "replay-logs":
+		err = runReplayLogs(args[1:], stdin, stdout, stderr)
+	case "config":
+		if len(args) < 2 || args[1] != "validate" {
+			fmt.Fprint(stderr, usage)
+			return 2
+		}
+		err = runConfigValidate(args[2:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	switch err.(type) {
+	case nil:
+		return 0
+	case usageError:
+		if err.Error() != "" {
+			fmt.Fprintln(stderr, err)
+		}
+		return 2
+	default:
+		if err == errHelp {
+			return 0
+		}
+		fmt.Fprintln(stderr, "ddctl:", err)
+		return 1
+	}
+}
+
+// usageError is returned when the command line is invalid.
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/andynuge/datadome-go/datadometest"
+	"github.com/stretchr/testify/assert"
+)
+
+func runCommand(args ...string) (int, string, string) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	code := run(args, strings.NewReader(""), stdout, stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stderr string
+	}{
+		{name: "Without command", args: nil, code: 2, stderr: "Usage:"},
+		{name: "Unknown command", args: []string{"scan"}, code: 2, stderr: `unknown command "scan"`},
+		{name: "Config without validate", args: []string{"config"}, code: 2, stderr: "Usage:"},
+		{name: "Unknown flag", args: []string{"payload", "-unknown"}, code: 2, stderr: "flag provided but not defined"},
+		{name: "Flags after the URL", args: []string{"payload", "http://www.example.com/", "-A", "x"}, code: 2, stderr: "the flags must precede the URL"},
+		{name: "Help of a command", args: []string{"check", "-h"}, code: 0, stderr: "-request"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			code, _, stderr := runCommand(tc.args...)
+
+			assert.Equal(t, tc.code, code)
+			assert.Contains(t, stderr, tc.stderr)
+		})
+	}
+}
+
+func TestPayloadCommand(t *testing.T) {
+	t.Run("JSON format", func(t *testing.T) {
+		code, stdout, stderr := runCommand("payload", "-key", "secret-key", "-request", "testdata/request.txt")
+
+		assert.Equal(t, 0, code, stderr)
+		var payload map[string]string
+		assert.Nil(t, json.Unmarshal([]byte(stdout), &payload))
+		assert.Equal(t, redactedKey, payload["Key"])
+		assert.Equal(t, "/login?next=%2F", payload["Request"])
+		assert.Equal(t, "BadBot/1.0", payload["UserAgent"])
+		assert.Equal(t, "client-id", payload["ClientID"])
+		assert.Equal(t, "datadome,session", payload["CookiesList"])
+		assert.Equal(t, "POST", payload["Method"])
+		assert.NotContains(t, stdout, "secret-key")
+	})
+
+	t.Run("Form format with the key", func(t *testing.T) {
+		code, stdout, _ := runCommand("payload", "-key", "secret-key", "-show-key", "-format", "form", "http://www.example.com/")
+
+		assert.Equal(t, 0, code)
+		assert.Contains(t, stdout, "Key=secret-key")
+		assert.Contains(t, stdout, "Request=%2F")
+	})
+
+	t.Run("Invalid format", func(t *testing.T) {
+		code, _, stderr := runCommand("payload", "-format", "xml", "http://www.example.com/")
+
+		assert.Equal(t, 2, code)
+		assert.Contains(t, stderr, `invalid format "xml"`)
+	})
+}
+
+func TestCheckCommand(t *testing.T) {
+	s := datadometest.NewServer(
+		datadometest.WithRule(datadometest.MatchUserAgent("BadBot"), datadometest.Block(http.StatusForbidden)),
+		datadometest.WithRule(datadometest.MatchAll(), datadometest.Allow().
+			WithRequestHeaders(http.Header{"X-Datadome-Botname": {"none"}})),
+	)
+	defer s.Close()
+
+	t.Run("Blocked request", func(t *testing.T) {
+		code, stdout, stderr := runCommand("check", "-key", "secret-key", "-endpoint", s.Endpoint(), "-request", "testdata/request.txt")
+
+		assert.Equal(t, 0, code, stderr)
+		assert.Contains(t, stdout, "Decision: blocked\n")
+		assert.Contains(t, stdout, "Status code: 403\n")
+		assert.Contains(t, stdout, "Response headers:\n  Set-Cookie: datadome=blocked")
+		assert.Contains(t, stdout, "Body:\n")
+
+		payload, ok := s.LastPayload()
+		assert.True(t, ok)
+		assert.Equal(t, "secret-key", payload.Form.Get("Key"))
+	})
+
+	t.Run("Allowed request in JSON", func(t *testing.T) {
+		code, stdout, _ := runCommand("check", "-key", "secret-key", "-endpoint", s.Endpoint(), "-format", "json", "http://www.example.com/")
+
+		assert.Equal(t, 0, code)
+		var result checkResult
+		assert.Nil(t, json.Unmarshal([]byte(stdout), &result))
+		assert.Equal(t, "allowed", result.Decision)
+		assert.Equal(t, http.StatusOK, result.StatusCode)
+		assert.Equal(t, "none", result.RequestHeaders.Get("X-Datadome-Botname"))
+	})
+
+	t.Run("Skipped request", func(t *testing.T) {
+		code, stdout, _ := runCommand("check", "-key", "secret-key", "-endpoint", s.Endpoint(), "http://www.example.com/app.js")
+
+		assert.Equal(t, 0, code)
+		assert.Contains(t, stdout, "Decision: skipped\nSkip reason: UrlPatternExclusion\n")
+	})
+
+	t.Run("Without key", func(t *testing.T) {
+		t.Setenv("DATADOME_SERVER_SIDE_KEY", "")
+		code, _, stderr := runCommand("check", "-endpoint", s.Endpoint(), "http://www.example.com/")
+
+		assert.Equal(t, 1, code)
+		assert.Contains(t, stderr, "ServerSideKey must be defined")
+	})
+
+	t.Run("Protection API error", func(t *testing.T) {
+		code, _, stderr := runCommand("check", "-key", "secret-key", "-endpoint", "http://127.0.0.1:1/validate-request", "http://www.example.com/")
+
+		assert.Equal(t, 1, code)
+		assert.Contains(t, stderr, "error when performing DataDome request")
+	})
+}
+
+func TestConfigValidateCommand(t *testing.T) {
+	t.Run("Valid configuration", func(t *testing.T) {
+		code, stdout, _ := runCommand("config", "validate", "-key", "secret-key", "-endpoint", "api-eu.datadome.co")
+
+		assert.Equal(t, 0, code)
+		assert.Equal(t, "configuration is valid\n", stdout)
+	})
+
+	t.Run("Every invalid setting is listed", func(t *testing.T) {
+		t.Setenv("DATADOME_SERVER_SIDE_KEY", "")
+		code, stdout, stderr := runCommand("config", "validate",
+			"-timeout", "0", "-maximum-body-size", "-1", "-exclusion", "[", "-inclusion", "(", "-endpoint", "ftp://example.org")
+
+		assert.Equal(t, 1, code)
+		assert.Contains(t, stderr, "invalid configuration: 6 error(s)")
+		for _, msg := range []string{
+			"ServerSideKey must be defined",
+			"Timeout must be a positive integer",
+			"MaximumBodySize must be a positive integer",
+			"UrlPatternExclusion must be a valid RegExp",
+			"UrlPatternInclusion must be a valid RegExp",
+			"Endpoint must be a valid URL or host",
+		} {
+			assert.Contains(t, stdout, msg)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	modulego "github.com/andynuge/datadome-go"
+)
+
+const redactedKey = "REDACTED"
+
+// clientFlags holds the settings of the [modulego.Client] read from the command line.
+type clientFlags struct {
+	config modulego.Config
+}
+
+// register defines the flags on the flag set.
+func (f *clientFlags) register(fs *flag.FlagSet) {
+	f.config = *modulego.DefaultConfig()
+	fs.StringVar(&f.config.Endpoint, "endpoint", f.config.Endpoint, "Protection API endpoint")
+	fs.BoolVar(&f.config.EnableGraphQLSupport, "graphql", f.config.EnableGraphQLSupport, "enable the GraphQL support")
+	fs.StringVar(&f.config.GraphQLEndpointPattern, "graphql-endpoint", f.config.GraphQLEndpointPattern, "regular expression of the GraphQL endpoint paths")
+	fs.BoolVar(&f.config.EnableReferrerRestoration, "referrer-restoration", f.config.EnableReferrerRestoration, "enable the referrer restoration")
+	fs.IntVar(&f.config.MaximumBodySize, "maximum-body-size", f.config.MaximumBodySize, "maximum body size read for GraphQL requests")
+	fs.StringVar(&f.config.ServerSideKey, "key", os.Getenv("DATADOME_SERVER_SIDE_KEY"), "server-side key (default $DATADOME_SERVER_SIDE_KEY)")
+	fs.StringVar(&f.config.ServerSideKeyFile, "key-file", os.Getenv("DATADOME_SERVER_SIDE_KEY_FILE"), "file containing the server-side key (default $DATADOME_SERVER_SIDE_KEY_FILE)")
+	fs.IntVar(&f.config.Timeout, "timeout", f.config.Timeout, "Protection API timeout in milliseconds")
+	fs.StringVar(&f.config.UrlPatternExclusion, "exclusion", f.config.UrlPatternExclusion, "regular expression of the excluded URLs")
+	fs.StringVar(&f.config.UrlPatternInclusion, "inclusion", f.config.UrlPatternInclusion, "regular expression of the included URLs")
+	fs.BoolVar(&f.config.UseXForwardedHost, "x-forwarded-host", f.config.UseXForwardedHost, "use the X-Forwarded-Host header as host")
+}
+
+// newClient instantiates the [modulego.Client].
+// When the key is optional and not defined, a placeholder is used.
+func (f *clientFlags) newClient(keyRequired bool, options ...modulego.Option) (*modulego.Client, error) {
+	config := f.config
+	if !keyRequired && config.ServerSideKey == "" && config.ServerSideKeyFile == "" {
+		config.ServerSideKey = redactedKey
+	}
+	return modulego.NewClientFromConfig(&config, options...)
+}
+
+// stderrLogger is a [modulego.Logger] writing the warnings and errors to the standard error,
+// and the other levels when verbose.
+type stderrLogger struct {
+	w       io.Writer
+	verbose bool
+}
+
+func (l *stderrLogger) Debug(args ...interface{}) {
+	if l.verbose {
+		fmt.Fprintln(l.w, "DEBUG:", fmt.Sprint(args...))
+	}
+}
+
+func (l *stderrLogger) Info(args ...interface{}) {
+	if l.verbose {
+		fmt.Fprintln(l.w, "INFO:", fmt.Sprint(args...))
+	}
+}
+
+func (l *stderrLogger) Warn(args ...interface{}) {
+	fmt.Fprintln(l.w, "WARN:", fmt.Sprint(args...))
+}
+
+func (l *stderrLogger) Error(args ...interface{}) {
+	fmt.Fprintln(l.w, "ERROR:", fmt.Sprint(args...))
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	modulego "github.com/andynuge/datadome-go"
+)
+
+// errHelp is returned when the help of a command is requested.
+var errHelp = errors.New("help requested")
+
+// parseFlags parses the arguments and returns the optional URL argument.
+func parseFlags(fs *flag.FlagSet, args []string) (string, error) {
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return "", errHelp
+	}
+	if err != nil {
+		// the flag package already printed the error and the usage
+		return "", usageError("")
+	}
+	switch fs.NArg() {
+	case 0:
+		return "", nil
+	case 1:
+		return fs.Arg(0), nil
+	default:
+		return "", usageError(fmt.Sprintf("unexpected arguments %q, the flags must precede the URL", fs.Args()[1:]))
+	}
+}
+
+// runPayload prints the payload built for the request.
+func runPayload(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("payload", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var cf clientFlags
+	cf.register(fs)
+	var rf requestFlags
+	rf.register(fs)
+	format := fs.String("format", "json", "output format: json or form")
+	showKey := fs.Bool("show-key", false, "print the server-side key instead of redacting it")
+	verbose := fs.Bool("v", false, "print the debug logs of the module")
+
+	rawURL, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *format != "json" && *format != "form" {
+		return usageError(fmt.Sprintf("invalid format %q", *format))
+	}
+
+	client, err := cf.newClient(false, modulego.WithLogger(&stderrLogger{w: stderr, verbose: *verbose}))
+	if err != nil {
+		return err
+	}
+	r, err := rf.newRequest(rawURL, stdin)
+	if err != nil {
+		return err
+	}
+
+	payload, err := client.Payload(r)
+	if err != nil {
+		return err
+	}
+	if !*showKey {
+		payload.Set("Key", redactedKey)
+	}
+
+	if *format == "form" {
+		_, err = fmt.Fprintln(stdout, payload.Encode())
+		return err
+	}
+	fields := make(map[string]string, len(payload))
+	for name := range payload {
+		fields[name] = payload.Get(name)
+	}
+	out, err := json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, string(out))
+	return err
+}
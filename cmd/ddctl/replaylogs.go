@@ -0,0 +1,374 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	modulego "github.com/andynuge/datadome-go"
+)
+
+// outcomeCounts counts the outcomes of the replayed requests.
+type outcomeCounts struct {
+	Total   int `json:"total"`
+	Allowed int `json:"allowed"`
+	Blocked int `json:"blocked"`
+	Skipped int `json:"skipped"`
+	Errors  int `json:"errors"`
+}
+
+// add counts the outcome of a request.
+func (c *outcomeCounts) add(decision *modulego.Decision, err error) {
+	c.Total++
+	switch {
+	case err != nil:
+		c.Errors++
+	case decision.Blocked:
+		c.Blocked++
+	case decision.SkipReason != "":
+		c.Skipped++
+	default:
+		c.Allowed++
+	}
+}
+
+// groupCounts counts the outcomes of the requests sharing the same path or User-Agent.
+type groupCounts struct {
+	Key string `json:"key"`
+	outcomeCounts
+}
+
+// replayReport is the summary of a replay.
+type replayReport struct {
+	Lines             int            `json:"lines"`
+	ParseErrors       int            `json:"parseErrors"`
+	Requests          outcomeCounts  `json:"requests"`
+	SkipReasons       map[string]int `json:"skipReasons,omitempty"`
+	BlockRate         float64        `json:"blockRate"`
+	ExclusionCoverage float64        `json:"exclusionCoverage"`
+	Paths             []groupCounts  `json:"paths"`
+	UserAgents        []groupCounts  `json:"userAgents"`
+
+	paths      map[string]*outcomeCounts
+	userAgents map[string]*outcomeCounts
+}
+
+func newReplayReport() *replayReport {
+	return &replayReport{
+		SkipReasons: map[string]int{},
+		paths:       map[string]*outcomeCounts{},
+		userAgents:  map[string]*outcomeCounts{},
+	}
+}
+
+// add counts the outcome of a replayed request.
+func (rep *replayReport) add(r *http.Request, decision *modulego.Decision, err error) {
+	rep.Requests.add(decision, err)
+	if err == nil && decision.SkipReason != "" {
+		rep.SkipReasons[string(decision.SkipReason)]++
+	}
+
+	path := r.URL.Path
+	if rep.paths[path] == nil {
+		rep.paths[path] = &outcomeCounts{}
+	}
+	rep.paths[path].add(decision, err)
+
+	userAgent := r.Header.Get("User-Agent")
+	if rep.userAgents[userAgent] == nil {
+		rep.userAgents[userAgent] = &outcomeCounts{}
+	}
+	rep.userAgents[userAgent].add(decision, err)
+}
+
+// finish computes the rates and keeps the top groups.
+func (rep *replayReport) finish(top int) {
+	evaluated := rep.Requests.Allowed + rep.Requests.Blocked
+	if evaluated > 0 {
+		rep.BlockRate = float64(rep.Requests.Blocked) / float64(evaluated)
+	}
+	if rep.Requests.Total > 0 {
+		rep.ExclusionCoverage = float64(rep.SkipReasons[string(modulego.SkipReasonUrlPatternExclusion)]) / float64(rep.Requests.Total)
+	}
+	rep.Paths = topGroups(rep.paths, top)
+	rep.UserAgents = topGroups(rep.userAgents, top)
+}
+
+// topGroups returns the n groups with the most blocked requests, then the most requests.
+func topGroups(groups map[string]*outcomeCounts, n int) []groupCounts {
+	list := make([]groupCounts, 0, len(groups))
+	for key, counts := range groups {
+		list = append(list, groupCounts{Key: key, outcomeCounts: *counts})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Blocked != list[j].Blocked {
+			return list[i].Blocked > list[j].Blocked
+		}
+		if list[i].Total != list[j].Total {
+			return list[i].Total > list[j].Total
+		}
+		return list[i].Key < list[j].Key
+	})
+	if len(list) > n {
+		list = list[:n]
+	}
+	return list
+}
+
+// writeText prints the report in a human readable format.
+func (rep *replayReport) writeText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Lines:\t%d\n", rep.Lines)
+	fmt.Fprintf(tw, "Parse errors:\t%d\n", rep.ParseErrors)
+	fmt.Fprintf(tw, "Requests:\t%d\n", rep.Requests.Total)
+	fmt.Fprintf(tw, "  Allowed:\t%d\n", rep.Requests.Allowed)
+	fmt.Fprintf(tw, "  Blocked:\t%d\n", rep.Requests.Blocked)
+	fmt.Fprintf(tw, "  Skipped:\t%d\n", rep.Requests.Skipped)
+	reasons := make([]string, 0, len(rep.SkipReasons))
+	for reason := range rep.SkipReasons {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		fmt.Fprintf(tw, "    %s:\t%d\n", reason, rep.SkipReasons[reason])
+	}
+	fmt.Fprintf(tw, "  Errors:\t%d\n", rep.Requests.Errors)
+	fmt.Fprintf(tw, "Block rate:\t%.2f%% (blocked / evaluated)\n", 100*rep.BlockRate)
+	fmt.Fprintf(tw, "Exclusion coverage:\t%.2f%% (excluded / requests)\n", 100*rep.ExclusionCoverage)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, section := range []struct {
+		title  string
+		column string
+		groups []groupCounts
+	}{
+		{title: "Top paths", column: "PATH", groups: rep.Paths},
+		{title: "Top User-Agents", column: "USER-AGENT", groups: rep.UserAgents},
+	} {
+		if len(section.groups) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s:\n", section.title)
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintf(tw, "TOTAL\tBLOCKED\tSKIPPED\tERRORS\t\t%s\n", section.column)
+		for _, g := range section.groups {
+			key := g.Key
+			if key == "" {
+				key = "-"
+			}
+			fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t\t%s\n", g.Total, g.Blocked, g.Skipped, g.Errors, key)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// offlineTransport is a [http.RoundTripper] allowing every request without calling the Protection API.
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Body != nil {
+		_, _ = io.Copy(io.Discard, r.Body)
+		_ = r.Body.Close()
+	}
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{"X-Datadomeresponse": {"200"}},
+		Body:       http.NoBody,
+		Request:    r,
+	}, nil
+}
+
+// replayJob is a line of access log to replay.
+type replayJob struct {
+	line    int
+	request *http.Request
+}
+
+// replayResult is the outcome of a replayed line.
+type replayResult struct {
+	request  *http.Request
+	decision *modulego.Decision
+	err      error
+}
+
+// replayOptions holds the settings of the replay.
+type replayOptions struct {
+	concurrency int
+	host        string
+	logFormat   string
+	rate        float64
+	scheme      string
+}
+
+// runReplayLogs replays access logs through the module and prints a summary of the outcomes.
+func runReplayLogs(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("replay-logs", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var cf clientFlags
+	cf.register(fs)
+	var opts replayOptions
+	fs.IntVar(&opts.concurrency, "concurrency", 4, "number of concurrent requests")
+	fs.StringVar(&opts.host, "host", "localhost", "host of the requests when the logs do not contain it")
+	fs.StringVar(&opts.logFormat, "log-format", formatAuto, "access log format: auto, combined or json")
+	fs.Float64Var(&opts.rate, "rate", 0, "maximum number of requests per second (0 for unlimited)")
+	fs.StringVar(&opts.scheme, "scheme", "https", "scheme of the requests: http or https")
+	format := fs.String("format", "text", "output format: text or json")
+	offline := fs.Bool("offline", false, "allow every request without calling the Protection API, to measure the exclusion coverage")
+	top := fs.Int("top", 10, "number of paths and User-Agents listed in the report")
+	verbose := fs.Bool("v", false, "print the parse errors and the logs of the module")
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return errHelp
+		}
+		return usageError("")
+	}
+	switch {
+	case *format != "text" && *format != "json":
+		return usageError(fmt.Sprintf("invalid format %q", *format))
+	case opts.logFormat != formatAuto && opts.logFormat != formatCombined && opts.logFormat != formatJSON:
+		return usageError(fmt.Sprintf("invalid log format %q", opts.logFormat))
+	case opts.scheme != "http" && opts.scheme != "https":
+		return usageError(fmt.Sprintf("invalid scheme %q", opts.scheme))
+	case opts.concurrency <= 0:
+		return usageError("the concurrency must be a positive integer")
+	case opts.rate < 0:
+		return usageError("the rate must be positive")
+	}
+
+	logOutput := io.Discard
+	if *verbose {
+		logOutput = stderr
+	}
+	options := []modulego.Option{modulego.WithLogger(&stderrLogger{w: logOutput, verbose: true})}
+	if *offline {
+		options = append(options, modulego.WithTransport(offlineTransport{}))
+	}
+	client, err := cf.newClient(!*offline, options...)
+	if err != nil {
+		return err
+	}
+
+	inputs := []io.Reader{stdin}
+	if fs.NArg() > 0 {
+		inputs = inputs[:0]
+		for _, name := range fs.Args() {
+			f, err := os.Open(name)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			inputs = append(inputs, f)
+		}
+	}
+
+	rep, err := replayLogs(client, io.MultiReader(inputs...), opts, logOutput)
+	if err != nil {
+		return err
+	}
+	rep.finish(*top)
+
+	if *format == "json" {
+		out, err := json.MarshalIndent(rep, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(stdout, string(out))
+		return err
+	}
+	return rep.writeText(stdout)
+}
+
+// replayLogs evaluates each line of the access logs with the client and returns the report.
+// The parse errors are written to errOutput.
+func replayLogs(p modulego.Protector, in io.Reader, opts replayOptions, errOutput io.Writer) (*replayReport, error) {
+	rep := newReplayReport()
+	jobs := make(chan replayJob)
+	results := make(chan replayResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < opts.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				decision, err := p.Evaluate(job.request)
+				results <- replayResult{request: job.request, decision: decision, err: err}
+			}
+		}()
+	}
+
+	var scanErr error
+	go func() {
+		defer func() {
+			close(jobs)
+			wg.Wait()
+			close(results)
+		}()
+
+		var tick <-chan time.Time
+		if opts.rate > 0 {
+			ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.rate))
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		line := 0
+		for scanner.Scan() {
+			line++
+			text := scanner.Text()
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+			rep.Lines++
+			r, err := parseLogRequest(text, opts)
+			if err != nil {
+				rep.ParseErrors++
+				fmt.Fprintf(errOutput, "line %d: %v\n", line, err)
+				continue
+			}
+			if tick != nil {
+				<-tick
+			}
+			jobs <- replayJob{line: line, request: r}
+		}
+		scanErr = scanner.Err()
+	}()
+
+	for result := range results {
+		rep.add(result.request, result.decision, result.err)
+	}
+	if scanErr != nil {
+		return nil, fmt.Errorf("fail to read the access logs: %w", scanErr)
+	}
+	return rep, nil
+}
+
+// parseLogRequest parses a line of access log and builds the synthetic request.
+func parseLogRequest(line string, opts replayOptions) (*http.Request, error) {
+	e, err := parseLogLine(line, opts.logFormat)
+	if err != nil {
+		return nil, err
+	}
+	return e.newRequest(opts.scheme, opts.host)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andynuge/datadome-go/datadometest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplayLogsCommand(t *testing.T) {
+	s := datadometest.NewServer(
+		datadometest.WithRule(datadometest.MatchUserAgent("BadBot"), datadometest.Block(http.StatusForbidden)),
+	)
+	defer s.Close()
+
+	t.Run("Text report", func(t *testing.T) {
+		code, stdout, stderr := runCommand("replay-logs", "-key", "secret-key", "-endpoint", s.Endpoint(), "testdata/access.log")
+
+		assert.Equal(t, 0, code, stderr)
+		assert.Regexp(t, `(?m)^Lines:\s+7$`, stdout)
+		assert.Regexp(t, `(?m)^Parse errors:\s+1$`, stdout)
+		assert.Regexp(t, `(?m)^Requests:\s+6$`, stdout)
+		assert.Regexp(t, `(?m)^  Blocked:\s+2$`, stdout)
+		assert.Regexp(t, `(?m)^    UrlPatternExclusion:\s+2$`, stdout)
+		assert.Regexp(t, `(?m)^Block rate:\s+50\.00% \(blocked / evaluated\)$`, stdout)
+		assert.Regexp(t, `(?m)^Exclusion coverage:\s+33\.33% \(excluded / requests\)$`, stdout)
+		assert.Contains(t, stdout, "Top paths:\n")
+		assert.Contains(t, stdout, "Top User-Agents:\n")
+		assert.Len(t, s.Payloads(), 4)
+	})
+
+	t.Run("JSON report offline", func(t *testing.T) {
+		code, stdout, stderr := runCommand("replay-logs", "-offline", "-format", "json", "-top", "1", "-exclusion", `\.(js|png)$`, "testdata/access.log")
+
+		assert.Equal(t, 0, code, stderr)
+		var rep replayReport
+		assert.Nil(t, json.Unmarshal([]byte(stdout), &rep))
+		assert.Equal(t, 6, rep.Requests.Total)
+		assert.Equal(t, 4, rep.Requests.Allowed)
+		assert.Equal(t, 0, rep.Requests.Blocked)
+		assert.Equal(t, 2, rep.SkipReasons["UrlPatternExclusion"])
+		assert.Len(t, rep.Paths, 1)
+		assert.Equal(t, "/login", rep.Paths[0].Key)
+		assert.Equal(t, 2, rep.Paths[0].Total)
+	})
+
+	t.Run("Path anchored exclusion", func(t *testing.T) {
+		code, stdout, stderr := runCommand("replay-logs", "-offline", "-exclusion", `^/static/`, "testdata/access.log")
+
+		assert.Equal(t, 0, code, stderr)
+		assert.Regexp(t, `(?m)^    UrlPatternExclusion:\s+1$`, stdout)
+		assert.Regexp(t, `(?m)^Exclusion coverage:\s+16\.67% \(excluded / requests\)$`, stdout)
+	})
+
+	t.Run("Invalid settings", func(t *testing.T) {
+		code, _, stderr := runCommand("replay-logs", "-offline", "-log-format", "w3c")
+
+		assert.Equal(t, 2, code)
+		assert.Contains(t, stderr, `invalid log format "w3c"`)
+	})
+}
+
+func TestReplayLogs(t *testing.T) {
+	t.Run("Errors and rate limit", func(t *testing.T) {
+		lines := strings.Repeat(`192.0.2.1 - - [18/Oct/2026:10:00:00 +0000] "GET / HTTP/1.1" 200 512 "-" "Mozilla/5.0"`+"\n", 5)
+		p := datadometest.NewScript(datadometest.ErrorStep(io.ErrUnexpectedEOF))
+		opts := replayOptions{concurrency: 2, host: "localhost", logFormat: formatAuto, rate: 100, scheme: "http"}
+
+		start := time.Now()
+		rep, err := replayLogs(p, strings.NewReader(lines), opts, io.Discard)
+
+		assert.Nil(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+		assert.Equal(t, 5, rep.Requests.Total)
+		assert.Equal(t, 1, rep.Requests.Errors)
+		assert.Equal(t, 4, rep.Requests.Allowed)
+	})
+
+	t.Run("Ranking", func(t *testing.T) {
+		rep := newReplayReport()
+		rep.paths = map[string]*outcomeCounts{
+			"/a": {Total: 10, Allowed: 10},
+			"/b": {Total: 2, Blocked: 1, Allowed: 1},
+			"/c": {Total: 3, Allowed: 3},
+		}
+
+		rep.finish(2)
+
+		assert.Equal(t, []string{"/b", "/a"}, []string{rep.Paths[0].Key, rep.Paths[1].Key})
+	})
+}
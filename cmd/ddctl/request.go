@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// headerFlags is a repeatable flag of `Name: value` headers.
+type headerFlags []string
+
+func (h *headerFlags) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *headerFlags) Set(value string) error {
+	if !strings.Contains(value, ":") {
+		return fmt.Errorf("header %q must be of the form \"Name: value\"", value)
+	}
+	*h = append(*h, value)
+	return nil
+}
+
+// requestFlags holds the description of the request read from the command line.
+type requestFlags struct {
+	cookie    string
+	data      string
+	file      string
+	headers   headerFlags
+	ip        string
+	method    string
+	tls       bool
+	userAgent string
+}
+
+// register defines the flags on the flag set.
+func (f *requestFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.cookie, "b", "", "Cookie header of the request")
+	fs.StringVar(&f.data, "d", "", "body of the request")
+	fs.StringVar(&f.file, "request", "", `raw HTTP request file ("-" for the standard input)`)
+	fs.Var(&f.headers, "H", `header of the request "Name: value" (repeatable)`)
+	fs.StringVar(&f.ip, "ip", "127.0.0.1", "IP of the client")
+	fs.StringVar(&f.method, "X", "", "method of the request (default GET, or POST with -d)")
+	fs.BoolVar(&f.tls, "tls", false, "consider the raw HTTP request as received over TLS")
+	fs.StringVar(&f.userAgent, "A", "", "User-Agent header of the request")
+}
+
+// newRequest builds the request from the raw HTTP request file or from the URL and the flags.
+func (f *requestFlags) newRequest(rawURL string, stdin io.Reader) (*http.Request, error) {
+	var r *http.Request
+	var err error
+	if f.file != "" {
+		if rawURL != "" {
+			return nil, usageError("the URL and -request are mutually exclusive")
+		}
+		r, err = f.readRequest(stdin)
+	} else {
+		if rawURL == "" {
+			return nil, usageError("a URL or -request is required")
+		}
+		r, err = f.buildRequest(rawURL)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if net.ParseIP(f.ip) == nil {
+		return nil, usageError(fmt.Sprintf("invalid IP %q", f.ip))
+	}
+	r.RemoteAddr = net.JoinHostPort(f.ip, "0")
+	// the headers of the flags replace the existing ones
+	for _, h := range f.headers {
+		name, _, _ := strings.Cut(h, ":")
+		r.Header.Del(strings.TrimSpace(name))
+	}
+	for _, h := range f.headers {
+		name, value, _ := strings.Cut(h, ":")
+		r.Header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	if f.userAgent != "" {
+		r.Header.Set("User-Agent", f.userAgent)
+	}
+	if f.cookie != "" {
+		r.Header.Set("Cookie", f.cookie)
+	}
+	return r, nil
+}
+
+// readRequest parses the raw HTTP request file.
+func (f *requestFlags) readRequest(stdin io.Reader) (*http.Request, error) {
+	in := stdin
+	if f.file != "-" {
+		file, err := os.Open(f.file)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		in = file
+	}
+
+	r, err := http.ReadRequest(bufio.NewReader(in))
+	if err != nil {
+		return nil, fmt.Errorf("fail to parse the raw HTTP request: %w", err)
+	}
+	if f.tls {
+		r.TLS = &tls.ConnectionState{}
+	}
+	if f.method != "" {
+		r.Method = f.method
+	}
+	return r, nil
+}
+
+// buildRequest builds the request from the URL and the curl-like flags.
+func (f *requestFlags) buildRequest(rawURL string) (*http.Request, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil, usageError(fmt.Sprintf("invalid URL %q", rawURL))
+	}
+
+	method := f.method
+	if method == "" {
+		method = http.MethodGet
+		if f.data != "" {
+			method = http.MethodPost
+		}
+	}
+
+	var body io.Reader = http.NoBody
+	if f.data != "" {
+		body = strings.NewReader(f.data)
+	}
+	r, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, usageError(err.Error())
+	}
+	if f.data != "" {
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.Header.Set("Content-Length", fmt.Sprint(len(f.data)))
+	}
+	if u.Scheme == "https" {
+		r.TLS = &tls.ConnectionState{}
+	}
+	// the URL of the request holds the path and the query only, like the requests received by a server
+	r.URL = &url.URL{Path: u.Path, RawPath: u.RawPath, RawQuery: u.RawQuery}
+	r.RequestURI = r.URL.RequestURI()
+	return r, nil
+}
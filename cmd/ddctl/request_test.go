@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parseRequestFlags(t *testing.T, args ...string) (*requestFlags, string) {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	var rf requestFlags
+	rf.register(fs)
+	rawURL, err := parseFlags(fs, args)
+	assert.Nil(t, err)
+	return &rf, rawURL
+}
+
+func TestNewRequest(t *testing.T) {
+	t.Run("With curl-like flags", func(t *testing.T) {
+		rf, rawURL := parseRequestFlags(t,
+			"-H", "Accept: text/html", "-H", "X-Forwarded-Proto: http",
+			"-A", "Mozilla", "-b", "datadome=abc", "-d", "a=b", "-ip", "203.0.113.7",
+			"https://www.example.com/login?next=%2F",
+		)
+
+		r, err := rf.newRequest(rawURL, nil)
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "www.example.com", r.Host)
+		assert.Equal(t, "/login", r.URL.Path)
+		assert.Equal(t, "203.0.113.7:0", r.RemoteAddr)
+		assert.Equal(t, "text/html", r.Header.Get("Accept"))
+		assert.Equal(t, "Mozilla", r.Header.Get("User-Agent"))
+		assert.Equal(t, "datadome=abc", r.Header.Get("Cookie"))
+		assert.Equal(t, "3", r.Header.Get("Content-Length"))
+		assert.NotNil(t, r.TLS)
+	})
+
+	t.Run("With a header replacing a default one", func(t *testing.T) {
+		rf, rawURL := parseRequestFlags(t, "-X", "PUT", "-d", "{}", "-H", "Content-Type: application/json", "http://www.example.com/")
+
+		r, err := rf.newRequest(rawURL, nil)
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, []string{"application/json"}, r.Header.Values("Content-Type"))
+		assert.Nil(t, r.TLS)
+	})
+
+	t.Run("With a raw HTTP request file", func(t *testing.T) {
+		rf, rawURL := parseRequestFlags(t, "-request", "testdata/request.txt", "-tls")
+
+		r, err := rf.newRequest(rawURL, nil)
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "www.example.com", r.Host)
+		assert.Equal(t, "next=%2F", r.URL.RawQuery)
+		assert.Equal(t, "BadBot/1.0", r.Header.Get("User-Agent"))
+		assert.Equal(t, "127.0.0.1:0", r.RemoteAddr)
+		assert.NotNil(t, r.TLS)
+	})
+
+	t.Run("With the URL of a raw HTTP request", func(t *testing.T) {
+		rf, rawURL := parseRequestFlags(t, "https://www.example.com/login?next=%2F")
+		r, err := rf.newRequest(rawURL, nil)
+		assert.Nil(t, err)
+		rf, rawURL = parseRequestFlags(t, "-request", "testdata/request.txt")
+		raw, err := rf.newRequest(rawURL, nil)
+		assert.Nil(t, err)
+
+		assert.Equal(t, raw.URL, r.URL)
+		assert.Equal(t, raw.RequestURI, r.RequestURI)
+		assert.Equal(t, raw.Host, r.Host)
+	})
+
+	t.Run("With a raw HTTP request on the standard input", func(t *testing.T) {
+		rf, rawURL := parseRequestFlags(t, "-request", "-")
+
+		r, err := rf.newRequest(rawURL, strings.NewReader("GET / HTTP/1.1\r\nHost: www.example.com\r\n\r\n"))
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "www.example.com", r.Host)
+	})
+
+	tests := []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{name: "Without request", args: nil, err: "a URL or -request is required"},
+		{name: "With both URL and file", args: []string{"-request", "testdata/request.txt", "http://www.example.com/"}, err: "mutually exclusive"},
+		{name: "With a relative URL", args: []string{"/login"}, err: "invalid URL"},
+		{name: "With an invalid IP", args: []string{"-ip", "localhost", "http://www.example.com/"}, err: "invalid IP"},
+		{name: "With a missing file", args: []string{"-request", "testdata/missing.txt"}, err: "no such file"},
+		{name: "With an invalid file", args: []string{"-request", "-"}, err: "fail to parse the raw HTTP request"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rf, rawURL := parseRequestFlags(t, tc.args...)
+
+			r, err := rf.newRequest(rawURL, strings.NewReader("not http"))
+
+			assert.Nil(t, r)
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestHeaderFlags(t *testing.T) {
+	var h headerFlags
+
+	assert.Nil(t, h.Set("Accept: */*"))
+	assert.ErrorContains(t, h.Set("Accept"), "must be of the form")
+	assert.Equal(t, "Accept: */*", h.String())
+}
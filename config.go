@@ -1,5 +1,19 @@
 package modulego
 
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
 type Option func(*Client)
 
 // WithEndpoint is a functional option to set the endpoint of the Protection API.
@@ -38,6 +52,14 @@ func WithReferrerRestoration(enableReferrerRestoration bool) Option {
 	}
 }
 
+// WithServerSideKey is a functional option to set the server-side key.
+// It is mostly useful with [Client.Update], the key being given to [NewClient].
+func WithServerSideKey(serverSideKey string) Option {
+	return func(c *Client) {
+		c.ServerSideKey = serverSideKey
+	}
+}
+
 // WithTimeout is a functional option to set the HTTP Client timeout in milliseconds.
 func WithTimeout(timeout int) Option {
 	return func(c *Client) {
@@ -45,6 +67,14 @@ func WithTimeout(timeout int) Option {
 	}
 }
 
+// WithTransport is a functional option to set the [http.RoundTripper] used to perform the calls to the Protection API.
+// The [http.DefaultTransport] is used by default.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		c.Transport = transport
+	}
+}
+
 // WithUrlPatternExclusion is a functional option to define the regular expression to exclude the request from being processed with the Protection API.
 func WithUrlPatternExclusion(urlPatternExclusion string) Option {
 	return func(c *Client) {
@@ -52,6 +82,14 @@ func WithUrlPatternExclusion(urlPatternExclusion string) Option {
 	}
 }
 
+// WithGraphQLEndpointPattern is a functional option to define the regular expression matching the path of the GraphQL endpoints.
+// It defaults to [DefaultGraphQLEndpointPatternValue], the paths containing "graphql", when empty.
+func WithGraphQLEndpointPattern(graphQLEndpointPattern string) Option {
+	return func(c *Client) {
+		c.GraphQLEndpointPattern = graphQLEndpointPattern
+	}
+}
+
 // WithUrlPatternInclusion is a functional option to define the regular expression to match to process the request with the Protection API.
 func WithUrlPatternInclusion(urlPatternInclusion string) Option {
 	return func(c *Client) {
@@ -65,3 +103,386 @@ func WithXForwardedHost(useXForwardedHost bool) Option {
 		c.UseXForwardedHost = useXForwardedHost
 	}
 }
+
+// Config describes the settings of a [Client] that can be loaded from a JSON file and from the environment.
+// Each field matches the [Option] of the same name.
+//
+// The fields may be overridden by the following environment variables:
+//
+//	DATADOME_ALLOW_LIST_FILE                  file listing the allowed IPs and CIDRs
+//	DATADOME_BYPASS_CLIENT_CA_FILE            file containing the CAs of the bypass client certificates
+//	DATADOME_BYPASS_HEADER                    header containing the bypass signature
+//	DATADOME_BYPASS_SECRET                    secret of the bypass signatures
+//	DATADOME_BYPASS_WINDOW                    validity of the bypass signatures in seconds
+//	DATADOME_DENY_BODY                        body of the denied requests
+//	DATADOME_DENY_LIST_FILE                   file listing the denied IPs, CIDRs and client IDs
+//	DATADOME_DENY_STATUS_CODE                 status code of the denied requests
+//	DATADOME_ENABLE_GRAPHQL_SUPPORT           enable the GraphQL support
+//	DATADOME_ENABLE_REFERRER_RESTORATION      enable the referrer restoration
+//	DATADOME_ENABLE_SERVER_SIDE_KEY_FALLBACK  use the secondary key when the server-side key is rejected
+//	DATADOME_ENDPOINT                         Protection API endpoint
+//	DATADOME_GRAPHQL_ENDPOINT_PATTERN         regular expression of the GraphQL endpoint paths
+//	DATADOME_GRAPHQL_WEBSOCKET_MESSAGES       number of GraphQL WebSocket messages evaluated
+//	DATADOME_MAXIMUM_BODY_SIZE                maximum body size read for GraphQL requests
+//	DATADOME_SECONDARY_SERVER_SIDE_KEY        key used when the server-side key is rejected
+//	DATADOME_SERVER_SIDE_KEY                  server-side key
+//	DATADOME_SERVER_SIDE_KEY_FILE             file containing the server-side key
+//	DATADOME_TIMEOUT                          Protection API timeout in milliseconds
+//	DATADOME_URL_PATTERN_EXCLUSION            regular expression of the excluded URLs
+//	DATADOME_URL_PATTERN_INCLUSION            regular expression of the included URLs
+//	DATADOME_USE_X_FORWARDED_HOST             use the X-Forwarded-Host header as host
+//
+// The structured fields AllowList, DenyList, FallbackRateLimit, GraphQLPersistedQueries, Routes and SkipRules
+// have no environment variable: they are only loaded from the JSON file.
+type Config struct {
+	AllowList     []string `json:"allowList"`
+	AllowListFile string   `json:"allowListFile"`
+	// BypassClientCAFile is the path of a file containing the PEM certificates of the CAs issuing the client certificates
+	// of the internal requests.
+	BypassClientCAFile string   `json:"bypassClientCAFile"`
+	BypassHeader       string   `json:"bypassHeader"`
+	BypassSecret       string   `json:"bypassSecret"`
+	BypassWindow       int      `json:"bypassWindow"`
+	DenyBody           string   `json:"denyBody"`
+	DenyList           []string `json:"denyList"`
+	DenyListFile       string   `json:"denyListFile"`
+	// DenyStatusCode is the status code of the requests matching the DenyList, 403 when it is not defined.
+	DenyStatusCode              int                `json:"denyStatusCode"`
+	EnableGraphQLSupport        bool               `json:"enableGraphQLSupport"`
+	EnableReferrerRestoration   bool               `json:"enableReferrerRestoration"`
+	EnableServerSideKeyFallback bool               `json:"enableServerSideKeyFallback"`
+	Endpoint                    string             `json:"endpoint"`
+	FallbackRateLimit           *FallbackRateLimit `json:"fallbackRateLimit"`
+	GraphQLEndpointPattern      string             `json:"graphQLEndpointPattern"`
+	// GraphQLPersistedQueries maps the SHA-256 hashes of the persisted queries to their operation.
+	GraphQLPersistedQueries map[string]PersistedQuery `json:"graphQLPersistedQueries"`
+	// GraphQLWebSocketMessages is the number of the first subscribe or start messages of the GraphQL WebSocket connections
+	// evaluated with the Protection API.
+	GraphQLWebSocketMessages int     `json:"graphQLWebSocketMessages"`
+	MaximumBodySize          int     `json:"maximumBodySize"`
+	Routes                   []Route `json:"routes"`
+	// SecondaryServerSideKey is the key used when the server-side key is rejected and EnableServerSideKeyFallback is set.
+	SecondaryServerSideKey string     `json:"secondaryServerSideKey"`
+	SkipRules              []SkipRule `json:"skipRules"`
+	ServerSideKey          string     `json:"serverSideKey"`
+	// ServerSideKeyFile is the path of a file containing the server-side key, such as a mounted secret.
+	// When defined, it takes precedence over ServerSideKey.
+	ServerSideKeyFile   string `json:"serverSideKeyFile"`
+	Timeout             int    `json:"timeout"`
+	UrlPatternExclusion string `json:"urlPatternExclusion"`
+	UrlPatternInclusion string `json:"urlPatternInclusion"`
+	UseXForwardedHost   bool   `json:"useXForwardedHost"`
+}
+
+// ConfigError lists every invalid field of a [Config].
+type ConfigError struct {
+	Errors []error
+}
+
+func (e *ConfigError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return "invalid configuration: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the errors of the invalid fields.
+func (e *ConfigError) Unwrap() []error {
+	return e.Errors
+}
+
+// DefaultConfig returns a [Config] filled with the default values.
+func DefaultConfig() *Config {
+	return &Config{
+		BypassHeader:              DefaultBypassHeaderValue,
+		BypassWindow:              DefaultBypassWindowValue,
+		DenyStatusCode:            DefaultDenyStatusCodeValue,
+		EnableGraphQLSupport:      DefaultEnableGraphQLSupportValue,
+		EnableReferrerRestoration: DefaultEnableReferrerRestorationValue,
+		Endpoint:                  DefaultEndpointValue,
+		GraphQLEndpointPattern:    DefaultGraphQLEndpointPatternValue,
+		MaximumBodySize:           DefaultMaximumBodySizeValue,
+		Timeout:                   DefaultTimeoutValue,
+		UrlPatternExclusion:       DefaultUrlPatternExclusionValue,
+		UrlPatternInclusion:       DefaultUrlPatternInclusionValue,
+		UseXForwardedHost:         DefaultUseXForwardedHostValue,
+	}
+}
+
+// LoadConfig returns the default [Config] overridden by the JSON file (if path is not empty)
+// and then by the `DATADOME_*` environment variables.
+// The configuration is not validated: see [Config.Validate].
+func LoadConfig(path string) (*Config, error) {
+	c := DefaultConfig()
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("fail to open configuration file: %w", err)
+		}
+		defer f.Close()
+
+		if err := c.Decode(f); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.ApplyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Decode overrides the configuration with the JSON document read from r.
+// Unknown fields are rejected.
+func (c *Config) Decode(r io.Reader) error {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("fail to decode configuration: %w", err)
+	}
+	return nil
+}
+
+// ApplyEnv overrides the configuration with the `DATADOME_*` environment variables returned by lookupEnv.
+// A [*ConfigError] listing every invalid variable is returned.
+func (c *Config) ApplyEnv(lookupEnv func(string) (string, bool)) error {
+	var errs []error
+
+	stringFields := map[string]*string{
+		"DATADOME_ALLOW_LIST_FILE":           &c.AllowListFile,
+		"DATADOME_DENY_LIST_FILE":            &c.DenyListFile,
+		"DATADOME_DENY_BODY":                 &c.DenyBody,
+		"DATADOME_BYPASS_CLIENT_CA_FILE":     &c.BypassClientCAFile,
+		"DATADOME_BYPASS_HEADER":             &c.BypassHeader,
+		"DATADOME_BYPASS_SECRET":             &c.BypassSecret,
+		"DATADOME_ENDPOINT":                  &c.Endpoint,
+		"DATADOME_GRAPHQL_ENDPOINT_PATTERN":  &c.GraphQLEndpointPattern,
+		"DATADOME_SECONDARY_SERVER_SIDE_KEY": &c.SecondaryServerSideKey,
+		"DATADOME_SERVER_SIDE_KEY":           &c.ServerSideKey,
+		"DATADOME_SERVER_SIDE_KEY_FILE":      &c.ServerSideKeyFile,
+		"DATADOME_URL_PATTERN_EXCLUSION":     &c.UrlPatternExclusion,
+		"DATADOME_URL_PATTERN_INCLUSION":     &c.UrlPatternInclusion,
+	}
+	for name, field := range stringFields {
+		if value, ok := lookupEnv(name); ok {
+			*field = value
+		}
+	}
+
+	boolFields := map[string]*bool{
+		"DATADOME_ENABLE_GRAPHQL_SUPPORT":          &c.EnableGraphQLSupport,
+		"DATADOME_ENABLE_REFERRER_RESTORATION":     &c.EnableReferrerRestoration,
+		"DATADOME_ENABLE_SERVER_SIDE_KEY_FALLBACK": &c.EnableServerSideKeyFallback,
+		"DATADOME_USE_X_FORWARDED_HOST":            &c.UseXForwardedHost,
+	}
+	for name, field := range boolFields {
+		if value, ok := lookupEnv(name); ok {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s must be a boolean: %w", name, err))
+				continue
+			}
+			*field = b
+		}
+	}
+
+	intFields := map[string]*int{
+		"DATADOME_BYPASS_WINDOW":              &c.BypassWindow,
+		"DATADOME_DENY_STATUS_CODE":           &c.DenyStatusCode,
+		"DATADOME_GRAPHQL_WEBSOCKET_MESSAGES": &c.GraphQLWebSocketMessages,
+		"DATADOME_MAXIMUM_BODY_SIZE":          &c.MaximumBodySize,
+		"DATADOME_TIMEOUT":                    &c.Timeout,
+	}
+	for name, field := range intFields {
+		if value, ok := lookupEnv(name); ok {
+			i, err := strconv.Atoi(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s must be an integer: %w", name, err))
+				continue
+			}
+			*field = i
+		}
+	}
+
+	if len(errs) > 0 {
+		sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+		return &ConfigError{Errors: errs}
+	}
+	return nil
+}
+
+// Validate verifies every field of the configuration.
+// A [*ConfigError] listing every invalid field is returned.
+func (c *Config) Validate() error {
+	var errs []error
+	if _, err := c.serverSideKey(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("Timeout must be a positive integer"))
+	}
+	if c.MaximumBodySize <= 0 {
+		errs = append(errs, fmt.Errorf("MaximumBodySize must be a positive integer"))
+	}
+	if c.BypassSecret != "" && c.BypassHeader == "" {
+		errs = append(errs, fmt.Errorf("BypassHeader must be defined with BypassSecret"))
+	}
+	if c.BypassSecret != "" && c.BypassWindow <= 0 {
+		errs = append(errs, fmt.Errorf("BypassWindow must be a positive integer"))
+	}
+	if _, err := c.bypassClientCAs(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.DenyStatusCode != 0 && (c.DenyStatusCode < 100 || c.DenyStatusCode > 999) {
+		errs = append(errs, fmt.Errorf("DenyStatusCode must be a valid HTTP status code"))
+	}
+	if c.GraphQLWebSocketMessages < 0 {
+		errs = append(errs, fmt.Errorf("GraphQLWebSocketMessages must be a positive integer"))
+	}
+	if err := validateEndpoint(c.Endpoint); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := regexp.Compile(c.UrlPatternExclusion); err != nil {
+		errs = append(errs, fmt.Errorf("UrlPatternExclusion must be a valid RegExp: %w", err))
+	}
+	if _, err := regexp.Compile(c.UrlPatternInclusion); err != nil {
+		errs = append(errs, fmt.Errorf("UrlPatternInclusion must be a valid RegExp: %w", err))
+	}
+	if _, err := regexp.Compile(c.GraphQLEndpointPattern); err != nil {
+		errs = append(errs, fmt.Errorf("GraphQLEndpointPattern must be a valid RegExp: %w", err))
+	}
+	_, skipRuleErrs := compileSkipRules(c.SkipRules)
+	errs = append(errs, skipRuleErrs...)
+	_, routeErrs := compileRoutes(c.Routes, &settings{})
+	errs = append(errs, routeErrs...)
+	_, rateLimitErrs := newRateLimiter(c.FallbackRateLimit)
+	errs = append(errs, rateLimitErrs...)
+	_, allowListErrs := compileAccessList("AllowList", c.AllowList, c.AllowListFile, false)
+	errs = append(errs, allowListErrs...)
+	_, denyListErrs := compileAccessList("DenyList", c.DenyList, c.DenyListFile, true)
+	errs = append(errs, denyListErrs...)
+	_, persistedQueryErrs := compilePersistedQueries(c.GraphQLPersistedQueries)
+	errs = append(errs, persistedQueryErrs...)
+
+	if len(errs) > 0 {
+		return &ConfigError{Errors: errs}
+	}
+	return nil
+}
+
+// Options returns the [Option] list matching the configuration.
+// The server-side key and the bypass CAs are not part of the options.
+func (c *Config) Options() []Option {
+	return []Option{
+		WithAllowList(c.AllowList...),
+		WithAllowListFile(c.AllowListFile),
+		WithBypassHeader(c.BypassHeader),
+		WithBypassSecret(c.BypassSecret),
+		WithBypassWindow(c.BypassWindow),
+		WithDenyList(c.DenyList...),
+		WithDenyListFile(c.DenyListFile),
+		WithDenyResponse(c.denyStatusCode(), c.DenyBody),
+		WithEndpoint(c.Endpoint),
+		withFallbackRateLimit(c.FallbackRateLimit),
+		WithGraphQLEndpointPattern(c.GraphQLEndpointPattern),
+		WithGraphQLPersistedQueries(c.GraphQLPersistedQueries),
+		WithGraphQLSupport(c.EnableGraphQLSupport),
+		WithGraphQLWebSocketMessages(c.GraphQLWebSocketMessages),
+		WithMaximumBodySize(c.MaximumBodySize),
+		WithReferrerRestoration(c.EnableReferrerRestoration),
+		WithRoutes(c.Routes...),
+		withSecondaryServerSideKey(c.SecondaryServerSideKey),
+		WithServerSideKeyFallback(c.EnableServerSideKeyFallback),
+		WithSkipRules(c.SkipRules...),
+		WithTimeout(c.Timeout),
+		WithUrlPatternExclusion(c.UrlPatternExclusion),
+		WithUrlPatternInclusion(c.UrlPatternInclusion),
+		WithXForwardedHost(c.UseXForwardedHost),
+	}
+}
+
+// NewClientFromConfig validates the configuration and instantiates a new DataDome [Client].
+// The options are applied after the ones of the configuration, to set a custom [Logger] for instance.
+// It returns a [*ConfigError] listing every invalid field.
+func NewClientFromConfig(c *Config, options ...Option) (*Client, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	key, err := c.serverSideKey()
+	if err != nil {
+		return nil, err
+	}
+	pool, err := c.bypassClientCAs()
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(key, append(append(c.Options(), WithBypassClientCAs(pool)), options...)...)
+}
+
+// UpdateConfig validates the configuration and switches the client to it with [Client.Update].
+// When the server-side key changes, the previous one becomes the secondary key as with [Client.RotateServerSideKey],
+// unless the configuration defines the SecondaryServerSideKey.
+// The options are applied after the ones of the configuration.
+// It returns a [*ConfigError] listing every invalid field, in which case the current settings are kept.
+func (c *Client) UpdateConfig(cfg *Config, options ...Option) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	key, err := cfg.serverSideKey()
+	if err != nil {
+		return err
+	}
+	pool, err := cfg.bypassClientCAs()
+	if err != nil {
+		return err
+	}
+	// the secondary key of the configuration, if any, replaces the previous key
+	options = append(append(append([]Option{rotateServerSideKey(key)}, cfg.Options()...), WithBypassClientCAs(pool)), options...)
+	return c.Update(options...)
+}
+
+// serverSideKey returns the server-side key, read from ServerSideKeyFile when defined.
+func (c *Config) serverSideKey() (string, error) {
+	if c.ServerSideKeyFile == "" {
+		if c.ServerSideKey == "" {
+			return "", fmt.Errorf("ServerSideKey must be defined")
+		}
+		return c.ServerSideKey, nil
+	}
+	return readServerSideKeyFile(c.ServerSideKeyFile)
+}
+
+// denyStatusCode returns the DenyStatusCode, or the default one when it is not defined.
+func (c *Config) denyStatusCode() int {
+	if c.DenyStatusCode == 0 {
+		return DefaultDenyStatusCodeValue
+	}
+	return c.DenyStatusCode
+}
+
+// bypassClientCAs returns the pool of the CAs read from BypassClientCAFile, or nil when it is not defined.
+func (c *Config) bypassClientCAs() (*x509.CertPool, error) {
+	if c.BypassClientCAFile == "" {
+		return nil, nil
+	}
+	return loadCertPool(c.BypassClientCAFile)
+}
+
+// validateEndpoint verifies the endpoint is either a host, an absolute HTTP(S) URL or a path.
+func validateEndpoint(endpoint string) error {
+	if strings.HasPrefix(endpoint, "/") {
+		return nil
+	}
+	if strings.HasPrefix(endpoint, "http") {
+		u, err := url.Parse(endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("Endpoint must be a valid URL or host: %q", endpoint)
+		}
+		return nil
+	}
+	u, err := url.Parse("https://" + endpoint)
+	if err != nil || u.Host != endpoint || u.Hostname() == "" {
+		return fmt.Errorf("Endpoint must be a valid URL or host: %q", endpoint)
+	}
+	return nil
+}
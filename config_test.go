@@ -1,7 +1,11 @@
 package modulego
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -94,6 +98,22 @@ func TestWithReferrerRestoration(t *testing.T) {
 	assert.Equal(t, enableReferrerRestoration, client.EnableReferrerRestoration)
 }
 
+func TestWithServerSideKey(t *testing.T) {
+	t.Run("With a key", func(t *testing.T) {
+		client, err := NewClient("your-api-key", WithServerSideKey("another-api-key"))
+
+		assert.Nil(t, err)
+		assert.Equal(t, "another-api-key", client.ServerSideKey)
+	})
+
+	t.Run("With an empty key", func(t *testing.T) {
+		client, err := NewClient("your-api-key", WithServerSideKey(""))
+
+		assert.Nil(t, client)
+		assert.Equal(t, "ServerSideKey must be defined", err.Error())
+	})
+}
+
 func TestWithTimeout(t *testing.T) {
 	t.Run("With a positive integer", func(t *testing.T) {
 		timeout := 1500
@@ -120,6 +140,19 @@ func TestWithTimeout(t *testing.T) {
 	})
 }
 
+func TestWithTransport(t *testing.T) {
+	transport := &http.Transport{}
+	client, err := NewClient(
+		"your-api-key",
+		WithTransport(transport),
+	)
+
+	assert.NotNil(t, client)
+	assert.Nil(t, err)
+	assert.Equal(t, transport, client.Transport)
+	assert.Equal(t, transport, client.current().httpClient.Transport)
+}
+
 func TestWithUrlPatternExclusion(t *testing.T) {
 	t.Run("With a valid RegExp", func(t *testing.T) {
 		urlPatternExclusion := `(?i)\/excluded-path\/.*`
@@ -131,7 +164,7 @@ func TestWithUrlPatternExclusion(t *testing.T) {
 		assert.NotNil(t, client)
 		assert.Nil(t, err)
 		assert.Equal(t, urlPatternExclusion, client.UrlPatternExclusion)
-		assert.NotNil(t, client.urlPatternExclusion)
+		assert.NotNil(t, client.current().urlPatternExclusion)
 	})
 
 	t.Run("With an invalid RegExp", func(t *testing.T) {
@@ -158,7 +191,7 @@ func TestWithUrlPatternInclusion(t *testing.T) {
 		assert.NotNil(t, client)
 		assert.Nil(t, err)
 		assert.Equal(t, urlPatternInclusion, client.UrlPatternInclusion)
-		assert.NotNil(t, client.urlPatternInclusion)
+		assert.NotNil(t, client.current().urlPatternInclusion)
 	})
 
 	t.Run("With an invalid RegExp", func(t *testing.T) {
@@ -185,6 +218,306 @@ func TestWithXForwardedHost(t *testing.T) {
 	assert.True(t, client.UseXForwardedHost)
 }
 
+func TestLoadConfig(t *testing.T) {
+	t.Run("Without file nor environment variables", func(t *testing.T) {
+		c, err := LoadConfig("")
+
+		assert.Nil(t, err)
+		assert.Equal(t, DefaultConfig(), c)
+	})
+
+	t.Run("Environment variables override the file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "datadome.json")
+		err := os.WriteFile(path, []byte(`{"serverSideKey": "file-key", "timeout": 300, "urlPatternExclusion": "", "enableGraphQLSupport": true}`), 0o600)
+		assert.Nil(t, err)
+		t.Setenv("DATADOME_TIMEOUT", "500")
+		t.Setenv("DATADOME_USE_X_FORWARDED_HOST", "true")
+
+		c, err := LoadConfig(path)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "file-key", c.ServerSideKey)
+		assert.Equal(t, 500, c.Timeout)
+		assert.Equal(t, "", c.UrlPatternExclusion)
+		assert.True(t, c.EnableGraphQLSupport)
+		assert.True(t, c.UseXForwardedHost)
+		assert.Equal(t, DefaultEndpointValue, c.Endpoint)
+	})
+
+	t.Run("Unknown fields are rejected", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "datadome.json")
+		err := os.WriteFile(path, []byte(`{"timeoutMs": 300}`), 0o600)
+		assert.Nil(t, err)
+
+		c, err := LoadConfig(path)
+
+		assert.Nil(t, c)
+		assert.ErrorContains(t, err, "unknown field")
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		c, err := LoadConfig(filepath.Join(t.TempDir(), "missing.json"))
+
+		assert.Nil(t, c)
+		assert.ErrorContains(t, err, "fail to open configuration file")
+	})
+}
+
+func TestConfigApplyEnv(t *testing.T) {
+	t.Run("Every variable", func(t *testing.T) {
+		env := map[string]string{
+			"DATADOME_ALLOW_LIST_FILE":                 "/etc/datadome/allow.txt",
+			"DATADOME_BYPASS_CLIENT_CA_FILE":           "/run/secrets/ca.pem",
+			"DATADOME_BYPASS_HEADER":                   "X-Internal-Signature",
+			"DATADOME_BYPASS_SECRET":                   "bypass-secret",
+			"DATADOME_BYPASS_WINDOW":                   "60",
+			"DATADOME_DENY_BODY":                       "Access denied",
+			"DATADOME_DENY_LIST_FILE":                  "/etc/datadome/deny.txt",
+			"DATADOME_DENY_STATUS_CODE":                "429",
+			"DATADOME_ENABLE_GRAPHQL_SUPPORT":          "true",
+			"DATADOME_ENABLE_REFERRER_RESTORATION":     "1",
+			"DATADOME_ENABLE_SERVER_SIDE_KEY_FALLBACK": "true",
+			"DATADOME_ENDPOINT":                        "api-eu.datadome.co",
+			"DATADOME_GRAPHQL_ENDPOINT_PATTERN":        `^/api/graphql$`,
+			"DATADOME_GRAPHQL_WEBSOCKET_MESSAGES":      "3",
+			"DATADOME_MAXIMUM_BODY_SIZE":               "1024",
+			"DATADOME_SECONDARY_SERVER_SIDE_KEY":       "env-secondary-key",
+			"DATADOME_SERVER_SIDE_KEY":                 "env-key",
+			"DATADOME_SERVER_SIDE_KEY_FILE":            "/run/secrets/datadome",
+			"DATADOME_TIMEOUT":                         "300",
+			"DATADOME_URL_PATTERN_EXCLUSION":           `\.css$`,
+			"DATADOME_URL_PATTERN_INCLUSION":           `^/app`,
+			"DATADOME_USE_X_FORWARDED_HOST":            "true",
+		}
+		c := DefaultConfig()
+
+		err := c.ApplyEnv(func(name string) (string, bool) {
+			v, ok := env[name]
+			return v, ok
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, &Config{
+			AllowListFile:               "/etc/datadome/allow.txt",
+			BypassClientCAFile:          "/run/secrets/ca.pem",
+			BypassHeader:                "X-Internal-Signature",
+			BypassSecret:                "bypass-secret",
+			BypassWindow:                60,
+			DenyBody:                    "Access denied",
+			DenyListFile:                "/etc/datadome/deny.txt",
+			DenyStatusCode:              429,
+			EnableGraphQLSupport:        true,
+			EnableReferrerRestoration:   true,
+			EnableServerSideKeyFallback: true,
+			Endpoint:                    "api-eu.datadome.co",
+			GraphQLEndpointPattern:      `^/api/graphql$`,
+			GraphQLWebSocketMessages:    3,
+			MaximumBodySize:             1024,
+			SecondaryServerSideKey:      "env-secondary-key",
+			ServerSideKey:               "env-key",
+			ServerSideKeyFile:           "/run/secrets/datadome",
+			Timeout:                     300,
+			UrlPatternExclusion:         `\.css$`,
+			UrlPatternInclusion:         `^/app`,
+			UseXForwardedHost:           true,
+		}, c)
+	})
+
+	t.Run("Every invalid variable is listed", func(t *testing.T) {
+		env := map[string]string{
+			"DATADOME_ENABLE_GRAPHQL_SUPPORT": "maybe",
+			"DATADOME_TIMEOUT":                "fast",
+			"DATADOME_MAXIMUM_BODY_SIZE":      "1kb",
+		}
+		c := DefaultConfig()
+
+		err := c.ApplyEnv(func(name string) (string, bool) {
+			v, ok := env[name]
+			return v, ok
+		})
+
+		var configErr *ConfigError
+		assert.True(t, errors.As(err, &configErr))
+		assert.Len(t, configErr.Errors, 3)
+		assert.ErrorContains(t, err, "DATADOME_ENABLE_GRAPHQL_SUPPORT must be a boolean")
+		assert.ErrorContains(t, err, "DATADOME_MAXIMUM_BODY_SIZE must be an integer")
+		assert.ErrorContains(t, err, "DATADOME_TIMEOUT must be an integer")
+		assert.Equal(t, DefaultTimeoutValue, c.Timeout)
+	})
+}
+
+func TestConfigValidate(t *testing.T) {
+	t.Run("Valid configuration", func(t *testing.T) {
+		c := DefaultConfig()
+		c.ServerSideKey = "your-api-key"
+
+		assert.Nil(t, c.Validate())
+	})
+
+	t.Run("Every invalid field is listed", func(t *testing.T) {
+		c := &Config{
+			Endpoint:            "ftp://api.datadome.co",
+			UrlPatternExclusion: `(?i)\/excluded-path\/with-[error.*`,
+			UrlPatternInclusion: `(`,
+		}
+
+		err := c.Validate()
+
+		var configErr *ConfigError
+		assert.True(t, errors.As(err, &configErr))
+		assert.Equal(t, []string{
+			"ServerSideKey must be defined",
+			"Timeout must be a positive integer",
+			"MaximumBodySize must be a positive integer",
+			`Endpoint must be a valid URL or host: "ftp://api.datadome.co"`,
+			"UrlPatternExclusion must be a valid RegExp: error parsing regexp: missing closing ]: `[error.*`",
+			"UrlPatternInclusion must be a valid RegExp: error parsing regexp: missing closing ): `(`",
+		}, errorMessages(configErr.Errors))
+		assert.True(t, strings.HasPrefix(err.Error(), "invalid configuration: ServerSideKey must be defined; Timeout"))
+	})
+
+	t.Run("Unreadable key file", func(t *testing.T) {
+		c := DefaultConfig()
+		c.ServerSideKeyFile = filepath.Join(t.TempDir(), "missing")
+
+		assert.ErrorContains(t, c.Validate(), "ServerSideKeyFile must be readable")
+	})
+
+	t.Run("Empty key file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "key")
+		assert.Nil(t, os.WriteFile(path, []byte("\n"), 0o600))
+		c := DefaultConfig()
+		c.ServerSideKeyFile = path
+
+		assert.ErrorContains(t, c.Validate(), "ServerSideKeyFile must not be empty")
+	})
+}
+
+func TestValidateEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		valid    bool
+	}{
+		{endpoint: "api.datadome.co", valid: true},
+		{endpoint: "api.datadome.co:8443", valid: true},
+		{endpoint: "https://api.datadome.co/validate-request", valid: true},
+		{endpoint: "http://127.0.0.1:8080/validate-request", valid: true},
+		{endpoint: "/validate-request", valid: true},
+		{endpoint: "", valid: false},
+		{endpoint: "ftp://api.datadome.co", valid: false},
+		{endpoint: "api.datadome.co/validate-request", valid: false},
+		{endpoint: "https:///validate-request", valid: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.endpoint, func(t *testing.T) {
+			err := validateEndpoint(tc.endpoint)
+
+			assert.Equal(t, tc.valid, err == nil, err)
+		})
+	}
+}
+
+func TestNewClientFromConfig(t *testing.T) {
+	t.Run("With a key file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "key")
+		assert.Nil(t, os.WriteFile(path, []byte("file-key\n"), 0o600))
+		mockLogger := &MockLogger{}
+		c := DefaultConfig()
+		c.ServerSideKey = "ignored-key"
+		c.ServerSideKeyFile = path
+		c.Timeout = 300
+
+		client, err := NewClientFromConfig(c, WithLogger(mockLogger), WithTimeout(500))
+
+		assert.Nil(t, err)
+		assert.Equal(t, "file-key", client.ServerSideKey)
+		assert.Equal(t, mockLogger, client.Logger)
+		assert.Equal(t, 500, client.Timeout)
+		assert.Equal(t, DefaultUrlPatternExclusionValue, client.UrlPatternExclusion)
+	})
+
+	t.Run("With a secondary key", func(t *testing.T) {
+		c := DefaultConfig()
+		c.ServerSideKey = "new-key"
+		c.SecondaryServerSideKey = "old-key"
+		c.EnableServerSideKeyFallback = true
+
+		client, err := NewClientFromConfig(c)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "old-key", client.SecondaryServerSideKey)
+		assert.True(t, client.EnableServerSideKeyFallback)
+	})
+
+	t.Run("With an invalid configuration", func(t *testing.T) {
+		client, err := NewClientFromConfig(&Config{})
+
+		assert.Nil(t, client)
+		var configErr *ConfigError
+		assert.True(t, errors.As(err, &configErr))
+		assert.Len(t, configErr.Errors, 4)
+	})
+}
+
+func TestClientUpdateConfig(t *testing.T) {
+	t.Run("With a valid configuration", func(t *testing.T) {
+		transport := &http.Transport{}
+		client, err := NewClient("your-api-key", WithTransport(transport))
+		assert.Nil(t, err)
+		c := DefaultConfig()
+		c.ServerSideKey = "another-api-key"
+		c.Timeout = 300
+		c.UrlPatternInclusion = `^/api/`
+
+		err = client.UpdateConfig(c, WithTimeout(500))
+
+		assert.Nil(t, err)
+		s := client.current()
+		assert.Equal(t, "another-api-key", s.serverSideKey)
+		assert.Equal(t, "your-api-key", s.secondaryServerSideKey)
+		assert.Equal(t, 500, s.timeout)
+		assert.Equal(t, `^/api/`, s.urlPatternInclusion.String())
+		assert.Equal(t, transport, s.httpClient.Transport)
+	})
+
+	t.Run("With a secondary key", func(t *testing.T) {
+		client, err := NewClient("your-api-key")
+		assert.Nil(t, err)
+		c := DefaultConfig()
+		c.ServerSideKey = "another-api-key"
+		c.SecondaryServerSideKey = "secondary-api-key"
+
+		assert.Nil(t, client.UpdateConfig(c))
+		assert.Equal(t, "secondary-api-key", client.current().secondaryServerSideKey)
+
+		// the secondary key is kept when the configuration does not define it
+		c.SecondaryServerSideKey = ""
+		c.Timeout = 300
+		assert.Nil(t, client.UpdateConfig(c))
+		assert.Equal(t, "secondary-api-key", client.current().secondaryServerSideKey)
+	})
+
+	t.Run("With an invalid configuration", func(t *testing.T) {
+		client, err := NewClient("your-api-key")
+		assert.Nil(t, err)
+		s := client.current()
+
+		err = client.UpdateConfig(&Config{ServerSideKey: "another-api-key"})
+
+		var configErr *ConfigError
+		assert.True(t, errors.As(err, &configErr))
+		assert.Same(t, s, client.current())
+	})
+}
+
+func errorMessages(errs []error) []string {
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return msgs
+}
+
 // Testable examples
 
 func ExampleWithEndpoint() {
@@ -230,6 +563,17 @@ func ExampleWithTimeout() {
 	// Output: 300
 }
 
+func ExampleNewClientFromConfig() {
+	c := DefaultConfig()
+	c.ServerSideKey = "your-api-key"
+	c.Timeout = 0
+	c.UrlPatternInclusion = "(invalid"
+
+	_, err := NewClientFromConfig(c)
+	fmt.Println(err)
+	// Output: invalid configuration: Timeout must be a positive integer; UrlPatternInclusion must be a valid RegExp: error parsing regexp: missing closing ): `(invalid`
+}
+
 func ExampleWithUrlPatternExclusion() {
 	c, _ := NewClient("your-api-key", WithUrlPatternExclusion(`(?i)\/not-this-path\/.*`))
 
@@ -240,7 +584,7 @@ func ExampleWithUrlPatternExclusion() {
 func ExampleWithUrlPatternInclusion() {
 	c, _ := NewClient("your-api-key", WithUrlPatternInclusion(`(?i)\/this-path\/.*`))
 
-	fmt.Println(c.urlPatternInclusion)
+	fmt.Println(c.current().urlPatternInclusion)
 	// Output: (?i)\/this-path\/.*
 }
 
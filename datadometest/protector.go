@@ -0,0 +1,172 @@
+package datadometest
+
+import (
+	"net/http"
+	"sync"
+
+	modulego "github.com/andynuge/datadome-go"
+)
+
+// AlwaysAllow returns a [modulego.Protector] allowing every request without calling the Protection API.
+func AlwaysAllow() modulego.Protector {
+	return modulego.EvaluatorFunc(func(r *http.Request) (*modulego.Decision, error) {
+		return &modulego.Decision{StatusCode: http.StatusOK}, nil
+	})
+}
+
+// AlwaysBlock returns a [modulego.Protector] blocking every request with the given status code.
+func AlwaysBlock(statusCode int) modulego.Protector {
+	return modulego.EvaluatorFunc(func(r *http.Request) (*modulego.Decision, error) {
+		return &modulego.Decision{
+			Blocked:    true,
+			StatusCode: statusCode,
+			Body:       []byte(http.StatusText(statusCode)),
+		}, nil
+	})
+}
+
+// Step is the result returned by a [Script] for one request.
+// A zero Step allows the request, like [AllowStep].
+type Step struct {
+	Decision *modulego.Decision
+	Err      error
+}
+
+// AllowStep returns a [Step] allowing the request.
+func AllowStep() Step {
+	return Step{Decision: &modulego.Decision{StatusCode: http.StatusOK}}
+}
+
+// BlockStep returns a [Step] blocking the request with the given status code.
+func BlockStep(statusCode int) Step {
+	return Step{Decision: &modulego.Decision{
+		Blocked:    true,
+		StatusCode: statusCode,
+		Body:       []byte(http.StatusText(statusCode)),
+	}}
+}
+
+// ErrorStep returns a [Step] failing with err, the request is allowed (fail open).
+func ErrorStep(err error) Step {
+	return Step{Err: err}
+}
+
+// Script is a [modulego.Protector] returning a predefined sequence of results, one per evaluated request.
+// Once the sequence is exhausted, the requests are allowed.
+// It is safe for concurrent use.
+type Script struct {
+	mu    sync.Mutex
+	steps []Step
+}
+
+// NewScript returns a [Script] returning the given steps in order.
+func NewScript(steps ...Step) *Script {
+	return &Script{steps: steps}
+}
+
+// Remaining returns the number of steps not returned yet.
+func (s *Script) Remaining() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.steps)
+}
+
+// Evaluate returns the result of the next step.
+func (s *Script) Evaluate(r *http.Request) (*modulego.Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.steps) == 0 {
+		return AllowStep().Decision, nil
+	}
+	step := s.steps[0]
+	s.steps = s.steps[1:]
+	if step.Decision == nil && step.Err == nil {
+		return AllowStep().Decision, nil
+	}
+	return step.Decision, step.Err
+}
+
+// DatadomeHandler implements the [http.Handler] interface
+func (s *Script) DatadomeHandler(next http.Handler) http.Handler {
+	return modulego.EvaluatorFunc(s.Evaluate).DatadomeHandler(next)
+}
+
+// DatadomeProtect validates the incoming request
+func (s *Script) DatadomeProtect(rw http.ResponseWriter, r *http.Request) (isBlocked bool, err error) {
+	return modulego.EvaluatorFunc(s.Evaluate).DatadomeProtect(rw, r)
+}
+
+var (
+	_ modulego.Protector = (*Script)(nil)
+	_ modulego.Protector = (*Recorder)(nil)
+)
+
+// Evaluation is a request evaluated through a [Recorder].
+type Evaluation struct {
+	Request  *http.Request
+	Decision *modulego.Decision
+	Err      error
+}
+
+// Recorder is a [modulego.Protector] decorator capturing every evaluated request.
+// It is safe for concurrent use.
+type Recorder struct {
+	mu          sync.Mutex
+	protector   modulego.Protector
+	evaluations []Evaluation
+}
+
+// NewRecorder returns a [Recorder] evaluating the requests with p.
+func NewRecorder(p modulego.Protector) *Recorder {
+	return &Recorder{protector: p}
+}
+
+// Evaluations returns a copy of the evaluations, in call order.
+func (rec *Recorder) Evaluations() []Evaluation {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	evaluations := make([]Evaluation, len(rec.evaluations))
+	copy(evaluations, rec.evaluations)
+	return evaluations
+}
+
+// Reset removes the recorded evaluations.
+func (rec *Recorder) Reset() {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.evaluations = nil
+}
+
+// Evaluate evaluates the request with the decorated protector and records the result.
+func (rec *Recorder) Evaluate(r *http.Request) (*modulego.Decision, error) {
+	decision, err := rec.protector.Evaluate(r)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.evaluations = append(rec.evaluations, Evaluation{Request: r, Decision: decision, Err: err})
+	return decision, err
+}
+
+// DatadomeHandler implements the [http.Handler] interface.
+// The requests are handled by the decorated protector, keeping its logging and its handling of the GraphQL WebSocket connections.
+func (rec *Recorder) DatadomeHandler(next http.Handler) http.Handler {
+	handler := rec.protector.DatadomeHandler(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, rec.observed(r))
+	})
+}
+
+// DatadomeProtect validates the incoming request with the decorated protector and records the result.
+func (rec *Recorder) DatadomeProtect(rw http.ResponseWriter, r *http.Request) (isBlocked bool, err error) {
+	return rec.protector.DatadomeProtect(rw, rec.observed(r))
+}
+
+// observed returns a copy of r whose evaluation by the decorated protector is recorded.
+func (rec *Recorder) observed(r *http.Request) *http.Request {
+	ctx := modulego.ContextWithDecisionObserver(r.Context(), func(r *http.Request, decision *modulego.Decision, err error) {
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		rec.evaluations = append(rec.evaluations, Evaluation{Request: r, Decision: decision, Err: err})
+	})
+	return r.WithContext(ctx)
+}
@@ -0,0 +1,164 @@
+package datadometest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	modulego "github.com/andynuge/datadome-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlwaysAllow(t *testing.T) {
+	p := AlwaysAllow()
+
+	decision, err := p.Evaluate(httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Nil(t, err)
+	assert.False(t, decision.Blocked)
+
+	isBlocked, err := p.DatadomeProtect(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Nil(t, err)
+	assert.False(t, isBlocked)
+}
+
+func TestAlwaysBlock(t *testing.T) {
+	p := AlwaysBlock(http.StatusForbidden)
+	nextCalled := false
+	handler := p.DatadomeHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nextCalled = true
+	}))
+
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.False(t, nextCalled)
+	assert.Equal(t, http.StatusForbidden, rw.Code)
+	assert.Equal(t, "Forbidden", rw.Body.String())
+}
+
+func TestScript(t *testing.T) {
+	errUnavailable := errors.New("unavailable")
+	s := NewScript(BlockStep(http.StatusUnauthorized), ErrorStep(errUnavailable), AllowStep())
+	assert.Equal(t, 3, s.Remaining())
+
+	isBlocked, err := s.DatadomeProtect(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Nil(t, err)
+	assert.True(t, isBlocked)
+
+	isBlocked, err = s.DatadomeProtect(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, errUnavailable, err)
+	assert.False(t, isBlocked)
+
+	decision, err := s.Evaluate(httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Nil(t, err)
+	assert.False(t, decision.Blocked)
+	assert.Equal(t, 0, s.Remaining())
+
+	// the requests are allowed once the script is exhausted
+	decision, err = s.Evaluate(httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Nil(t, err)
+	assert.False(t, decision.Blocked)
+}
+
+func TestScript_ZeroStep(t *testing.T) {
+	s := NewScript(Step{})
+
+	isBlocked, err := s.DatadomeProtect(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Nil(t, err)
+	assert.False(t, isBlocked)
+	assert.Equal(t, 0, s.Remaining())
+}
+
+func TestRecorder(t *testing.T) {
+	rec := NewRecorder(NewScript(AllowStep(), BlockStep(http.StatusForbidden)))
+	handler := rec.DatadomeHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/first", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/second", nil))
+
+	evaluations := rec.Evaluations()
+	assert.Len(t, evaluations, 2)
+	assert.Equal(t, "/first", evaluations[0].Request.URL.Path)
+	assert.False(t, evaluations[0].Decision.Blocked)
+	assert.Equal(t, http.MethodPost, evaluations[1].Request.Method)
+	assert.True(t, evaluations[1].Decision.Blocked)
+
+	rec.Reset()
+	assert.Empty(t, rec.Evaluations())
+}
+
+func TestRecorderWithClient(t *testing.T) {
+	s := NewServer(WithRule(MatchPath(`^/admin`), Block(http.StatusForbidden)))
+	defer s.Close()
+	client := newClient(t, s)
+
+	var p modulego.Protector = NewRecorder(client)
+	rec := p.(*Recorder)
+
+	var wg sync.WaitGroup
+	for _, path := range []string{"/", "/admin", "/about"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = p.DatadomeProtect(httptest.NewRecorder(), newRequest(path, "Mozilla"))
+		}()
+	}
+	wg.Wait()
+
+	evaluations := rec.Evaluations()
+	assert.Len(t, evaluations, 3)
+	blocked := 0
+	for _, e := range evaluations {
+		assert.Nil(t, e.Err)
+		if e.Decision.Blocked {
+			blocked++
+			assert.Equal(t, "/admin", e.Request.URL.Path)
+		}
+	}
+	assert.Equal(t, 1, blocked)
+	assert.Len(t, s.Payloads(), 3)
+}
+
+// failingWriter is a response writer failing to write the body.
+type failingWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (w failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+// errorLogger records the errors logged.
+type errorLogger struct {
+	nopLogger
+	mu     sync.Mutex
+	errors []string
+}
+
+func (l *errorLogger) Error(args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errors = append(l.errors, fmt.Sprint(args...))
+}
+
+func TestRecorderDelegatesToClient(t *testing.T) {
+	s := NewServer(WithRule(MatchPath(`^/admin`), Block(http.StatusForbidden)))
+	defer s.Close()
+	logger := &errorLogger{}
+	client, err := modulego.NewClient("test-key", modulego.WithEndpoint(s.Endpoint()), modulego.WithLogger(logger))
+	assert.Nil(t, err)
+	rec := NewRecorder(client)
+
+	isBlocked, err := rec.DatadomeProtect(failingWriter{httptest.NewRecorder()}, newRequest("/admin", "Mozilla"))
+
+	assert.True(t, isBlocked)
+	assert.EqualError(t, err, "connection reset")
+	// the error is logged by the client and the evaluation is recorded once
+	assert.Len(t, logger.errors, 1)
+	assert.Len(t, rec.Evaluations(), 1)
+	assert.True(t, rec.Evaluations()[0].Decision.Blocked)
+}
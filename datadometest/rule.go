@@ -0,0 +1,173 @@
+package datadometest
+
+import (
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Rule associates a [Matcher] with the [Response] to send.
+type Rule struct {
+	Match    Matcher
+	Response Response
+}
+
+// Matcher reports whether a [Rule] applies to the received [Payload].
+type Matcher func(p Payload) bool
+
+// MatchAll matches every payload.
+func MatchAll() Matcher {
+	return func(Payload) bool { return true }
+}
+
+// MatchIP matches the payloads whose `IP` field is one of the given IPs or belongs to one of the given CIDRs.
+// It panics if a value is neither an IP nor a CIDR.
+func MatchIP(ips ...string) Matcher {
+	var nets []*net.IPNet
+	for _, v := range ips {
+		if !strings.Contains(v, "/") {
+			ip := net.ParseIP(v)
+			if ip == nil {
+				panic("datadometest: invalid IP " + v)
+			}
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(v)
+		if err != nil {
+			panic("datadometest: invalid CIDR " + v)
+		}
+		nets = append(nets, n)
+	}
+	return func(p Payload) bool {
+		ip := net.ParseIP(p.Form.Get("IP"))
+		if ip == nil {
+			return false
+		}
+		for _, n := range nets {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MatchUserAgent matches the payloads whose `UserAgent` field contains substr.
+func MatchUserAgent(substr string) Matcher {
+	return func(p Payload) bool {
+		return strings.Contains(p.Form.Get("UserAgent"), substr)
+	}
+}
+
+// MatchPath matches the payloads whose `Request` field (path and query) matches the regular expression.
+// It panics if the expression cannot be parsed.
+func MatchPath(pattern string) Matcher {
+	re := regexp.MustCompile(pattern)
+	return func(p Payload) bool {
+		return re.MatchString(p.Form.Get("Request"))
+	}
+}
+
+// MatchField matches the payloads whose field has the given value.
+func MatchField(name, value string) Matcher {
+	return func(p Payload) bool {
+		return p.Form.Get(name) == value
+	}
+}
+
+// Response describes the answer of the [Server].
+type Response struct {
+	// StatusCode of the response, 200 when zero.
+	StatusCode int
+	// DataDomeResponse overrides the `X-DataDomeResponse` header, which matches StatusCode by default.
+	DataDomeResponse string
+	// Headers are added to the response and listed in the `X-DataDome-Headers` header.
+	Headers http.Header
+	// RequestHeaders are added to the response and listed in the `X-DataDome-Request-Headers` header.
+	RequestHeaders http.Header
+	// Body of the response.
+	Body string
+	// Latency is waited before answering.
+	Latency time.Duration
+	// ResetConnection closes the connection without answering.
+	ResetConnection bool
+}
+
+// Allow returns a 200 [Response] allowing the request.
+func Allow() Response {
+	return Response{StatusCode: http.StatusOK}
+}
+
+// Block returns a [Response] blocking the request with the given status code (401 or 403)
+// and a `Set-Cookie` header.
+func Block(statusCode int) Response {
+	return Response{
+		StatusCode: statusCode,
+		Headers: http.Header{
+			"Set-Cookie": {"datadome=blocked; Max-Age=31536000; Path=/; Secure; SameSite=Lax"},
+		},
+		Body: `{"url":"https://geo.captcha-delivery.com/captcha/"}`,
+	}
+}
+
+// Redirect returns a [Response] redirecting the request to location with the given status code (301 or 302).
+func Redirect(statusCode int, location string) Response {
+	return Response{
+		StatusCode: statusCode,
+		Headers:    http.Header{"Location": {location}},
+	}
+}
+
+// BadRequest returns a 400 [Response], the request is allowed by the module.
+func BadRequest() Response {
+	return Response{StatusCode: http.StatusBadRequest, Body: "Invalid request"}
+}
+
+// InternalError returns a 500 [Response], the module fails open.
+func InternalError() Response {
+	return Response{StatusCode: http.StatusInternalServerError, Body: "Internal error"}
+}
+
+// ConnectionReset returns a [Response] closing the connection without answering.
+func ConnectionReset() Response {
+	return Response{ResetConnection: true}
+}
+
+// WithHeaders returns a copy of the response with headers added to the `X-DataDome-Headers` list.
+func (resp Response) WithHeaders(headers http.Header) Response {
+	resp.Headers = mergeHeaders(resp.Headers, headers)
+	return resp
+}
+
+// WithRequestHeaders returns a copy of the response with headers added to the `X-DataDome-Request-Headers` list.
+func (resp Response) WithRequestHeaders(headers http.Header) Response {
+	resp.RequestHeaders = mergeHeaders(resp.RequestHeaders, headers)
+	return resp
+}
+
+// WithLatency returns a copy of the response answered after d.
+func (resp Response) WithLatency(d time.Duration) Response {
+	resp.Latency = d
+	return resp
+}
+
+// mergeHeaders returns a new [http.Header] containing the values of a and b.
+func mergeHeaders(a, b http.Header) http.Header {
+	merged := a.Clone()
+	if merged == nil {
+		merged = http.Header{}
+	}
+	for name, values := range b {
+		for _, value := range values {
+			merged.Add(name, value)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,66 @@
+package datadometest
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newPayload(fields ...string) Payload {
+	form := url.Values{}
+	for i := 0; i+1 < len(fields); i += 2 {
+		form.Set(fields[i], fields[i+1])
+	}
+	return Payload{Form: form}
+}
+
+func TestMatchers(t *testing.T) {
+	tests := []struct {
+		name     string
+		matcher  Matcher
+		payload  Payload
+		expected bool
+	}{
+		{name: "MatchAll", matcher: MatchAll(), payload: newPayload(), expected: true},
+		{name: "MatchIP with an IPv4", matcher: MatchIP("192.0.2.1"), payload: newPayload("IP", "192.0.2.1"), expected: true},
+		{name: "MatchIP with another IPv4", matcher: MatchIP("192.0.2.1"), payload: newPayload("IP", "192.0.2.2"), expected: false},
+		{name: "MatchIP with a CIDR", matcher: MatchIP("10.0.0.1", "192.0.2.0/24"), payload: newPayload("IP", "192.0.2.2"), expected: true},
+		{name: "MatchIP with an IPv6", matcher: MatchIP("2001:db8::1"), payload: newPayload("IP", "2001:db8::1"), expected: true},
+		{name: "MatchIP without IP", matcher: MatchIP("192.0.2.1"), payload: newPayload(), expected: false},
+		{name: "MatchUserAgent", matcher: MatchUserAgent("Bot"), payload: newPayload("UserAgent", "BadBot/1.0"), expected: true},
+		{name: "MatchUserAgent not matching", matcher: MatchUserAgent("Bot"), payload: newPayload("UserAgent", "Mozilla"), expected: false},
+		{name: "MatchPath", matcher: MatchPath(`^/login`), payload: newPayload("Request", "/login?next=/"), expected: true},
+		{name: "MatchPath not matching", matcher: MatchPath(`^/login`), payload: newPayload("Request", "/"), expected: false},
+		{name: "MatchField", matcher: MatchField("Method", "POST"), payload: newPayload("Method", "POST"), expected: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.matcher(tc.payload))
+		})
+	}
+}
+
+func TestMatchIPPanics(t *testing.T) {
+	assert.Panics(t, func() { MatchIP("not-an-ip") })
+	assert.Panics(t, func() { MatchIP("192.0.2.0/99") })
+}
+
+func TestResponseBuilders(t *testing.T) {
+	base := Allow()
+	resp := base.
+		WithHeaders(http.Header{"X-A": {"1"}}).
+		WithRequestHeaders(http.Header{"X-B": {"2"}}).
+		WithLatency(time.Millisecond)
+
+	assert.Nil(t, base.Headers)
+	assert.Equal(t, "1", resp.Headers.Get("X-A"))
+	assert.Equal(t, "2", resp.RequestHeaders.Get("X-B"))
+	assert.Equal(t, time.Millisecond, resp.Latency)
+
+	blocked := Block(http.StatusForbidden).WithHeaders(http.Header{"Set-Cookie": {"other=1"}})
+	assert.Len(t, blocked.Headers.Values("Set-Cookie"), 2)
+	assert.Len(t, Block(http.StatusForbidden).Headers.Values("Set-Cookie"), 1)
+}
@@ -0,0 +1,232 @@
+// Package datadometest provides an in-process fake of the DataDome Protection API for integration tests.
+//
+// The [Server] answers the payloads sent by a [modulego.Client] with the [Response] of the first
+// matching [Rule], or with the default response (allow) when no rule matches.
+// Every payload received is recorded and can be inspected with [Server.Payloads].
+//
+//	srv := datadometest.NewServer(
+//		datadometest.WithRule(datadometest.MatchUserAgent("BadBot"), datadometest.Block(http.StatusForbidden)),
+//	)
+//	defer srv.Close()
+//
+//	client, err := modulego.NewClient("test-key", modulego.WithEndpoint(srv.Endpoint()))
+//
+// The package also provides [modulego.Protector] implementations replacing the [modulego.Client] in unit tests:
+// [AlwaysAllow], [AlwaysBlock], [Script] and the [Recorder] decorator.
+package datadometest
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is a fake Protection API backed by an [httptest.Server].
+type Server struct {
+	// URL is the base URL of the server, of the form http://ipaddr:port with no trailing slash.
+	URL string
+
+	server          *httptest.Server
+	mu              sync.Mutex
+	rules           []Rule
+	defaultResponse Response
+	payloads        []Payload
+}
+
+// Option is a function used to customize the [Server].
+type Option func(*Server)
+
+// WithRule appends a [Rule] answering the payloads matched by m with resp.
+func WithRule(m Matcher, resp Response) Option {
+	return func(s *Server) {
+		s.rules = append(s.rules, Rule{Match: m, Response: resp})
+	}
+}
+
+// WithDefaultResponse sets the [Response] used when no rule matches.
+// The default response allows the request.
+func WithDefaultResponse(resp Response) Option {
+	return func(s *Server) {
+		s.defaultResponse = resp
+	}
+}
+
+// Payload is a request received by the [Server].
+type Payload struct {
+	// Form contains the fields of the payload (`IP`, `UserAgent`, `Request`, ...).
+	Form url.Values
+	// Header contains the headers sent with the payload.
+	Header http.Header
+	// Time is the reception time of the payload.
+	Time time.Time
+}
+
+// NewServer starts and returns a new [Server].
+// The caller should call [Server.Close] when finished, to shut it down.
+func NewServer(options ...Option) *Server {
+	s := &Server{
+		defaultResponse: Allow(),
+	}
+	for _, opt := range options {
+		opt(s)
+	}
+
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.server.URL
+	return s
+}
+
+// Endpoint returns the endpoint to use with [modulego.WithEndpoint].
+func (s *Server) Endpoint() string {
+	return s.URL + "/validate-request"
+}
+
+// Close shuts down the server and blocks until all outstanding requests have completed.
+func (s *Server) Close() {
+	s.server.CloseClientConnections()
+	s.server.Close()
+}
+
+// AddRule appends a [Rule] answering the payloads matched by m with resp.
+// Rules are evaluated in insertion order and the first match wins.
+func (s *Server) AddRule(m Matcher, resp Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = append(s.rules, Rule{Match: m, Response: resp})
+}
+
+// Payloads returns a copy of the payloads received by the server, in reception order.
+func (s *Server) Payloads() []Payload {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	payloads := make([]Payload, len(s.payloads))
+	copy(payloads, s.payloads)
+	return payloads
+}
+
+// LastPayload returns the last payload received by the server.
+// The boolean is false when no payload was received.
+func (s *Server) LastPayload() (Payload, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.payloads) == 0 {
+		return Payload{}, false
+	}
+	return s.payloads[len(s.payloads)-1], true
+}
+
+// Reset removes the rules and the recorded payloads.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = nil
+	s.payloads = nil
+}
+
+// serveHTTP records the payload and writes the response of the first matching rule.
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p := Payload{
+		Form:   r.PostForm,
+		Header: r.Header.Clone(),
+		Time:   time.Now(),
+	}
+
+	s.mu.Lock()
+	s.payloads = append(s.payloads, p)
+	resp := s.defaultResponse
+	for _, rule := range s.rules {
+		if rule.Match(p) {
+			resp = rule.Response
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	if !sleep(r.Context(), resp.Latency) {
+		return
+	}
+	if resp.ResetConnection {
+		resetConnection(w)
+		return
+	}
+	resp.write(w)
+}
+
+// write writes the response with the `x-datadome*` headers expected by the module.
+func (resp *Response) write(w http.ResponseWriter) {
+	statusCode := resp.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+
+	h := w.Header()
+	setListedHeaders(h, "X-DataDome-Headers", resp.Headers)
+	setListedHeaders(h, "X-DataDome-Request-Headers", resp.RequestHeaders)
+	if resp.DataDomeResponse != "" {
+		h.Set("X-DataDomeResponse", resp.DataDomeResponse)
+	} else {
+		h.Set("X-DataDomeResponse", strconv.Itoa(statusCode))
+	}
+	h.Set("Content-Length", strconv.Itoa(len(resp.Body)))
+	w.WriteHeader(statusCode)
+	_, _ = io.WriteString(w, resp.Body)
+}
+
+// setListedHeaders sets the headers and lists their names in the listHeaderName header.
+func setListedHeaders(h http.Header, listHeaderName string, headers http.Header) {
+	if len(headers) == 0 {
+		return
+	}
+	names := make([]string, 0, len(headers))
+	for name, values := range headers {
+		for _, value := range values {
+			h.Add(name, value)
+		}
+		names = append(names, http.CanonicalHeaderKey(name))
+	}
+	h.Set(listHeaderName, strings.Join(names, " "))
+}
+
+// sleep waits for the given duration.
+// It returns false when the context is done before.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// resetConnection closes the underlying connection abruptly so that the client receives a TCP reset.
+func resetConnection(w http.ResponseWriter) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		panic(http.ErrAbortHandler)
+	}
+	conn, _, err := hj.Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		_ = tcpConn.SetLinger(0)
+	}
+	_ = conn.Close()
+}
@@ -0,0 +1,186 @@
+package datadometest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	modulego "github.com/andynuge/datadome-go"
+	"github.com/stretchr/testify/assert"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(args ...interface{}) {}
+func (nopLogger) Info(args ...interface{})  {}
+func (nopLogger) Warn(args ...interface{})  {}
+func (nopLogger) Error(args ...interface{}) {}
+
+func newClient(t *testing.T, s *Server) *modulego.Client {
+	t.Helper()
+	client, err := modulego.NewClient(
+		"test-key",
+		modulego.WithEndpoint(s.Endpoint()),
+		modulego.WithLogger(nopLogger{}),
+	)
+	assert.Nil(t, err)
+	return client
+}
+
+func newRequest(path, userAgent string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "http://example.org"+path, nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	r.Header.Set("User-Agent", userAgent)
+	return r
+}
+
+func TestServer(t *testing.T) {
+	s := NewServer(
+		WithRule(MatchUserAgent("BadBot"), Block(http.StatusForbidden)),
+		WithRule(MatchPath(`^/old`), Redirect(http.StatusMovedPermanently, "/new")),
+		WithRule(MatchPath(`^/invalid`), BadRequest()),
+		WithRule(MatchPath(`^/down`), InternalError()),
+		WithRule(MatchPath(`^/reset`), ConnectionReset()),
+		WithRule(MatchPath(`^/slow`), Allow().WithLatency(time.Second)),
+		WithRule(MatchPath(`^/headers`), Allow().
+			WithHeaders(http.Header{"Set-Cookie": {"datadome=abc"}}).
+			WithRequestHeaders(http.Header{"X-Datadome-Botname": {"none"}})),
+	)
+	defer s.Close()
+	client := newClient(t, s)
+
+	t.Run("Allow by default", func(t *testing.T) {
+		decision, err := client.Evaluate(newRequest("/", "Mozilla"))
+
+		assert.Nil(t, err)
+		assert.False(t, decision.Blocked)
+		assert.Equal(t, http.StatusOK, decision.StatusCode)
+	})
+
+	t.Run("Block", func(t *testing.T) {
+		decision, err := client.Evaluate(newRequest("/", "BadBot/1.0"))
+
+		assert.Nil(t, err)
+		assert.True(t, decision.Blocked)
+		assert.Equal(t, http.StatusForbidden, decision.StatusCode)
+		assert.Contains(t, string(decision.Body), "captcha")
+		assert.Contains(t, decision.ResponseHeaders.Get("Set-Cookie"), "datadome=blocked")
+	})
+
+	t.Run("Redirect", func(t *testing.T) {
+		decision, err := client.Evaluate(newRequest("/old", "Mozilla"))
+
+		assert.Nil(t, err)
+		assert.True(t, decision.Blocked)
+		assert.Equal(t, http.StatusMovedPermanently, decision.StatusCode)
+		assert.Equal(t, "/new", decision.ResponseHeaders.Get("Location"))
+	})
+
+	t.Run("Bad request", func(t *testing.T) {
+		decision, err := client.Evaluate(newRequest("/invalid", "Mozilla"))
+
+		assert.Nil(t, err)
+		assert.False(t, decision.Blocked)
+		assert.Equal(t, http.StatusBadRequest, decision.StatusCode)
+	})
+
+	t.Run("Internal error", func(t *testing.T) {
+		decision, err := client.Evaluate(newRequest("/down", "Mozilla"))
+
+		assert.Nil(t, decision)
+		assert.ErrorContains(t, err, "500 response from Protection API")
+	})
+
+	t.Run("Connection reset", func(t *testing.T) {
+		decision, err := client.Evaluate(newRequest("/reset", "Mozilla"))
+
+		assert.Nil(t, decision)
+		assert.ErrorContains(t, err, "error when performing DataDome request")
+	})
+
+	t.Run("Latency above the timeout", func(t *testing.T) {
+		start := time.Now()
+		decision, err := client.Evaluate(newRequest("/slow", "Mozilla"))
+
+		assert.Nil(t, decision)
+		assert.ErrorContains(t, err, "Client.Timeout exceeded")
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("Headers", func(t *testing.T) {
+		decision, err := client.Evaluate(newRequest("/headers", "Mozilla"))
+
+		assert.Nil(t, err)
+		assert.Equal(t, "datadome=abc", decision.ResponseHeaders.Get("Set-Cookie"))
+		assert.Equal(t, "none", decision.RequestHeaders.Get("X-Datadome-Botname"))
+	})
+}
+
+func TestServerPayloads(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	client := newClient(t, s)
+
+	_, ok := s.LastPayload()
+	assert.False(t, ok)
+
+	_, err := client.Evaluate(newRequest("/first", "Mozilla"))
+	assert.Nil(t, err)
+	_, err = client.Evaluate(newRequest("/second?q=1", "curl/8.0"))
+	assert.Nil(t, err)
+
+	payloads := s.Payloads()
+	assert.Len(t, payloads, 2)
+	assert.Equal(t, "/first", payloads[0].Form.Get("Request"))
+	assert.Equal(t, "test-key", payloads[0].Form.Get("Key"))
+	assert.Equal(t, "192.0.2.1", payloads[0].Form.Get("IP"))
+	assert.Equal(t, "DataDome", payloads[0].Header.Get("User-Agent"))
+
+	last, ok := s.LastPayload()
+	assert.True(t, ok)
+	assert.Equal(t, "/second?q=1", last.Form.Get("Request"))
+	assert.Equal(t, "curl/8.0", last.Form.Get("UserAgent"))
+
+	s.Reset()
+	assert.Empty(t, s.Payloads())
+}
+
+func TestServerAddRule(t *testing.T) {
+	s := NewServer(WithDefaultResponse(Block(http.StatusForbidden)))
+	defer s.Close()
+	client := newClient(t, s)
+
+	decision, err := client.Evaluate(newRequest("/", "Mozilla"))
+	assert.Nil(t, err)
+	assert.True(t, decision.Blocked)
+
+	s.AddRule(MatchIP("192.0.2.0/24"), Allow())
+	decision, err = client.Evaluate(newRequest("/", "Mozilla"))
+	assert.Nil(t, err)
+	assert.False(t, decision.Blocked)
+}
+
+// Testable examples
+
+func ExampleNewServer() {
+	s := NewServer(
+		WithRule(MatchUserAgent("BadBot"), Block(http.StatusForbidden)),
+	)
+	defer s.Close()
+
+	client, _ := modulego.NewClient("test-key", modulego.WithEndpoint(s.Endpoint()))
+	handler := client.DatadomeHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("User-Agent", "BadBot/1.0")
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, r)
+
+	last, _ := s.LastPayload()
+	fmt.Println(rw.Code, last.Form.Get("UserAgent"))
+	// Output: 403 BadBot/1.0
+}
@@ -5,6 +5,7 @@ go 1.24.1
 require (
 	github.com/jarcoal/httpmock v1.3.1
 	github.com/stretchr/testify v1.8.4
+	github.com/traefik/yaegi v0.16.1
 )
 
 require (
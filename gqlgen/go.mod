@@ -0,0 +1,26 @@
+module github.com/andynuge/datadome-go/gqlgen
+
+go 1.24.1
+
+require (
+	github.com/99designs/gqlgen v0.17.81
+	github.com/andynuge/datadome-go v1.4.0
+	github.com/stretchr/testify v1.11.1
+	github.com/vektah/gqlparser/v2 v2.5.30
+)
+
+require (
+	github.com/agnivade/levenshtein v1.2.1 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/go-viper/mapstructure/v2 v2.4.0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/gorilla/websocket v1.5.0 // indirect
+	github.com/hashicorp/golang-lru/v2 v2.0.7 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/sosodev/duration v1.3.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// The local builds use the parent directory: the builds depending on this module ignore the replace directive and resolve the required release tag of the root module.
+// Tag the root module first, then tag this module with the gqlgen/ prefix (e.g. gqlgen/v1.4.0) on the same commit.
+replace github.com/andynuge/datadome-go => ../
@@ -0,0 +1,219 @@
+// Package gqlgen provides a gqlgen extension protecting the GraphQL operations with DataDome
+// once they are parsed and validated by the server, instead of reading them from the request body.
+//
+// Usage:
+//
+//	srv := handler.New(generated.NewExecutableSchema(cfg))
+//	srv.Use(&gqlgen.Extension{Protector: client})
+//	http.Handle("/graphql", gqlgen.Middleware(srv))
+//
+// The sensitive fields are annotated in the schema with the directive:
+//
+//	directive @sensitive on FIELD_DEFINITION
+//
+//	type Mutation {
+//	    login(email: String!, password: String!): Session! @sensitive
+//	}
+package gqlgen
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	modulego "github.com/andynuge/datadome-go"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// DefaultSensitiveDirective is the default name of the directive annotating the sensitive fields of the schema.
+const DefaultSensitiveDirective = "sensitive"
+
+// Error codes of the operations rejected by the [Extension].
+const (
+	// ErrBlocked is the code of the operations blocked by the Protection API.
+	ErrBlocked = "DATADOME_BLOCKED"
+	// ErrUnavailable is the code of the operations selecting a sensitive field when the Protection API call fails.
+	ErrUnavailable = "DATADOME_UNAVAILABLE"
+)
+
+const extensionName = "DataDome"
+
+// Extension is a gqlgen extension evaluating the request of each operation with the Protection API,
+// reporting the type and the name of the operation and the number of operations of the document.
+// The blocked operations are rejected with a GraphQL error of code [ErrBlocked].
+//
+// The operations selecting a sensitive field fail closed with a GraphQL error of code [ErrUnavailable]
+// when the Protection API call fails; the other ones fail open.
+//
+// The handler of the server must be wrapped with [Middleware].
+type Extension struct {
+	// Protector evaluates the requests, usually a [*modulego.Client].
+	Protector modulego.Protector
+	// SensitiveFields lists the coordinates (Type.field) of the sensitive fields,
+	// in addition to the fields annotated with the SensitiveDirective in the schema.
+	SensitiveFields []string
+	// SensitiveDirective is the name of the directive annotating the sensitive fields, [DefaultSensitiveDirective] when empty.
+	SensitiveDirective string
+	// SensitiveOnly restricts the evaluation to the operations selecting a sensitive field.
+	SensitiveOnly bool
+
+	sensitive map[string]struct{}
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = &Extension{}
+
+// Stats describes the evaluation of an operation, available with [graphql.Stats.GetExtension] under "DataDome".
+type Stats struct {
+	// SensitiveFields lists the coordinates of the sensitive fields selected by the operation.
+	SensitiveFields []string
+	// Decision is the decision of the Protection API, nil when the operation is not evaluated or when the call fails.
+	Decision *modulego.Decision
+}
+
+// ExtensionName returns the name of the extension.
+func (e *Extension) ExtensionName() string {
+	return extensionName
+}
+
+// Validate verifies the extension is configured and that the sensitive fields belong to the schema.
+func (e *Extension) Validate(schema graphql.ExecutableSchema) error {
+	if e.Protector == nil {
+		return fmt.Errorf("Protector must be defined")
+	}
+	directive := e.SensitiveDirective
+	if directive == "" {
+		directive = DefaultSensitiveDirective
+	}
+
+	e.sensitive = map[string]struct{}{}
+	for _, coordinate := range e.SensitiveFields {
+		typeName, fieldName, _ := strings.Cut(coordinate, ".")
+		definition := schema.Schema().Types[typeName]
+		if definition == nil || definition.Fields.ForName(fieldName) == nil {
+			return fmt.Errorf("SensitiveFields must contain fields of the schema: %q", coordinate)
+		}
+		e.sensitive[coordinate] = struct{}{}
+	}
+	for typeName, definition := range schema.Schema().Types {
+		for _, field := range definition.Fields {
+			if field.Directives.ForName(directive) != nil {
+				e.sensitive[typeName+"."+field.Name] = struct{}{}
+			}
+		}
+	}
+	return nil
+}
+
+// MutateOperationContext evaluates the request of the operation, once it is parsed and validated.
+func (e *Extension) MutateOperationContext(ctx context.Context, opCtx *graphql.OperationContext) *gqlerror.Error {
+	stats := &Stats{SensitiveFields: e.sensitiveFields(opCtx.Operation)}
+	opCtx.Stats.SetExtension(extensionName, stats)
+	if e.SensitiveOnly && len(stats.SensitiveFields) == 0 {
+		return nil
+	}
+
+	exchange, ok := ctx.Value(exchangeKey{}).(*exchange)
+	if !ok {
+		return gqlerror.Errorf("the request of the operation is not found: the handler must be wrapped with gqlgen.Middleware")
+	}
+	data := modulego.GraphQLData{
+		Type:  modulego.OperationType(opCtx.Operation.Operation),
+		Name:  opCtx.Operation.Name,
+		Count: len(opCtx.Doc.Operations),
+	}
+	decision, err := e.Protector.Evaluate(exchange.r.Clone(modulego.ContextWithGraphQLData(ctx, data)))
+	if err != nil {
+		if len(stats.SensitiveFields) == 0 {
+			return nil
+		}
+		gqlErr := gqlerror.Errorf("the operation cannot be verified")
+		errcode.Set(gqlErr, ErrUnavailable)
+		return gqlErr
+	}
+	stats.Decision = decision
+
+	addResponseHeaders(exchange.w, decision.ResponseHeaders)
+	if decision.Blocked {
+		gqlErr := gqlerror.Errorf("the operation is blocked")
+		errcode.Set(gqlErr, ErrBlocked)
+		return gqlErr
+	}
+	return nil
+}
+
+// sensitiveFields returns the sorted coordinates of the sensitive fields selected by the operation, with its fragments.
+func (e *Extension) sensitiveFields(operation *ast.OperationDefinition) []string {
+	if len(e.sensitive) == 0 {
+		return nil
+	}
+	selected := map[string]struct{}{}
+	visited := map[string]bool{}
+	var walk func(selections ast.SelectionSet)
+	walk = func(selections ast.SelectionSet) {
+		for _, selection := range selections {
+			switch s := selection.(type) {
+			case *ast.Field:
+				if s.ObjectDefinition != nil {
+					coordinate := s.ObjectDefinition.Name + "." + s.Name
+					if _, ok := e.sensitive[coordinate]; ok {
+						selected[coordinate] = struct{}{}
+					}
+				}
+				walk(s.SelectionSet)
+			case *ast.InlineFragment:
+				walk(s.SelectionSet)
+			case *ast.FragmentSpread:
+				if s.Definition != nil && !visited[s.Name] {
+					visited[s.Name] = true
+					walk(s.Definition.SelectionSet)
+				}
+			}
+		}
+	}
+	walk(operation.SelectionSet)
+
+	var fields []string
+	for coordinate := range selected {
+		fields = append(fields, coordinate)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// exchange holds the request of the operations and its response writer.
+type exchange struct {
+	w http.ResponseWriter
+	r *http.Request
+}
+
+// exchangeKey is the context key of the *exchange.
+type exchangeKey struct{}
+
+// Middleware stores the request and the response writer in the context of the operations, for the [Extension].
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), exchangeKey{}, &exchange{w: w, r: r})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// addResponseHeaders adds the headers returned by the Protection API to the response, such as the DataDome cookie.
+func addResponseHeaders(w http.ResponseWriter, headers http.Header) {
+	for name, values := range headers {
+		for _, value := range values {
+			if strings.EqualFold(name, "Set-Cookie") {
+				w.Header().Add(name, value)
+			} else {
+				w.Header().Set(name, value)
+			}
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package gqlgen
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	modulego "github.com/andynuge/datadome-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+var schema = gqlparser.MustLoadSchema(&ast.Source{Input: `
+	directive @sensitive on FIELD_DEFINITION
+	type Query {
+		profile: String!
+		user: User!
+	}
+	type User {
+		name: String!
+		creditCard: String! @sensitive
+	}
+	type Mutation {
+		login(password: String!): String! @sensitive
+		logout: Boolean!
+	}
+`})
+
+// protectionAPI is a Protection API stub blocking the operations named Blocked, and failing while failing is set.
+type protectionAPI struct {
+	*httptest.Server
+	failing atomic.Bool
+
+	mu         sync.Mutex
+	operations []string
+}
+
+func newProtectionAPI(t *testing.T) *protectionAPI {
+	api := &protectionAPI{}
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if api.failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_ = r.ParseForm()
+		api.mu.Lock()
+		api.operations = append(api.operations, r.PostForm.Get("GraphQLOperationType")+" "+r.PostForm.Get("GraphQLOperationName")+" "+r.PostForm.Get("GraphQLOperationCount"))
+		api.mu.Unlock()
+		w.Header().Set("X-Datadome-Headers", "Set-Cookie")
+		w.Header().Set("Set-Cookie", "datadome=client-id; Path=/")
+		if r.PostForm.Get("GraphQLOperationName") == "Blocked" {
+			w.Header().Set("X-Datadomeresponse", "403")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("X-Datadomeresponse", "200")
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(api.Close)
+	return api
+}
+
+// lastOperation returns the last operation reported to the Protection API.
+func (api *protectionAPI) lastOperation() string {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if len(api.operations) == 0 {
+		return ""
+	}
+	return api.operations[len(api.operations)-1]
+}
+
+// newServer returns a GraphQL server using the extension, whose resolvers return the same response.
+func newServer(t *testing.T, extension *Extension) http.Handler {
+	srv := handler.New(&graphql.ExecutableSchemaMock{
+		SchemaFunc: func() *ast.Schema {
+			return schema
+		},
+		ComplexityFunc: func(ctx context.Context, typeName string, fieldName string, childComplexity int, args map[string]any) (int, bool) {
+			return 1, true
+		},
+		ExecFunc: func(ctx context.Context) graphql.ResponseHandler {
+			ran := false
+			return func(ctx context.Context) *graphql.Response {
+				if ran {
+					return nil
+				}
+				ran = true
+				return &graphql.Response{Data: []byte(`{"ok":true}`)}
+			}
+		},
+	})
+	srv.AddTransport(transport.POST{})
+	srv.Use(extension)
+	return srv
+}
+
+// response is the response of a GraphQL request.
+type response struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+// post sends the GraphQL request to the handler.
+func post(t *testing.T, h http.Handler, query string, operationName string) (*httptest.ResponseRecorder, response) {
+	body, _ := json.Marshal(map[string]string{"query": query, "operationName": operationName})
+	r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	r.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	h.ServeHTTP(rr, r)
+
+	var resp response
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &resp), rr.Body.String())
+	return rr, resp
+}
+
+func TestExtension(t *testing.T) {
+	api := newProtectionAPI(t)
+	client, err := modulego.NewClient("your-api-key", modulego.WithEndpoint(api.URL))
+	assert.Nil(t, err)
+	h := Middleware(newServer(t, &Extension{Protector: client}))
+
+	t.Run("Allowed operation", func(t *testing.T) {
+		rr, resp := post(t, h, "query Profile { profile }", "")
+
+		assert.Empty(t, resp.Errors)
+		assert.JSONEq(t, `{"ok":true}`, string(resp.Data))
+		assert.Equal(t, "query Profile 1", api.lastOperation())
+		assert.Equal(t, "datadome=client-id; Path=/", rr.Header().Get("Set-Cookie"))
+	})
+
+	t.Run("Operation selected by name", func(t *testing.T) {
+		_, resp := post(t, h, "query Profile { profile } mutation Logout { logout }", "Logout")
+
+		assert.Empty(t, resp.Errors)
+		assert.Equal(t, "mutation Logout 2", api.lastOperation())
+	})
+
+	t.Run("Blocked operation", func(t *testing.T) {
+		_, resp := post(t, h, "mutation Blocked { logout }", "")
+
+		assert.Len(t, resp.Errors, 1)
+		assert.Equal(t, "the operation is blocked", resp.Errors[0].Message)
+		assert.Equal(t, ErrBlocked, resp.Errors[0].Extensions["code"])
+		assert.Equal(t, "null", string(resp.Data))
+	})
+
+	t.Run("Failing Protection API", func(t *testing.T) {
+		api.failing.Store(true)
+		defer api.failing.Store(false)
+
+		// the operation without sensitive field fails open
+		_, resp := post(t, h, "query Profile { profile }", "")
+		assert.Empty(t, resp.Errors)
+
+		// the operation selecting a sensitive field through a fragment fails closed
+		_, resp = post(t, h, "query User { user { ...Card } } fragment Card on User { ... on User { creditCard } }", "")
+		assert.Len(t, resp.Errors, 1)
+		assert.Equal(t, ErrUnavailable, resp.Errors[0].Extensions["code"])
+	})
+
+	t.Run("Handler without middleware", func(t *testing.T) {
+		_, resp := post(t, newServer(t, &Extension{Protector: client}), "query Profile { profile }", "")
+
+		assert.Len(t, resp.Errors, 1)
+		assert.Equal(t, "the request of the operation is not found: the handler must be wrapped with gqlgen.Middleware", resp.Errors[0].Message)
+	})
+}
+
+func TestExtension_SensitiveOnly(t *testing.T) {
+	api := newProtectionAPI(t)
+	client, err := modulego.NewClient("your-api-key", modulego.WithEndpoint(api.URL))
+	assert.Nil(t, err)
+	h := Middleware(newServer(t, &Extension{Protector: client, SensitiveOnly: true, SensitiveFields: []string{"Query.profile"}}))
+
+	_, resp := post(t, h, "query User { user { name } }", "")
+	assert.Empty(t, resp.Errors)
+	assert.Equal(t, "", api.lastOperation())
+
+	_, resp = post(t, h, "mutation Login { login(password: \"secret\") }", "")
+	assert.Empty(t, resp.Errors)
+	assert.Equal(t, "mutation Login 1", api.lastOperation())
+
+	_, resp = post(t, h, "{ profile }", "")
+	assert.Empty(t, resp.Errors)
+	assert.Equal(t, "query  1", api.lastOperation())
+}
+
+func TestExtension_SensitiveFields(t *testing.T) {
+	extension := &Extension{Protector: modulego.EvaluatorFunc(nil), SensitiveFields: []string{"User.name"}}
+	assert.Nil(t, extension.Validate(&graphql.ExecutableSchemaMock{SchemaFunc: func() *ast.Schema { return schema }}))
+
+	document := gqlparser.MustLoadQuery(schema, `
+		query User { user { ...Name ... on User { creditCard } } }
+		mutation Login { login(password: "secret") }
+		fragment Name on User { name ...Name2 }
+		fragment Name2 on User { name }
+	`)
+
+	assert.Equal(t, []string{"User.creditCard", "User.name"}, extension.sensitiveFields(document.Operations.ForName("User")))
+	assert.Equal(t, []string{"Mutation.login"}, extension.sensitiveFields(document.Operations.ForName("Login")))
+}
+
+func TestExtension_Validate(t *testing.T) {
+	es := &graphql.ExecutableSchemaMock{SchemaFunc: func() *ast.Schema { return schema }}
+
+	t.Run("Missing protector", func(t *testing.T) {
+		assert.EqualError(t, (&Extension{}).Validate(es), "Protector must be defined")
+	})
+
+	t.Run("Unknown sensitive field", func(t *testing.T) {
+		extension := &Extension{Protector: modulego.EvaluatorFunc(nil), SensitiveFields: []string{"User.password"}}
+
+		assert.EqualError(t, extension.Validate(es), `SensitiveFields must contain fields of the schema: "User.password"`)
+	})
+
+	t.Run("Custom directive", func(t *testing.T) {
+		extension := &Extension{Protector: modulego.EvaluatorFunc(nil), SensitiveDirective: "private"}
+
+		assert.Nil(t, extension.Validate(es))
+		assert.Empty(t, extension.sensitive)
+	})
+}
@@ -0,0 +1,635 @@
+package modulego
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// PersistedQuery describes the operation of a GraphQL persisted query, known by the SHA-256 hash of its document.
+type PersistedQuery struct {
+	Name string        `json:"name"`
+	Type OperationType `json:"type"`
+}
+
+// graphQLDataKey is the context key of the [GraphQLData] reported for a request instead of the one read from its body.
+type graphQLDataKey struct{}
+
+// ContextWithGraphQLData returns a copy of ctx with the GraphQL operation of the request.
+// The requests evaluated with this context report data to the Protection API without reading their body,
+// for instance when the operation is already parsed by the GraphQL server.
+func ContextWithGraphQLData(ctx context.Context, data GraphQLData) context.Context {
+	return context.WithValue(ctx, graphQLDataKey{}, &data)
+}
+
+// WithGraphQLPersistedQueries is a functional option to report the operations of the Automatic Persisted Queries
+// sent without their document, the queries being indexed by the hexadecimal SHA-256 hash of their document.
+// The persisted queries missing from the mapping are reported with their hash as operation name.
+func WithGraphQLPersistedQueries(queries map[string]PersistedQuery) Option {
+	return func(c *Client) {
+		c.GraphQLPersistedQueries = queries
+	}
+}
+
+// compilePersistedQueries validates the persisted queries and returns them indexed by lower case hashes.
+func compilePersistedQueries(queries map[string]PersistedQuery) (map[string]PersistedQuery, []error) {
+	if len(queries) == 0 {
+		return nil, nil
+	}
+	hashes := make([]string, 0, len(queries))
+	for hash := range queries {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+
+	var errs []error
+	compiled := make(map[string]PersistedQuery, len(queries))
+	for _, hash := range hashes {
+		query := queries[hash]
+		if !isSHA256Hash(hash) {
+			errs = append(errs, fmt.Errorf("GraphQLPersistedQueries must be indexed by hexadecimal SHA-256 hashes: %q", hash))
+		}
+		switch query.Type {
+		case Query, Mutation, Subscription:
+		default:
+			errs = append(errs, fmt.Errorf("GraphQLPersistedQueries[%q].Type must be query, mutation or subscription: %q", hash, query.Type))
+		}
+		compiled[strings.ToLower(hash)] = query
+	}
+	return compiled, errs
+}
+
+// isSHA256Hash reports whether hash is the hexadecimal encoding of a SHA-256 hash.
+func isSHA256Hash(hash string) bool {
+	if len(hash) != 64 {
+		return false
+	}
+	for i := 0; i < len(hash); i++ {
+		if !isDigit(hash[i]) && !bytesContain("abcdefABCDEF", hash[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// getGraphQLData extracts the GraphQL operations of the request and parses them.
+// The operation of a GET request is read from its URL parameters (see [getGraphQLURLRequest]).
+// Otherwise, at most maximumBodySize bytes of the body are read and the body is restored to the original request.
+// The operations of a batch are aggregated (see [aggregateGraphQLRequests]).
+// The Automatic Persisted Queries sent without their document are looked up in persistedQueries.
+// An error is returned if
+// - an error happened during the lecture of the body
+// - neither a GraphQL query nor a persisted query was found
+func getGraphQLData(r *http.Request, maximumBodySize int, persistedQueries map[string]PersistedQuery) (*GraphQLData, error) {
+	var requests []graphQLRequest
+	var err error
+	if r.Method == http.MethodGet {
+		requests = []graphQLRequest{getGraphQLURLRequest(r.URL.Query())}
+	} else {
+		requests, err = readGraphQLBody(r, maximumBodySize)
+	}
+
+	gqlData := aggregateGraphQLRequests(requests, persistedQueries)
+	if gqlData == nil {
+		if err != nil {
+			return nil, fmt.Errorf("error while reading request body: %w", err)
+		}
+		return nil, fmt.Errorf("query not found in the request body")
+	}
+	return gqlData, nil
+}
+
+// aggregateGraphQLRequests returns the [GraphQLData] of the requests, or nil when none has a query or a persisted query hash.
+// The Count is the total of the operations, and the Type and the Name are the ones of the first most sensitive operation.
+func aggregateGraphQLRequests(requests []graphQLRequest, persistedQueries map[string]PersistedQuery) *GraphQLData {
+	var gqlData *GraphQLData
+	for _, request := range requests {
+		data := request.data(persistedQueries)
+		if data == nil {
+			continue
+		}
+		if gqlData == nil {
+			gqlData = data
+			continue
+		}
+		gqlData.Count += data.Count
+		if operationSensitivity(data.Type) > operationSensitivity(gqlData.Type) {
+			gqlData.Type = data.Type
+			gqlData.Name = data.Name
+		}
+	}
+	return gqlData
+}
+
+// operationSensitivity ranks the operation types: the mutations are the most sensitive, followed by the subscriptions and the queries.
+func operationSensitivity(operationType OperationType) int {
+	switch operationType {
+	case Mutation:
+		return 2
+	case Subscription:
+		return 1
+	}
+	return 0
+}
+
+// readGraphQLBody returns the GraphQL requests of the body, depending on its media type:
+//   - application/graphql: the body is the document, and the operation name is read from the URL
+//   - multipart/form-data: the `operations` field holds the JSON requests, as defined by the GraphQL multipart request specification
+//   - otherwise, the body holds the JSON requests
+//
+// At most maximumBodySize bytes are read and the body is restored to the original request.
+func readGraphQLBody(r *http.Request, maximumBodySize int) ([]graphQLRequest, error) {
+	var consumed bytes.Buffer
+	body := r.Body
+	reader := io.TeeReader(&io.LimitedReader{R: body, N: int64(maximumBodySize)}, &consumed)
+	defer func() {
+		r.Body = readCloser{Reader: io.MultiReader(&consumed, body), Closer: body}
+	}()
+
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/graphql":
+		document, err := io.ReadAll(reader)
+		if len(document) == 0 {
+			return nil, err
+		}
+		return []graphQLRequest{{query: string(document), hasQuery: true, operationName: r.URL.Query().Get("operationName")}}, err
+	case "multipart/form-data":
+		return decodeGraphQLMultipart(reader, params["boundary"])
+	}
+	return decodeGraphQLBody(reader)
+}
+
+// decodeGraphQLMultipart returns the GraphQL requests of the `operations` field of the multipart body read from r.
+// The fields preceding it are skipped, although the specification requires it to be the first one.
+func decodeGraphQLMultipart(r io.Reader, boundary string) ([]graphQLRequest, error) {
+	if boundary == "" {
+		return nil, fmt.Errorf("multipart boundary not found")
+	}
+	reader := multipart.NewReader(r, boundary)
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "operations" {
+			return decodeGraphQLBody(part)
+		}
+	}
+}
+
+// getGraphQLURLRequest returns the GraphQL request of the `query`, `operationName` and `extensions` URL parameters,
+// the extensions being JSON encoded.
+func getGraphQLURLRequest(values url.Values) graphQLRequest {
+	request := graphQLRequest{
+		query:            values.Get("query"),
+		hasQuery:         values.Has("query"),
+		operationName:    values.Get("operationName"),
+		hasOperationName: values.Has("operationName"),
+	}
+	if extensions := values.Get("extensions"); extensions != "" {
+		request.persistedQueryHash = persistedQueryHash([]byte(extensions))
+	}
+	return request
+}
+
+// readCloser restores a partially read body.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// graphQLRequest holds the fields of a GraphQL request identifying its operation.
+type graphQLRequest struct {
+	query              string
+	hasQuery           bool
+	operationName      string
+	hasOperationName   bool
+	persistedQueryHash string
+}
+
+// data returns the [GraphQLData] of the request, or nil when it has neither a query nor a persisted query hash.
+// A persisted query missing from persistedQueries is reported as a single query named by its hash.
+func (request graphQLRequest) data(persistedQueries map[string]PersistedQuery) *GraphQLData {
+	if request.hasQuery {
+		return parseGraphQLQuery(request.query, request.operationName)
+	}
+	if request.persistedQueryHash == "" {
+		return nil
+	}
+	if query, ok := persistedQueries[strings.ToLower(request.persistedQueryHash)]; ok {
+		return &GraphQLData{Count: 1, Type: query.Type, Name: query.Name}
+	}
+	return &GraphQLData{Count: 1, Type: Query, Name: request.persistedQueryHash}
+}
+
+// decodeGraphQLBody returns the GraphQL requests read from r, a single JSON object or a batch of JSON objects.
+// The objects are decoded as a stream of tokens: the other fields, such as the variables, are skipped without being stored,
+// and the decoding of a single object stops as soon as its query and operation name are found.
+// When the body is truncated or invalid, the requests decoded so far are returned along with the error.
+func decodeGraphQLBody(r io.Reader) ([]graphQLRequest, error) {
+	decoder := json.NewDecoder(r)
+	t, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case json.Delim('{'):
+		request, err := decodeGraphQLObject(decoder, false)
+		return []graphQLRequest{request}, err
+	case json.Delim('['):
+		var requests []graphQLRequest
+		for decoder.More() {
+			t, err := decoder.Token()
+			if err != nil {
+				return requests, err
+			}
+			if t != json.Delim('{') {
+				return requests, fmt.Errorf("GraphQL batch must contain JSON objects")
+			}
+			request, err := decodeGraphQLObject(decoder, true)
+			requests = append(requests, request)
+			if err != nil {
+				return requests, err
+			}
+			// closing brace of the object
+			if _, err := decoder.Token(); err != nil {
+				return requests, err
+			}
+		}
+		return requests, nil
+	}
+	return nil, fmt.Errorf("GraphQL request must be a JSON object or an array of JSON objects")
+}
+
+// decodeGraphQLObject reads the fields of the JSON object whose opening brace has just been read.
+// Unless whole is set, the decoding stops once the query and the operation name are found.
+func decodeGraphQLObject(decoder *json.Decoder, whole bool) (graphQLRequest, error) {
+	var request graphQLRequest
+	for decoder.More() && (whole || !(request.hasQuery && request.hasOperationName)) {
+		t, err := decoder.Token()
+		if err != nil {
+			return request, err
+		}
+		switch t {
+		case "query", "operationName":
+			var value interface{}
+			if err := decoder.Decode(&value); err != nil {
+				return request, err
+			}
+			if t == "query" {
+				request.query, request.hasQuery = value.(string)
+			} else {
+				request.operationName, _ = value.(string)
+				request.hasOperationName = true
+			}
+		case "extensions":
+			var raw json.RawMessage
+			if err := decoder.Decode(&raw); err != nil {
+				return request, err
+			}
+			request.persistedQueryHash = persistedQueryHash(raw)
+		default:
+			if err := skipJSONValue(decoder); err != nil {
+				return request, err
+			}
+		}
+	}
+	return request, nil
+}
+
+// persistedQueryHash returns the `persistedQuery.sha256Hash` field of the JSON extensions, or an empty string when it is not found.
+func persistedQueryHash(extensions []byte) string {
+	var value struct {
+		PersistedQuery struct {
+			Sha256Hash interface{} `json:"sha256Hash"`
+		} `json:"persistedQuery"`
+	}
+	if json.Unmarshal(extensions, &value) != nil {
+		return ""
+	}
+	hash, _ := value.PersistedQuery.Sha256Hash.(string)
+	return hash
+}
+
+// skipJSONValue reads the tokens of the next value of the decoder.
+func skipJSONValue(decoder *json.Decoder) error {
+	depth := 0
+	for {
+		t, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch t {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// graphQLOperation describes an operation definition of a GraphQL document.
+type graphQLOperation struct {
+	Type OperationType
+	Name string
+}
+
+// parseGraphQLQuery returns a [GraphQLData] structure with information extracted from the GraphQL document.
+// The reported operation is the one named operationName, or the first one when operationName is empty or unknown.
+// The Count is 0 when the document is not valid.
+func parseGraphQLQuery(query string, operationName string) *GraphQLData {
+	gqlData := &GraphQLData{
+		Count: 0,
+		Type:  Query,
+		Name:  "",
+	}
+	operations, err := parseGraphQLDocument(query)
+	if err != nil || len(operations) == 0 {
+		return gqlData
+	}
+
+	gqlData.Count = len(operations)
+	selected := operations[0]
+	if operationName != "" {
+		for _, operation := range operations {
+			if operation.Name == operationName {
+				selected = operation
+				break
+			}
+		}
+	}
+	gqlData.Type = selected.Type
+	gqlData.Name = selected.Name
+	return gqlData
+}
+
+// parseGraphQLDocument returns the operation definitions of the executable GraphQL document.
+// The selection sets, the variable definitions and the arguments are only checked to be balanced;
+// the fragment definitions are skipped.
+func parseGraphQLDocument(document string) ([]graphQLOperation, error) {
+	var operations []graphQLOperation
+	l := &graphQLLexer{src: document}
+	for {
+		t, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case t.kind == tokenEOF:
+			return operations, nil
+		case t.is(tokenPunctuator, "{"):
+			// shorthand syntax of a query
+			if err := l.skipBalanced("{", "}"); err != nil {
+				return nil, err
+			}
+			operations = append(operations, graphQLOperation{Type: Query})
+		case t.is(tokenName, "query"), t.is(tokenName, "mutation"), t.is(tokenName, "subscription"):
+			operation := graphQLOperation{Type: OperationType(t.value)}
+			if t, err = l.next(); err != nil {
+				return nil, err
+			}
+			if t.kind == tokenName {
+				operation.Name = t.value
+				if t, err = l.next(); err != nil {
+					return nil, err
+				}
+			}
+			if t.is(tokenPunctuator, "(") {
+				// variable definitions
+				if err := l.skipBalanced("(", ")"); err != nil {
+					return nil, err
+				}
+				if t, err = l.next(); err != nil {
+					return nil, err
+				}
+			}
+			if err := l.skipSelectionSet(t); err != nil {
+				return nil, err
+			}
+			operations = append(operations, operation)
+		case t.is(tokenName, "fragment"):
+			// fragment Name on Type
+			for _, expected := range []string{"", "on", ""} {
+				if t, err = l.next(); err != nil {
+					return nil, err
+				}
+				if t.kind != tokenName || (expected != "" && t.value != expected) {
+					return nil, fmt.Errorf("invalid fragment definition at %d", t.pos)
+				}
+			}
+			if t, err = l.next(); err != nil {
+				return nil, err
+			}
+			if err := l.skipSelectionSet(t); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %q at %d", t.value, t.pos)
+		}
+	}
+}
+
+// skipSelectionSet skips the directives starting with t and the following selection set.
+func (l *graphQLLexer) skipSelectionSet(t graphQLToken) error {
+	var err error
+	for t.is(tokenPunctuator, "@") {
+		if t, err = l.next(); err != nil {
+			return err
+		}
+		if t.kind != tokenName {
+			return fmt.Errorf("invalid directive at %d", t.pos)
+		}
+		if t, err = l.next(); err != nil {
+			return err
+		}
+		if t.is(tokenPunctuator, "(") {
+			if err := l.skipBalanced("(", ")"); err != nil {
+				return err
+			}
+			if t, err = l.next(); err != nil {
+				return err
+			}
+		}
+	}
+	if !t.is(tokenPunctuator, "{") {
+		return fmt.Errorf("selection set expected at %d", t.pos)
+	}
+	return l.skipBalanced("{", "}")
+}
+
+// skipBalanced reads the tokens up to the close punctuator matching the open punctuator that has just been read.
+func (l *graphQLLexer) skipBalanced(open string, close string) error {
+	depth := 1
+	for depth > 0 {
+		t, err := l.next()
+		if err != nil {
+			return err
+		}
+		switch {
+		case t.kind == tokenEOF:
+			return fmt.Errorf("%q expected at %d", close, t.pos)
+		case t.is(tokenPunctuator, open):
+			depth++
+		case t.is(tokenPunctuator, close):
+			depth--
+		}
+	}
+	return nil
+}
+
+// graphQLTokenKind describes the lexical tokens of GraphQL.
+type graphQLTokenKind int
+
+const (
+	tokenEOF graphQLTokenKind = iota
+	tokenPunctuator
+	tokenName
+	tokenNumber
+	tokenString
+)
+
+// graphQLToken is a lexical token of a GraphQL document.
+// The value of the strings is not unescaped.
+type graphQLToken struct {
+	kind  graphQLTokenKind
+	value string
+	pos   int
+}
+
+// is reports whether the token has the kind and the value.
+func (t graphQLToken) is(kind graphQLTokenKind, value string) bool {
+	return t.kind == kind && t.value == value
+}
+
+// graphQLLexer splits a GraphQL document into tokens,
+// ignoring the white spaces, the line terminators, the commas and the comments.
+type graphQLLexer struct {
+	src string
+	pos int
+}
+
+// next returns the next token of the document, or a token of kind tokenEOF at its end.
+func (l *graphQLLexer) next() (graphQLToken, error) {
+	l.skipIgnored()
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return graphQLToken{kind: tokenEOF, pos: start}, nil
+	}
+
+	c := l.src[l.pos]
+	switch {
+	case isGraphQLNameStart(c):
+		l.pos++
+		for l.pos < len(l.src) && (isGraphQLNameStart(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		return graphQLToken{kind: tokenName, value: l.src[start:l.pos], pos: start}, nil
+	case c == '-' || isDigit(c):
+		l.pos++
+		for l.pos < len(l.src) && (isDigit(l.src[l.pos]) || bytesContain(".eE+-", l.src[l.pos])) {
+			l.pos++
+		}
+		return graphQLToken{kind: tokenNumber, value: l.src[start:l.pos], pos: start}, nil
+	case c == '"':
+		if err := l.skipString(); err != nil {
+			return graphQLToken{}, err
+		}
+		return graphQLToken{kind: tokenString, value: l.src[start:l.pos], pos: start}, nil
+	case c == '.':
+		if len(l.src)-l.pos < 3 || l.src[l.pos:l.pos+3] != "..." {
+			return graphQLToken{}, fmt.Errorf("unexpected '.' at %d", start)
+		}
+		l.pos += 3
+		return graphQLToken{kind: tokenPunctuator, value: "...", pos: start}, nil
+	case bytesContain("!$&():=@[]{|}", c):
+		l.pos++
+		return graphQLToken{kind: tokenPunctuator, value: l.src[start:l.pos], pos: start}, nil
+	}
+	return graphQLToken{}, fmt.Errorf("unexpected character %q at %d", c, start)
+}
+
+// skipIgnored skips the white spaces, the line terminators, the commas, the byte order marks and the comments.
+func (l *graphQLLexer) skipIgnored() {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			l.pos++
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+		case c == 0xEF && len(l.src)-l.pos >= 3 && l.src[l.pos:l.pos+3] == "\uFEFF":
+			l.pos += 3
+		default:
+			return
+		}
+	}
+}
+
+// skipString skips the string or the block string starting at the current position.
+func (l *graphQLLexer) skipString() error {
+	start := l.pos
+	if len(l.src)-l.pos >= 3 && l.src[l.pos:l.pos+3] == `"""` {
+		l.pos += 3
+		for l.pos < len(l.src) {
+			switch {
+			case len(l.src)-l.pos >= 4 && l.src[l.pos:l.pos+4] == `\"""`:
+				l.pos += 4
+			case len(l.src)-l.pos >= 3 && l.src[l.pos:l.pos+3] == `"""`:
+				l.pos += 3
+				return nil
+			default:
+				l.pos++
+			}
+		}
+		return fmt.Errorf("unterminated block string at %d", start)
+	}
+
+	l.pos++
+	for l.pos < len(l.src) {
+		switch l.src[l.pos] {
+		case '"':
+			l.pos++
+			return nil
+		case '\\':
+			l.pos += 2
+		case '\n', '\r':
+			return fmt.Errorf("unterminated string at %d", start)
+		default:
+			l.pos++
+		}
+	}
+	return fmt.Errorf("unterminated string at %d", start)
+}
+
+// isGraphQLNameStart reports whether c may start a GraphQL name.
+func isGraphQLNameStart(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_'
+}
+
+// isDigit reports whether c is an ASCII digit.
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// bytesContain reports whether the ASCII characters contain c.
+func bytesContain(chars string, c byte) bool {
+	for i := 0; i < len(chars); i++ {
+		if chars[i] == c {
+			return true
+		}
+	}
+	return false
+}
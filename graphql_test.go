@@ -0,0 +1,465 @@
+package modulego
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGetGraphQLData with different type of GraphQL JSON body
+// 1. Shorthand Syntax
+// 2. Mutation
+// 3. Query
+// 4. Multiple operations
+// 5. Wrong GraphQL query
+// 6. Comments, strings and fragments
+// 7. Operation selected by the operationName field
+func TestGetGraphQLData(t *testing.T) {
+	tests := []struct {
+		want  GraphQLData
+		input string
+	}{
+		{want: GraphQLData{Count: 1, Name: "", Type: Query}, input: `{"query":"{ todos { title }}"}`},
+		{want: GraphQLData{Count: 1, Name: "LoginV2", Type: Mutation}, input: `{"query":"mutation LoginV2($loginV2LoginName2: String!, $loginV2Password2: String!, $loginV2ServiceLocationId3: ID!) { loginV2(loginName: $loginV2LoginName2, password: $loginV2Password2, serviceLocationId: $loginV2ServiceLocationId3) { cookieAdapterToken msg }}","variables":{"loginV2LoginName2":"alpha@staging.com","loginV2Password2":"Test1234","loginV2ServiceLocationId3":"50000059"}}`},
+		{want: GraphQLData{Count: 1, Name: "Coupons", Type: Query}, input: `{"query":"query Coupons($couponsServiceLocationId3: ID!) { coupons(serviceLocationId: $couponsServiceLocationId3) { coupons { name title circularId couponType endDate } } }","variables":{"couponsServiceLocationId3":"50000059"}}`},
+		{want: GraphQLData{Count: 4, Name: "One", Type: Mutation}, input: `{"query":"mutation One { # Do something ...\n}mutation Two { # Do something ...\n}query Three @depends(on: [\"One\", \"Two\"]) { # Do something ...\n}query Four @depends(on: \"Three\") { # Do something ...\n}"}`},
+		{want: GraphQLData{Count: 1, Name: "", Type: Query}, input: `{"query":"query { todos { title }}"}`},
+		{want: GraphQLData{Count: 0, Name: "", Type: Query}, input: `{"query":"mutatio RefreshTokenV2 $serviceLocationId: ID!) { refreshTokenV2(serviceLocationId: $serviceLocationId) { cookieAdapterToken msg }}","variables":{"serviceLocationId":""}}`},
+		{want: GraphQLData{Count: 1, Name: "Search", Type: Query}, input: `{"variables":{"query":"mutation Fake { x }"},"query":"# mutation Commented { x }\nquery Search { search(text: \"mutation Inside { x }\") { id } }"}`},
+		{want: GraphQLData{Count: 1, Name: "Describe", Type: Mutation}, input: `{"query":"mutation Describe { describe(text: \"\"\"subscription { \\\"\"\" } \"\"\") { id } }"}`},
+		{want: GraphQLData{Count: 1, Name: "Escaped", Type: Subscription}, input: `{"query":"subscription Escaped { events { id } }"}`},
+		{want: GraphQLData{Count: 2, Name: "Checkout", Type: Mutation}, input: `{"operationName":"Checkout","query":"query Cart { cart { ...Items } } fragment Items on Cart { items { id } } mutation Checkout($input: CheckoutInput = {cart: {id: 1}}) @auth(roles: [ADMIN]) { checkout(input: $input) { id } }"}`},
+		{want: GraphQLData{Count: 2, Name: "Cart", Type: Query}, input: `{"query":"query Cart { cart { id } } mutation Checkout { checkout { id } }","operationName":null}`},
+		{want: GraphQLData{Count: 2, Name: "Cart", Type: Query}, input: `{"query":"query Cart { cart { id } } mutation Checkout { checkout { id } }","operationName":"Unknown"}`},
+		{want: GraphQLData{Count: 0, Name: "", Type: Query}, input: `{"query":"query Unterminated { cart { id }"}`},
+	}
+
+	for _, tc := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(tc.input))
+
+		got, err := getGraphQLData(r, DefaultMaximumBodySizeValue, nil)
+
+		assert.Nil(t, err, tc.input)
+		assert.Equal(t, tc.want, *got, tc.input)
+		// the body is restored
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, tc.input, string(body))
+	}
+}
+
+func TestGetGraphQLData_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{"String", `"{ todos { title } }"`, "error while reading request body: GraphQL request must be a JSON object or an array of JSON objects"},
+		{"Batch of strings", `["{ todos { title } }"]`, "error while reading request body: GraphQL batch must contain JSON objects"},
+		{"Empty batch", `[]`, "query not found in the request body"},
+		{"Persisted query without hash", `{"extensions":{"persistedQuery":{"version":1}}}`, "query not found in the request body"},
+		{"Missing query", `{"variables":{"query":"{ todos }"}}`, "query not found in the request body"},
+		{"Query not a string", `{"query":{"text":"{ todos }"}}`, "query not found in the request body"},
+		{"Invalid JSON", `{"variables":{"id":}, "query":"{ todos }"}`, "error while reading request body: "},
+		{"Empty body", ``, "error while reading request body: EOF"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(tc.input))
+
+			got, err := getGraphQLData(r, DefaultMaximumBodySizeValue, nil)
+
+			assert.Nil(t, got)
+			assert.ErrorContains(t, err, tc.err)
+			body, _ := io.ReadAll(r.Body)
+			assert.Equal(t, tc.input, string(body))
+		})
+	}
+
+	t.Run("Query after the maximum body size", func(t *testing.T) {
+		input := `{"variables":{"text":"` + strings.Repeat("a", 100) + `"},"query":"{ todos }"}`
+		r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(input))
+
+		got, err := getGraphQLData(r, 64, nil)
+
+		assert.Nil(t, got)
+		assert.NotNil(t, err)
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, input, string(body))
+	})
+
+	t.Run("Variables after the maximum body size", func(t *testing.T) {
+		input := `{"query":"mutation Upload { upload }","variables":{"file":"` + strings.Repeat("a", 100) + `"}}`
+		r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(input))
+
+		got, err := getGraphQLData(r, 64, nil)
+
+		assert.Nil(t, err)
+		assert.Equal(t, GraphQLData{Count: 1, Name: "Upload", Type: Mutation}, *got)
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, input, string(body))
+	})
+}
+
+func TestGetGraphQLData_Batch(t *testing.T) {
+	tests := []struct {
+		name  string
+		want  GraphQLData
+		input string
+	}{
+		{"Queries", GraphQLData{Count: 3, Name: "A", Type: Query}, `[{"query":"query A { a }"},{"query":"query B { b } query C { c }","operationName":"C"}]`},
+		{"Mutation", GraphQLData{Count: 3, Name: "Checkout", Type: Mutation}, `[{"query":"query Cart { cart }"},{"query":"subscription OnPrice { price }"},{"query":"mutation Checkout { checkout }"}]`},
+		{"Subscription", GraphQLData{Count: 2, Name: "OnPrice", Type: Subscription}, `[{"variables":{"id":1},"query":"query Cart { cart }"},{"query":"subscription OnPrice { price }","extensions":{}}]`},
+		{"Invalid elements", GraphQLData{Count: 1, Name: "", Type: Query}, `[{"variables":{}},{"query":"mutatio X { x }"},{"query":"{ a }"}]`},
+		{"Persisted queries", GraphQLData{Count: 2, Name: "Checkout", Type: Mutation}, `[{"query":"{ a }"},{"extensions":{"persistedQuery":{"version":1,"sha256Hash":"` + strings.Repeat("c", 64) + `"}}}]`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(tc.input))
+
+			got, err := getGraphQLData(r, DefaultMaximumBodySizeValue, map[string]PersistedQuery{
+				strings.Repeat("c", 64): {Name: "Checkout", Type: Mutation},
+			})
+
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, *got)
+			body, _ := io.ReadAll(r.Body)
+			assert.Equal(t, tc.input, string(body))
+		})
+	}
+
+	t.Run("Batch truncated by the maximum body size", func(t *testing.T) {
+		input := `[{"query":"mutation A { a }"},{"query":"query B { b }","variables":{"text":"` + strings.Repeat("a", 100) + `"}}]`
+		r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(input))
+
+		got, err := getGraphQLData(r, 64, nil)
+
+		assert.Nil(t, err)
+		assert.Equal(t, GraphQLData{Count: 2, Name: "A", Type: Mutation}, *got)
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, input, string(body))
+	})
+}
+
+func TestGetGraphQLData_PersistedQueries(t *testing.T) {
+	known := "ecf4edb46db40b5132295c0291d62fb65d6759a9eedfa4d5d612dd5ec54a6b38"
+	unknown := strings.Repeat("0", 64)
+	persistedQueries, errs := compilePersistedQueries(map[string]PersistedQuery{
+		strings.ToUpper(known): {Name: "LoginV2", Type: Mutation},
+	})
+	assert.Empty(t, errs)
+
+	tests := []struct {
+		name  string
+		want  GraphQLData
+		input string
+	}{
+		{"Known hash", GraphQLData{Count: 1, Name: "LoginV2", Type: Mutation}, `{"operationName":"Fake","variables":{},"extensions":{"persistedQuery":{"version":1,"sha256Hash":"` + known + `"}}}`},
+		{"Unknown hash", GraphQLData{Count: 1, Name: unknown, Type: Query}, `{"extensions":{"persistedQuery":{"version":1,"sha256Hash":"` + unknown + `"}}}`},
+		{"Registration with the query", GraphQLData{Count: 1, Name: "Coupons", Type: Query}, `{"extensions":{"persistedQuery":{"version":1,"sha256Hash":"` + known + `"}},"query":"query Coupons { coupons }"}`},
+		{"Invalid extensions", GraphQLData{Count: 1, Name: "", Type: Query}, `{"extensions":{"persistedQuery":"` + known + `"},"query":"{ a }"}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(tc.input))
+
+			got, err := getGraphQLData(r, DefaultMaximumBodySizeValue, persistedQueries)
+
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, *got)
+		})
+	}
+}
+
+func TestCompilePersistedQueries(t *testing.T) {
+	hash := strings.Repeat("a", 64)
+
+	t.Run("Empty mapping", func(t *testing.T) {
+		queries, errs := compilePersistedQueries(nil)
+
+		assert.Empty(t, errs)
+		assert.Nil(t, queries)
+	})
+
+	t.Run("Invalid mapping", func(t *testing.T) {
+		_, errs := compilePersistedQueries(map[string]PersistedQuery{
+			hash:       {Name: "A", Type: "delete"},
+			"abc":      {Name: "B", Type: Query},
+			hash + "0": {Type: Mutation},
+		})
+
+		assert.Equal(t, []string{
+			`GraphQLPersistedQueries["` + hash + `"].Type must be query, mutation or subscription: "delete"`,
+			`GraphQLPersistedQueries must be indexed by hexadecimal SHA-256 hashes: "` + hash + `0"`,
+			`GraphQLPersistedQueries must be indexed by hexadecimal SHA-256 hashes: "abc"`,
+		}, errorMessages(errs))
+	})
+
+	t.Run("Mapping is decoded from the configuration", func(t *testing.T) {
+		c := DefaultConfig()
+		c.ServerSideKey = "your-api-key"
+		err := c.Decode(strings.NewReader(`{"graphQLPersistedQueries": {"` + hash + `": {"name": "LoginV2", "type": "mutation"}}}`))
+		assert.Nil(t, err)
+
+		client, err := NewClientFromConfig(c)
+
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]PersistedQuery{hash: {Name: "LoginV2", Type: Mutation}}, client.GraphQLPersistedQueries)
+	})
+}
+
+func TestGetGraphQLData_Transports(t *testing.T) {
+	multipartBody := "--boundary\r\n" +
+		"Content-Disposition: form-data; name=\"operations\"\r\n\r\n" +
+		`{"query":"mutation Upload($file: Upload!) { upload(file: $file) { id } }","variables":{"file":null}}` + "\r\n" +
+		"--boundary\r\n" +
+		"Content-Disposition: form-data; name=\"map\"\r\n\r\n" +
+		`{"0":["variables.file"]}` + "\r\n" +
+		"--boundary\r\n" +
+		"Content-Disposition: form-data; name=\"0\"; filename=\"a.txt\"\r\n\r\n" +
+		strings.Repeat("a", 100) + "\r\n" +
+		"--boundary--\r\n"
+
+	tests := []struct {
+		name        string
+		want        GraphQLData
+		method      string
+		target      string
+		contentType string
+		body        string
+	}{
+		{"GET", GraphQLData{Count: 2, Name: "B", Type: Query}, http.MethodGet, "/graphql?query=query+A+%7B+a+%7D+query+B+%7B+b+%7D&operationName=B", "", ""},
+		{"GET persisted query", GraphQLData{Count: 1, Name: strings.Repeat("a", 64), Type: Query}, http.MethodGet, "/graphql?extensions=" + url.QueryEscape(`{"persistedQuery":{"version":1,"sha256Hash":"`+strings.Repeat("a", 64)+`"}}`), "", ""},
+		{"GraphQL document", GraphQLData{Count: 2, Name: "B", Type: Mutation}, http.MethodPost, "/graphql?operationName=B", "application/graphql", "query A { a } mutation B { b }"},
+		{"JSON with charset", GraphQLData{Count: 1, Name: "A", Type: Query}, http.MethodPost, "/graphql", "application/json; charset=utf-8", `{"query":"query A { a }"}`},
+		{"Multipart", GraphQLData{Count: 1, Name: "Upload", Type: Mutation}, http.MethodPost, "/graphql", "multipart/form-data; boundary=boundary", multipartBody},
+		{"Multipart batch", GraphQLData{Count: 2, Name: "B", Type: Mutation}, http.MethodPost, "/graphql", `multipart/form-data; boundary="boundary"`, "--boundary\r\nContent-Disposition: form-data; name=\"operations\"\r\n\r\n" + `[{"query":"query A { a }"},{"query":"mutation B { b }"}]` + "\r\n--boundary--\r\n"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			r.Header.Set("Content-Type", tc.contentType)
+
+			got, err := getGraphQLData(r, 512, nil)
+
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, *got)
+			body, _ := io.ReadAll(r.Body)
+			assert.Equal(t, tc.body, string(body))
+		})
+	}
+
+	t.Run("Multipart without operations", func(t *testing.T) {
+		body := "--boundary\r\nContent-Disposition: form-data; name=\"map\"\r\n\r\n{}\r\n--boundary--\r\n"
+		r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+		r.Header.Set("Content-Type", "multipart/form-data; boundary=boundary")
+
+		got, err := getGraphQLData(r, 512, nil)
+
+		assert.Nil(t, got)
+		assert.EqualError(t, err, "error while reading request body: EOF")
+	})
+
+	t.Run("Multipart without boundary", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(multipartBody))
+		r.Header.Set("Content-Type", "multipart/form-data")
+
+		_, err := getGraphQLData(r, 512, nil)
+
+		assert.EqualError(t, err, "error while reading request body: multipart boundary not found")
+	})
+}
+
+func TestEvaluate_GraphQLEndpointPattern(t *testing.T) {
+	// the Protection API reports the GraphQL operation of the request in the response
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		w.Header().Set("X-Datadomeresponse", "200")
+		w.Header().Set("X-Datadome-Request-Headers", "X-GraphQL-Operation")
+		w.Header().Set("X-GraphQL-Operation", r.PostForm.Get("GraphQLOperationType")+" "+r.PostForm.Get("GraphQLOperationName"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer api.Close()
+
+	client, err := NewClient("your-api-key",
+		WithEndpoint(api.URL),
+		WithGraphQLSupport(true),
+		WithGraphQLEndpointPattern(`^/query$`),
+	)
+	assert.Nil(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/query?query=mutation+Pay+%7B+pay+%7D", nil)
+
+	decision, err := client.Evaluate(r)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "mutation Pay", decision.RequestHeaders.Get("X-GraphQL-Operation"))
+
+	t.Run("Invalid pattern", func(t *testing.T) {
+		_, err := NewClient("your-api-key", WithGraphQLEndpointPattern("("))
+
+		assert.ErrorContains(t, err, "GraphQLEndpointPattern must be a valid RegExp: ")
+	})
+
+	t.Run("Empty pattern", func(t *testing.T) {
+		client, err := NewClient("your-api-key", WithGraphQLEndpointPattern(""))
+
+		assert.Nil(t, err)
+		assert.Equal(t, DefaultGraphQLEndpointPatternValue, client.current().graphQLEndpoint.String())
+	})
+}
+
+func TestEvaluate_PersistedQueriesOnRoutes(t *testing.T) {
+	// the Protection API reports the GraphQL operation of the request in the response
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		w.Header().Set("X-Datadomeresponse", "200")
+		w.Header().Set("X-Datadome-Request-Headers", "X-GraphQL-Operation")
+		w.Header().Set("X-GraphQL-Operation", r.PostForm.Get("GraphQLOperationType")+" "+r.PostForm.Get("GraphQLOperationName"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer api.Close()
+
+	hash := strings.Repeat("a", 64)
+	client, err := NewClient("your-api-key",
+		WithEndpoint(api.URL),
+		WithGraphQLSupport(true),
+		WithRoutes(Route{PathPrefix: "/graphql", Action: RouteMonitor}),
+		WithGraphQLPersistedQueries(map[string]PersistedQuery{hash: {Name: "LoginV2", Type: Mutation}}),
+	)
+	assert.Nil(t, err)
+
+	body := `{"extensions":{"persistedQuery":{"version":1,"sha256Hash":"` + hash + `"}}}`
+	r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+
+	decision, err := client.Evaluate(r)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "mutation LoginV2", decision.RequestHeaders.Get("X-GraphQL-Operation"))
+}
+
+func TestContextWithGraphQLData(t *testing.T) {
+	client, err := NewClient("your-api-key", WithGraphQLSupport(true))
+	assert.Nil(t, err)
+
+	// the operation of the context is reported instead of the one of the body
+	r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"query A { a }"}`))
+	r.Header.Set("Content-Type", "application/json")
+	r = r.WithContext(ContextWithGraphQLData(r.Context(), GraphQLData{Type: Mutation, Name: "Pay", Count: 2}))
+
+	payload, err := client.Payload(r)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "mutation", payload.Get("GraphQLOperationType"))
+	assert.Equal(t, "Pay", payload.Get("GraphQLOperationName"))
+	assert.Equal(t, "2", payload.Get("GraphQLOperationCount"))
+
+	t.Run("GraphQL support disabled", func(t *testing.T) {
+		client, err := NewClient("your-api-key")
+		assert.Nil(t, err)
+
+		payload, err := client.Payload(r)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "Pay", payload.Get("GraphQLOperationName"))
+	})
+}
+
+func TestParseGraphQLDocument(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []graphQLOperation
+		err   string
+	}{
+		{input: "", want: nil},
+		{input: "\uFEFF# only a comment", want: nil},
+		{input: "{ a } , { b }", want: []graphQLOperation{{Type: Query}, {Type: Query}}},
+		{input: "subscription OnEvent($id: ID! = 1.5e3) @live { event(id: $id) { ... on Click { x } } }", want: []graphQLOperation{{Type: Subscription, Name: "OnEvent"}}},
+		{input: "fragment F on T { a } query Q { ...F }", want: []graphQLOperation{{Type: Query, Name: "Q"}}},
+		{input: "query Q", err: "selection set expected at 7"},
+		{input: "query Q { a(text: \"}\n\") }", err: "unterminated string at 18"},
+		{input: "query Q { a(text: \"\"\"", err: "unterminated block string at 18"},
+		{input: "fragment F { a }", err: "invalid fragment definition at 11"},
+		{input: "query Q @ { a }", err: "invalid directive at 10"},
+		{input: "query Q { a.b }", err: "unexpected '.' at 11"},
+		{input: "query Q { a ~ }", err: "unexpected character '~' at 12"},
+		{input: "type Query { a: Int }", err: `unexpected "type" at 0`},
+	}
+
+	for _, tc := range tests {
+		got, err := parseGraphQLDocument(tc.input)
+
+		if tc.err != "" {
+			assert.EqualError(t, err, tc.err, tc.input)
+			continue
+		}
+		assert.Nil(t, err, tc.input)
+		assert.Equal(t, tc.want, got, tc.input)
+	}
+}
+
+func FuzzParseGraphQLDocument(f *testing.F) {
+	for _, seed := range []string{
+		"{ todos { title } }",
+		"query Q($a: [Int!] = [1, 2]) @d(x: {y: \"}\"}) { a(b: \"\"\"c\\\"\"\"\"\"\") { ...F } }",
+		"fragment F on T { a } mutation M { b } subscription S { c }",
+		"# comment\r\n{ a }",
+		"query Q { a(b: -1.5E+3) }",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, document string) {
+		operations, err := parseGraphQLDocument(document)
+		if err != nil {
+			return
+		}
+		// every operation has a selection set
+		if len(operations) > strings.Count(document, "{") {
+			t.Errorf("%d operations found in %q", len(operations), document)
+		}
+		for _, operation := range operations {
+			if operation.Type != Query && operation.Type != Mutation && operation.Type != Subscription {
+				t.Errorf("invalid operation type %q in %q", operation.Type, document)
+			}
+		}
+	})
+}
+
+func FuzzDecodeGraphQLBody(f *testing.F) {
+	for _, seed := range []string{
+		`{"query":"{ todos { title } }"}`,
+		`{"operationName":"A","variables":{"a":[1,{"b":null}]},"query":"query A { a }"}`,
+		`{"query":null,"operationName":{}}`,
+		`[{"query":"{ a }"},{"extensions":{"persistedQuery":{"sha256Hash":"abc"}}}]`,
+		`{"query":"query A { a }"`,
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, body string) {
+		r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+
+		got, err := getGraphQLData(r, 256, nil)
+
+		if (got == nil) == (err == nil) {
+			t.Errorf("unexpected result %v, %v for %q", got, err, body)
+		}
+		if got != nil && got.Count < 0 {
+			t.Errorf("negative count for %q", body)
+		}
+		restored, _ := io.ReadAll(r.Body)
+		if string(restored) != body {
+			t.Errorf("body not restored: %q instead of %q", restored, body)
+		}
+	})
+}
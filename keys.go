@@ -0,0 +1,159 @@
+package modulego
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrServerSideKeyRejected is returned when the Protection API rejects the server-side key.
+var ErrServerSideKeyRejected = errors.New("server-side key rejected by the Protection API")
+
+// KeyEvent describes a change or a use of the server-side keys, to be reported as a metric for instance.
+type KeyEvent string
+
+const (
+	// KeyEventRotated is emitted when the server-side key of the [Client] changes.
+	KeyEventRotated KeyEvent = "rotated"
+	// KeyEventFallback is emitted when the server-side key is rejected and the secondary key is used instead.
+	KeyEventFallback KeyEvent = "fallback"
+)
+
+// KeySource returns the server-side key to use, read from a mounted secret or a secret manager for instance.
+// The returned errors must not contain the key.
+type KeySource func() (string, error)
+
+// FileKeySource returns a [KeySource] reading the key from the file at path.
+// The surrounding whitespaces are trimmed.
+func FileKeySource(path string) KeySource {
+	return func() (string, error) {
+		return readServerSideKeyFile(path)
+	}
+}
+
+// WithKeyEventHandler is a functional option to set a function called on each [KeyEvent].
+// The function is called synchronously and must not block.
+func WithKeyEventHandler(handler func(KeyEvent)) Option {
+	return func(c *Client) {
+		c.KeyEventHandler = handler
+	}
+}
+
+// WithSecondaryServerSideKey is a functional option to set the key used when the server-side key is rejected.
+// It is only used when the fallback is enabled with [WithServerSideKeyFallback].
+func WithSecondaryServerSideKey(secondaryServerSideKey string) Option {
+	return func(c *Client) {
+		c.SecondaryServerSideKey = secondaryServerSideKey
+	}
+}
+
+// withSecondaryServerSideKey is a functional option to set the secondary key of a [Config], keeping the current one when it is empty.
+func withSecondaryServerSideKey(secondaryServerSideKey string) Option {
+	return func(c *Client) {
+		if secondaryServerSideKey != "" {
+			c.SecondaryServerSideKey = secondaryServerSideKey
+		}
+	}
+}
+
+// WithServerSideKeyFallback is a functional option to enable the fallback to the secondary server-side key.
+// When the Protection API rejects the server-side key, the call is performed again with the secondary key.
+func WithServerSideKeyFallback(enableServerSideKeyFallback bool) Option {
+	return func(c *Client) {
+		c.EnableServerSideKeyFallback = enableServerSideKeyFallback
+	}
+}
+
+// rotateServerSideKey is a functional option to set the server-side key, the previous one becoming the secondary key.
+// Nothing is changed when the key is the current one.
+func rotateServerSideKey(serverSideKey string) Option {
+	return func(c *Client) {
+		if c.ServerSideKey != serverSideKey {
+			c.SecondaryServerSideKey = c.ServerSideKey
+			c.ServerSideKey = serverSideKey
+		}
+	}
+}
+
+// RotateServerSideKey switches to the new server-side key atomically.
+// The current key becomes the secondary key, used when the new one is rejected and the fallback is enabled with [WithServerSideKeyFallback].
+func (c *Client) RotateServerSideKey(serverSideKey string) error {
+	return c.Update(rotateServerSideKey(serverSideKey))
+}
+
+// WatchServerSideKey calls source every interval and rotates the server-side key with [Client.RotateServerSideKey] when it changes.
+// The errors of source are logged and the current key is kept.
+//
+// WatchServerSideKey blocks until ctx is done and returns its error.
+func (c *Client) WatchServerSideKey(ctx context.Context, source KeySource, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("interval must be a positive duration")
+	}
+	if _, err := c.load(); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		key, err := source()
+		if err != nil {
+			c.current().logger.Error("fail to read the server-side key, keeping the current one: ", err)
+			continue
+		}
+		if key == c.current().serverSideKey {
+			continue
+		}
+		if err := c.RotateServerSideKey(key); err != nil {
+			c.current().logger.Error("fail to rotate the server-side key, keeping the current one: ", err)
+		}
+	}
+}
+
+// keyEvent calls the [KeyEvent] handler, if any.
+func (s *settings) keyEvent(event KeyEvent) {
+	if s.keyEventHandler != nil {
+		s.keyEventHandler(event)
+	}
+}
+
+// isKeyRejection indicates whether the status code of a Protection API response without the `X-DataDomeResponse` header
+// means that the server-side key was rejected.
+func isKeyRejection(statusCode int) bool {
+	return statusCode == http.StatusBadRequest || statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden
+}
+
+// withKey returns the encoded payload with the server-side key replaced.
+func withKey(queryStr string, serverSideKey string) string {
+	values, err := url.ParseQuery(queryStr)
+	if err != nil {
+		return queryStr
+	}
+	values.Set("Key", serverSideKey)
+	return values.Encode()
+}
+
+// readServerSideKeyFile returns the server-side key read from the file at path.
+// The errors do not contain the content of the file.
+func readServerSideKeyFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("ServerSideKeyFile must be readable: %w", err)
+	}
+	key := strings.TrimSpace(string(b))
+	if key == "" {
+		return "", fmt.Errorf("ServerSideKeyFile must not be empty")
+	}
+	return key, nil
+}
@@ -0,0 +1,226 @@
+package modulego
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setupKeyAPI starts a fake Protection API rejecting the given key with a 403 response without `X-DataDomeResponse` header.
+// The keys of the received payloads are returned by the keys function.
+func setupKeyAPI(t *testing.T, rejectedKey string) (endpoint string, keys func() []string) {
+	var mu sync.Mutex
+	var received []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		key := r.PostForm.Get("Key")
+		mu.Lock()
+		received = append(received, key)
+		mu.Unlock()
+
+		if key == rejectedKey {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("X-Datadomeresponse", "200")
+	}))
+	t.Cleanup(api.Close)
+	return api.URL, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), received...)
+	}
+}
+
+// recordKeyEvents returns an option recording the key events, and a function returning them.
+func recordKeyEvents() (Option, func() []KeyEvent) {
+	var mu sync.Mutex
+	var events []KeyEvent
+	option := WithKeyEventHandler(func(event KeyEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	})
+	return option, func() []KeyEvent {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]KeyEvent(nil), events...)
+	}
+}
+
+func TestRotateServerSideKey(t *testing.T) {
+	logger := &syncLogger{}
+	withEvents, events := recordKeyEvents()
+	client, err := NewClient("old-key", WithLogger(logger), withEvents)
+	assert.Nil(t, err)
+
+	t.Run("With a new key", func(t *testing.T) {
+		err := client.RotateServerSideKey("new-key")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "new-key", client.current().serverSideKey)
+		assert.Equal(t, "old-key", client.current().secondaryServerSideKey)
+		assert.Equal(t, []KeyEvent{KeyEventRotated}, events())
+		assert.Equal(t, "INFO DataDome server-side key rotated", logger.last())
+	})
+
+	t.Run("With the current key", func(t *testing.T) {
+		err := client.RotateServerSideKey("new-key")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "old-key", client.current().secondaryServerSideKey)
+		assert.Equal(t, []KeyEvent{KeyEventRotated}, events())
+	})
+
+	t.Run("With an empty key", func(t *testing.T) {
+		err := client.RotateServerSideKey("")
+
+		assert.EqualError(t, err, "ServerSideKey must be defined")
+		assert.Equal(t, "new-key", client.current().serverSideKey)
+	})
+
+	for _, message := range logger.messages {
+		assert.NotContains(t, message, "old-key")
+		assert.NotContains(t, message, "new-key")
+	}
+}
+
+func TestServerSideKeyFallback(t *testing.T) {
+	endpoint, keys := setupKeyAPI(t, "new-key")
+
+	t.Run("With the fallback enabled", func(t *testing.T) {
+		withEvents, events := recordKeyEvents()
+		client, err := NewClient("new-key",
+			WithEndpoint(endpoint),
+			WithLogger(&syncLogger{}),
+			WithSecondaryServerSideKey("old-key"),
+			WithServerSideKeyFallback(true),
+			withEvents,
+		)
+		assert.Nil(t, err)
+		before := len(keys())
+
+		decision, err := client.Evaluate(httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, decision.StatusCode)
+		assert.Equal(t, []string{"new-key", "old-key"}, keys()[before:])
+		assert.Equal(t, []KeyEvent{KeyEventFallback}, events())
+	})
+
+	t.Run("With the fallback disabled", func(t *testing.T) {
+		logger := &syncLogger{}
+		client, err := NewClient("new-key",
+			WithEndpoint(endpoint),
+			WithLogger(logger),
+			WithSecondaryServerSideKey("old-key"),
+		)
+		assert.Nil(t, err)
+		before := len(keys())
+
+		decision, err := client.Evaluate(httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+		assert.Nil(t, decision)
+		assert.True(t, errors.Is(err, ErrServerSideKeyRejected))
+		assert.NotContains(t, err.Error(), "new-key")
+		assert.NotContains(t, logger.last(), "new-key")
+		assert.Equal(t, []string{"new-key"}, keys()[before:])
+	})
+
+	t.Run("With a valid key", func(t *testing.T) {
+		withEvents, events := recordKeyEvents()
+		client, err := NewClient("old-key",
+			WithEndpoint(endpoint),
+			WithSecondaryServerSideKey("new-key"),
+			WithServerSideKeyFallback(true),
+			withEvents,
+		)
+		assert.Nil(t, err)
+		before := len(keys())
+
+		_, err = client.Evaluate(httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"old-key"}, keys()[before:])
+		assert.Empty(t, events())
+	})
+}
+
+func TestFileKeySource(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("With a key file", func(t *testing.T) {
+		path := filepath.Join(dir, "key")
+		assert.Nil(t, os.WriteFile(path, []byte("  file-key\n"), 0o600))
+
+		key, err := FileKeySource(path)()
+
+		assert.Nil(t, err)
+		assert.Equal(t, "file-key", key)
+	})
+
+	t.Run("With an empty file", func(t *testing.T) {
+		path := filepath.Join(dir, "empty")
+		assert.Nil(t, os.WriteFile(path, []byte("\n"), 0o600))
+
+		_, err := FileKeySource(path)()
+
+		assert.EqualError(t, err, "ServerSideKeyFile must not be empty")
+	})
+
+	t.Run("With a missing file", func(t *testing.T) {
+		_, err := FileKeySource(filepath.Join(dir, "missing"))()
+
+		assert.ErrorContains(t, err, "ServerSideKeyFile must be readable")
+	})
+}
+
+func TestWatchServerSideKey(t *testing.T) {
+	logger := &syncLogger{}
+	client, err := NewClient("old-key", WithLogger(logger))
+	assert.Nil(t, err)
+
+	var mu sync.Mutex
+	key, keyErr := "new-key", error(nil)
+	source := func() (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return key, keyErr
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- client.WatchServerSideKey(ctx, source, 5*time.Millisecond)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return client.current().serverSideKey == "new-key"
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "old-key", client.current().secondaryServerSideKey)
+
+	// the settings are not updated while the key is unchanged
+	s := client.current()
+	time.Sleep(30 * time.Millisecond)
+	assert.Same(t, s, client.current())
+
+	mu.Lock()
+	key, keyErr = "", errors.New("secret unavailable")
+	mu.Unlock()
+	assert.Eventually(t, func() bool {
+		return strings.HasSuffix(logger.last(), "fail to read the server-side key, keeping the current one: secret unavailable")
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "new-key", client.current().serverSideKey)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
@@ -0,0 +1,270 @@
+package modulego
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// uriMatcher reports whether a URI matches the UrlPatternExclusion or the UrlPatternInclusion.
+// It is implemented by [*regexp.Regexp] and by the faster matchers of the common patterns.
+type uriMatcher interface {
+	MatchString(s string) bool
+	String() string
+}
+
+// compileURIPattern returns the matcher of the pattern.
+// Extension lists, such as [DefaultUrlPatternExclusionValue], and literal prefixes, such as `^/(static|assets)/`,
+// are matched without the regular expression engine.
+func compileURIPattern(pattern string) (uriMatcher, error) {
+	if m, ok := parseExtensionPattern(pattern); ok {
+		return m, nil
+	}
+	if m, ok := parsePrefixPattern(pattern); ok {
+		return m, nil
+	}
+	return regexp.Compile(pattern)
+}
+
+// maxExtensionLength is the maximum length of an extension matched by [extensionMatcher].
+const maxExtensionLength = 16
+
+// extensionMatcher matches the URIs ending with a dot followed by one of the extensions,
+// like the `\.(css|js)$` pattern.
+// The case-insensitive matching folds the URI like [regexp] does, see [foldASCII].
+type extensionMatcher struct {
+	pattern         string
+	caseInsensitive bool
+	extensions      map[string]struct{}
+}
+
+// parseExtensionPattern returns the [extensionMatcher] of the pattern if it has the form `(?i)\.(ext1|ext2)$`,
+// where the case-insensitive flag and the group are optional.
+func parseExtensionPattern(pattern string) (*extensionMatcher, bool) {
+	p, caseInsensitive := strings.CutPrefix(pattern, "(?i)")
+	p, ok := strings.CutPrefix(p, `\.`)
+	if !ok {
+		return nil, false
+	}
+	p, ok = strings.CutSuffix(p, "$")
+	if !ok {
+		return nil, false
+	}
+	alternatives, ok := cutGroup(p)
+	if !ok {
+		return nil, false
+	}
+
+	m := &extensionMatcher{
+		pattern:         pattern,
+		caseInsensitive: caseInsensitive,
+		extensions:      map[string]struct{}{},
+	}
+	for _, ext := range alternatives {
+		if ext == "" || len(ext) > maxExtensionLength || !isAlphanumeric(ext) {
+			return nil, false
+		}
+		if caseInsensitive {
+			ext = strings.ToLower(ext)
+		}
+		m.extensions[ext] = struct{}{}
+	}
+	return m, true
+}
+
+// MatchString reports whether the URI ends with one of the extensions.
+func (m *extensionMatcher) MatchString(uri string) bool {
+	i := strings.LastIndexByte(uri, '.')
+	if i < 0 {
+		return false
+	}
+	ext := uri[i+1:]
+	if !m.caseInsensitive {
+		if len(ext) == 0 || len(ext) > maxExtensionLength {
+			return false
+		}
+		_, ok := m.extensions[ext]
+		return ok
+	}
+
+	var buf [maxExtensionLength]byte
+	n := 0
+	for j := 0; j < len(ext); {
+		r, size := decodeRune(ext[j:])
+		c, ok := foldASCII(r)
+		if !ok || n == maxExtensionLength {
+			return false
+		}
+		buf[n] = c
+		n++
+		j += size
+	}
+	_, ok := m.extensions[string(buf[:n])]
+	return ok
+}
+
+// String returns the source pattern.
+func (m *extensionMatcher) String() string {
+	return m.pattern
+}
+
+// prefixMatcher matches the URIs starting with one of the prefixes, like the `^/(static|assets)/` pattern.
+// The case-insensitive matching folds the URI like [regexp] does, see [foldASCII].
+type prefixMatcher struct {
+	pattern         string
+	caseInsensitive bool
+	prefixes        []string
+}
+
+// parsePrefixPattern returns the [prefixMatcher] of the pattern if it has the form `(?i)^(/prefix1|/prefix2)`,
+// where the case-insensitive flag and the group are optional and the prefixes are literals.
+// The case-insensitive prefixes must be ASCII and are stored in lower case.
+func parsePrefixPattern(pattern string) (*prefixMatcher, bool) {
+	p, caseInsensitive := strings.CutPrefix(pattern, "(?i)")
+	p, ok := strings.CutPrefix(p, "^")
+	if !ok {
+		return nil, false
+	}
+	alternatives, ok := cutGroup(p)
+	if !ok {
+		return nil, false
+	}
+
+	m := &prefixMatcher{
+		pattern:         pattern,
+		caseInsensitive: caseInsensitive,
+	}
+	for _, alternative := range alternatives {
+		prefix, ok := unquoteLiteral(alternative)
+		if !ok || prefix == "" {
+			return nil, false
+		}
+		if caseInsensitive {
+			if !isASCII(prefix) {
+				return nil, false
+			}
+			prefix = strings.ToLower(prefix)
+		}
+		m.prefixes = append(m.prefixes, prefix)
+	}
+	return m, true
+}
+
+// MatchString reports whether the URI starts with one of the prefixes.
+func (m *prefixMatcher) MatchString(uri string) bool {
+	for _, prefix := range m.prefixes {
+		if m.caseInsensitive {
+			if hasFoldedPrefix(uri, prefix) {
+				return true
+			}
+		} else if strings.HasPrefix(uri, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasFoldedPrefix reports whether the URI starts with the lower case ASCII prefix once folded by [foldASCII].
+func hasFoldedPrefix(uri string, prefix string) bool {
+	i := 0
+	for j := 0; j < len(prefix); j++ {
+		if i == len(uri) {
+			return false
+		}
+		r, size := decodeRune(uri[i:])
+		if c, ok := foldASCII(r); !ok || c != prefix[j] {
+			return false
+		}
+		i += size
+	}
+	return true
+}
+
+// String returns the source pattern.
+func (m *prefixMatcher) String() string {
+	return m.pattern
+}
+
+// cutGroup returns the alternatives of a pattern made of a single group, such as `(a|b)` or `(?:a|b)`.
+// A pattern without group is returned as the single alternative when it does not contain an alternation.
+func cutGroup(p string) ([]string, bool) {
+	if inner, ok := strings.CutPrefix(p, "("); ok {
+		inner = strings.TrimPrefix(inner, "?:")
+		inner, ok = strings.CutSuffix(inner, ")")
+		if !ok || strings.ContainsAny(inner, "()") {
+			return nil, false
+		}
+		return strings.Split(inner, "|"), true
+	}
+	if strings.ContainsAny(p, "()|") {
+		return nil, false
+	}
+	return []string{p}, true
+}
+
+// unquoteLiteral returns the string matched by a regular expression made of literal characters only,
+// where the punctuation may be escaped.
+func unquoteLiteral(p string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch {
+		case c == '\\':
+			if i+1 == len(p) || isAlphanumeric(p[i+1:i+2]) {
+				return "", false
+			}
+			i++
+			b.WriteByte(p[i])
+		case strings.IndexByte(`.+*?()|[]{}^$`, c) >= 0:
+			return "", false
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), true
+}
+
+// foldASCII returns the lower case ASCII character matching r case-insensitively, if any.
+// Like the Unicode folding of [regexp], the Kelvin sign matches `k` and the long s matches `s`.
+func foldASCII(r rune) (byte, bool) {
+	switch {
+	case 'A' <= r && r <= 'Z':
+		return byte(r) + 'a' - 'A', true
+	case r < utf8.RuneSelf:
+		return byte(r), true
+	case r == '\u212A':
+		return 'k', true
+	case r == '\u017F':
+		return 's', true
+	}
+	return 0, false
+}
+
+// decodeRune returns the first rune of s and its size, without decoding the ASCII characters.
+func decodeRune(s string) (rune, int) {
+	if s[0] < utf8.RuneSelf {
+		return rune(s[0]), 1
+	}
+	return utf8.DecodeRuneInString(s)
+}
+
+// isASCII reports whether s only contains ASCII characters.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// isAlphanumeric reports whether s only contains ASCII letters and digits.
+func isAlphanumeric(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,142 @@
+package modulego
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var matcherURIs = []string{
+	"",
+	"/",
+	".",
+	"/ping",
+	"/picture.jpg",
+	"/picture.JPG",
+	"/picture.jpg/",
+	"/picture.jpgx",
+	"/archive.tar.gz",
+	"/.css",
+	"/css",
+	"/static/site.js?v=1",
+	"/api/v1.2/users",
+	"www.example.com/map",
+	"www.example.com/index.map",
+	"/STATIC/logo.png",
+	"/static",
+	"/static/",
+	"/assets/fonts/a.woff2",
+	"/files/very.longextensionlongerthansixteen",
+	"/aſk",
+	"/AſK",
+	"/picture.ſvg",
+	"/picture.mKv",
+	"/picture.é",
+	"/ſtatic/",
+	"/été/",
+	"/\xffstatic",
+}
+
+func TestCompileURIPattern(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		expected string
+	}{
+		{DefaultUrlPatternExclusionValue, "*modulego.extensionMatcher"},
+		{`\.(css|js)$`, "*modulego.extensionMatcher"},
+		{`(?i)\.(?:css|js)$`, "*modulego.extensionMatcher"},
+		{`\.css$`, "*modulego.extensionMatcher"},
+		{`\.(css|)$`, "*regexp.Regexp"},
+		{`\.(css|j.)$`, "*regexp.Regexp"},
+		{`\.(css|js)`, "*regexp.Regexp"},
+		{`\.css$|\.js$`, "*regexp.Regexp"},
+		{`^/static/`, "*modulego.prefixMatcher"},
+		{`(?i)^(/static/|/assets/)`, "*modulego.prefixMatcher"},
+		{`^(?:/api\.v1|/api-v2)`, "*modulego.prefixMatcher"},
+		{`(?i)^/ask`, "*modulego.prefixMatcher"},
+		{`(?i)\.(svg|mkv)$`, "*modulego.extensionMatcher"},
+		{`^/été/`, "*modulego.prefixMatcher"},
+		{`(?i)^/été/`, "*regexp.Regexp"},
+		{`^/static/.*`, "*regexp.Regexp"},
+		{`^/static|/assets`, "*regexp.Regexp"},
+		{`^(/static|/assets)$`, "*regexp.Regexp"},
+		{`^/\d+/`, "*regexp.Regexp"},
+		{`/static/`, "*regexp.Regexp"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.pattern, func(t *testing.T) {
+			m, err := compileURIPattern(tc.pattern)
+
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, fmt.Sprintf("%T", m))
+			assert.Equal(t, tc.pattern, m.String())
+
+			// the matcher behaves like the regular expression
+			r := regexp.MustCompile(tc.pattern)
+			for _, uri := range matcherURIs {
+				assert.Equal(t, r.MatchString(uri), m.MatchString(uri), uri)
+			}
+		})
+	}
+
+	t.Run("With an invalid pattern", func(t *testing.T) {
+		_, err := compileURIPattern(`(invalid`)
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestURIMatcherAllocations(t *testing.T) {
+	for _, pattern := range []string{DefaultUrlPatternExclusionValue, `(?i)^(/static/|/assets/)`} {
+		m, err := compileURIPattern(pattern)
+		assert.Nil(t, err)
+
+		allocs := testing.AllocsPerRun(100, func() {
+			for _, uri := range matcherURIs {
+				m.MatchString(uri)
+			}
+		})
+		assert.Zero(t, allocs, pattern)
+	}
+}
+
+func BenchmarkUrlPatternExclusion(b *testing.B) {
+	uris := []string{"/picture.JPG", "/api/v1/users", "/static/site.css", "/index.html"}
+	m, _ := compileURIPattern(DefaultUrlPatternExclusionValue)
+	matchers := map[string]uriMatcher{
+		"Regexp":    regexp.MustCompile(DefaultUrlPatternExclusionValue),
+		"Extension": m,
+	}
+
+	for _, name := range []string{"Regexp", "Extension"} {
+		b.Run(name, func(b *testing.B) {
+			matcher := matchers[name]
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				matcher.MatchString(uris[i%len(uris)])
+			}
+		})
+	}
+}
+
+func BenchmarkUrlPatternPrefix(b *testing.B) {
+	const pattern = `(?i)^(/static/|/assets/|/images/)`
+	uris := []string{"/Images/logo.png", "/api/v1/users", "/static/site.css", "/index.html"}
+	m, _ := compileURIPattern(pattern)
+	matchers := map[string]uriMatcher{
+		"Regexp": regexp.MustCompile(pattern),
+		"Prefix": m,
+	}
+
+	for _, name := range []string{"Regexp", "Prefix"} {
+		b.Run(name, func(b *testing.B) {
+			matcher := matchers[name]
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				matcher.MatchString(uris[i%len(uris)])
+			}
+		})
+	}
+}
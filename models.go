@@ -1,14 +1,20 @@
 package modulego
 
 import (
+	"crypto/x509"
 	"net/http"
-	"regexp"
+	"sync"
+	"sync/atomic"
 )
 
 const (
+	DefaultBypassHeaderValue              = "X-DataDome-Bypass"
+	DefaultBypassWindowValue              = 30
+	DefaultDenyStatusCodeValue            = http.StatusForbidden
 	DefaultEnableGraphQLSupportValue      = false
 	DefaultEnableReferrerRestorationValue = false
 	DefaultEndpointValue                  = "api.datadome.co"
+	DefaultGraphQLEndpointPatternValue    = "graphql"
 	DefaultMaximumBodySizeValue           = 25 * 1024
 	DefaultModuleNameValue                = "Golang"
 	DefaultModuleVersionValue             = "2.2.0"
@@ -20,24 +26,95 @@ const (
 
 // Client is used to interract with the DataDome's Protection API.
 // This structure contains all the informations specified through the [Option]'s functions.
+//
+// The exported fields keep the values the Client was created with:
+// the settings changed with [Client.Update] are not reflected in them.
+//
+// A Client must be created with [NewClient] or [NewClientFromConfig]:
+// the methods of a Client declared as a struct literal return [ErrClientNotInitialized] instead of processing the requests.
 type Client struct {
-	EnableGraphQLSupport      bool
-	EnableReferrerRestoration bool
-	Endpoint                  string
-	Logger                    Logger
-	MaximumBodySize           int
-	ModuleName                string
-	ModuleVersion             string
-	ServerSideKey             string
-	Timeout                   int
-	UrlPatternInclusion       string
-	UrlPatternExclusion       string
-	UseXForwardedHost         bool
+	AllowList                   []string
+	AllowListFile               string
+	BypassClientCAs             *x509.CertPool
+	BypassHeader                string
+	BypassSecret                string
+	BypassWindow                int
+	DenyBody                    string
+	DenyList                    []string
+	DenyListFile                string
+	DenyStatusCode              int
+	EnableGraphQLSupport        bool
+	EnableReferrerRestoration   bool
+	EnableServerSideKeyFallback bool
+	Endpoint                    string
+	FallbackRateLimit           *FallbackRateLimit
+	GraphQLEndpointPattern      string
+	GraphQLPersistedQueries     map[string]PersistedQuery
+	GraphQLWebSocketMessages    int
+	KeyEventHandler             func(KeyEvent)
+	Logger                      Logger
+	MaximumBodySize             int
+	ModuleName                  string
+	ModuleVersion               string
+	Routes                      []Route
+	SecondaryServerSideKey      string
+	ServerSideKey               string
+	SkipRules                   []SkipRule
+	Timeout                     int
+	Transport                   http.RoundTripper
+	UrlPatternInclusion         string
+	UrlPatternExclusion         string
+	UseXForwardedHost           bool
 
+	// mu serializes the calls to [Client.Update].
+	mu sync.Mutex
+	// settings holds the current *settings.
+	settings atomic.Value
+}
+
+// settings is an immutable snapshot of the configuration of a [Client].
+// A request is processed with a single snapshot, even when the settings are updated meanwhile.
+type settings struct {
+	allowListFile               string
+	bypassClientCAs             *x509.CertPool
+	bypassHeader                string
+	bypassSecret                string
+	bypassWindow                int
+	denyBody                    string
+	denyListFile                string
+	denyStatusCode              int
+	enableGraphQLSupport        bool
+	enableReferrerRestoration   bool
+	enableServerSideKeyFallback bool
+	fallbackRateLimit           *FallbackRateLimit
+	graphQLPersistedQueries     map[string]PersistedQuery
+	graphQLWebSocketMessages    int
+	keyEventHandler             func(KeyEvent)
+	logger                      Logger
+	maximumBodySize             int
+	moduleName                  string
+	moduleVersion               string
+	rawAllowList                []string
+	rawDenyList                 []string
+	rawEndpoint                 string
+	rawRoutes                   []Route
+	rawSkipRules                []SkipRule
+	secondaryServerSideKey      string
+	serverSideKey               string
+	timeout                     int
+	transport                   http.RoundTripper
+	useXForwardedHost           bool
+
+	allowList           *accessList
+	denyList            *accessList
 	endpoint            string
+	graphQLEndpoint     uriMatcher
 	httpClient          *http.Client
-	urlPatternExclusion *regexp.Regexp
-	urlPatternInclusion *regexp.Regexp
+	rateLimiter         *rateLimiter
+	routes              *routeIndex
+	skipRules           []*compiledSkipRule
+	urlPatternExclusion uriMatcher
+	urlPatternInclusion uriMatcher
 }
 
 // OperationType describes the expected operations values for a GraphQL query.
@@ -56,6 +133,55 @@ type GraphQLData struct {
 	Count int
 }
 
+// SkipReason describes why a request was not sent to the Protection API.
+type SkipReason string
+
+const (
+	// SkipReasonAllowList is used when the IP of the request matches the AllowList.
+	// The matching entry is in [Decision.ListEntry].
+	SkipReasonAllowList           SkipReason = "AllowList"
+	SkipReasonUrlPatternExclusion SkipReason = "UrlPatternExclusion"
+	SkipReasonUrlPatternInclusion SkipReason = "UrlPatternInclusion"
+	// SkipReasonHostNotRouted is used by [MultiClient] when no [Client] matches the host of the request.
+	SkipReasonHostNotRouted SkipReason = "HostNotRouted"
+	// SkipReasonBypass is used when the request is signed with the bypass secret or presents a client certificate of the bypass CAs.
+	SkipReasonBypass SkipReason = "Bypass"
+	// SkipReasonRoute is used when the request matches a [Route] with the [RouteSkip] action.
+	SkipReasonRoute SkipReason = "Route"
+	// SkipReasonSkipRule is used when the request matches a [SkipRule], whose name is in [Decision.SkipRule].
+	SkipReasonSkipRule SkipReason = "SkipRule"
+)
+
+// Decision describes the outcome of the validation of a request returned by [Client.Evaluate].
+type Decision struct {
+	// Blocked indicates that the request must not reach the application.
+	Blocked bool
+	// StatusCode is the status code returned by the Protection API.
+	// It is 0 when the Protection API was not called.
+	StatusCode int
+	// Monitored indicates that the request should have been blocked but matches a [Route] with the [RouteMonitor] action.
+	// Blocked is then false.
+	Monitored bool
+	// Denied indicates that the request is blocked because its IP or its client ID matches the DenyList.
+	// The Protection API is not called.
+	Denied bool
+	// RateLimited indicates that the request is blocked by the [FallbackRateLimit] because the Protection API call failed.
+	RateLimited bool
+	// SkipReason indicates why the request was not sent to the Protection API.
+	// It is empty when the request has been evaluated.
+	SkipReason SkipReason
+	// SkipRule is the name of the [SkipRule] matching the request.
+	SkipRule string
+	// ListEntry is the entry of the AllowList or of the DenyList matching the request.
+	ListEntry string
+	// Body is the content of the response to send when the request is blocked.
+	Body []byte
+	// RequestHeaders lists the headers to add to the request before it reaches the application.
+	RequestHeaders http.Header
+	// ResponseHeaders lists the headers to add to the response sent to the client.
+	ResponseHeaders http.Header
+}
+
 // ProtectionAPIRequestPayload is used to construct the payload that will be send to the Protection API
 type ProtectionAPIRequestPayload struct {
 	Key                    string        `url:"Key"`
@@ -0,0 +1,225 @@
+package modulego
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var _ Protector = (*MultiClient)(nil)
+
+// MultiClient routes the requests to a [Client] selected by the host of the request,
+// so that several DataDome accounts can be protected by a single middleware.
+// Each [Client] holds the settings of its hosts: server-side key, endpoint, inclusion and exclusion patterns, GraphQL support...
+//
+// The host patterns are matched in the following order, without case sensitivity and without the port:
+// 1. Exact hosts, such as `www.example.com`
+// 2. Wildcard hosts, such as `*.example.com` matching the subdomains of `example.com`, the longest suffix first
+// 3. Regular expressions, in the order they were added
+//
+// The default client is used when no pattern matches.
+// Without default client, the requests of unknown hosts are not validated.
+type MultiClient struct {
+	DefaultClient     *Client
+	UseXForwardedHost bool
+
+	exactHosts    map[string]*Client
+	wildcardHosts []hostSuffix
+	regexpHosts   []hostRegexp
+	errs          []error
+}
+
+// hostSuffix routes the hosts ending with suffix to client.
+type hostSuffix struct {
+	suffix string
+	client *Client
+}
+
+// hostRegexp routes the hosts matching pattern to client.
+type hostRegexp struct {
+	pattern *regexp.Regexp
+	client  *Client
+}
+
+// MultiClientOption is a functional option to configure a [MultiClient].
+type MultiClientOption func(*MultiClient)
+
+// WithDefaultClient is a functional option to set the [Client] used when no host pattern matches.
+func WithDefaultClient(client *Client) MultiClientOption {
+	return func(m *MultiClient) {
+		m.DefaultClient = client
+	}
+}
+
+// WithHost is a functional option to route the requests of the hosts matching pattern to client.
+// The pattern is either an exact host, such as `www.example.com`, or a wildcard, such as `*.example.com`.
+func WithHost(pattern string, client *Client) MultiClientOption {
+	return func(m *MultiClient) {
+		if client == nil {
+			m.errs = append(m.errs, fmt.Errorf("client of host %q must be defined", pattern))
+			return
+		}
+		host, wildcard, err := parseHostPattern(pattern)
+		if err != nil {
+			m.errs = append(m.errs, err)
+			return
+		}
+		if wildcard {
+			suffix := host[1:]
+			for _, w := range m.wildcardHosts {
+				if w.suffix == suffix {
+					m.errs = append(m.errs, fmt.Errorf("host %q must not be routed twice", pattern))
+					return
+				}
+			}
+			m.wildcardHosts = append(m.wildcardHosts, hostSuffix{suffix: suffix, client: client})
+			return
+		}
+		if _, ok := m.exactHosts[host]; ok {
+			m.errs = append(m.errs, fmt.Errorf("host %q must not be routed twice", pattern))
+			return
+		}
+		m.exactHosts[host] = client
+	}
+}
+
+// WithHostRegexp is a functional option to route the requests of the hosts matching the regular expression to client.
+// The host is lowercased and does not contain the port.
+func WithHostRegexp(pattern string, client *Client) MultiClientOption {
+	return func(m *MultiClient) {
+		if client == nil {
+			m.errs = append(m.errs, fmt.Errorf("client of host %q must be defined", pattern))
+			return
+		}
+		r, err := regexp.Compile(pattern)
+		if err != nil {
+			m.errs = append(m.errs, fmt.Errorf("host %q must be a valid RegExp: %w", pattern, err))
+			return
+		}
+		m.regexpHosts = append(m.regexpHosts, hostRegexp{pattern: r, client: client})
+	}
+}
+
+// WithMultiClientXForwardedHost is a functional option to indicate to use the X-Forwarded-Host header first to select the [Client],
+// as [WithXForwardedHost] does for the payload.
+func WithMultiClientXForwardedHost(useXForwardedHost bool) MultiClientOption {
+	return func(m *MultiClient) {
+		m.UseXForwardedHost = useXForwardedHost
+	}
+}
+
+// NewMultiClient instantiates a new [MultiClient] routing the requests with the host patterns given through [MultiClientOption] functions.
+// It returns an error listing every invalid pattern.
+func NewMultiClient(options ...MultiClientOption) (*MultiClient, error) {
+	m := &MultiClient{
+		exactHosts: map[string]*Client{},
+	}
+	for _, opt := range options {
+		opt(m)
+	}
+	if len(m.errs) > 0 {
+		return nil, &ConfigError{Errors: m.errs}
+	}
+
+	// the most specific wildcard is matched first
+	sort.SliceStable(m.wildcardHosts, func(i, j int) bool {
+		return len(m.wildcardHosts[i].suffix) > len(m.wildcardHosts[j].suffix)
+	})
+	return m, nil
+}
+
+// Client returns the [Client] matching the host of the request.
+// It returns nil when no pattern matches and no default client is defined.
+func (m *MultiClient) Client(r *http.Request) *Client {
+	host := r.Host
+	if m.UseXForwardedHost {
+		host = getHost(r)
+	}
+	host = normalizeHost(host)
+
+	if c, ok := m.exactHosts[host]; ok {
+		return c
+	}
+	for _, w := range m.wildcardHosts {
+		if strings.HasSuffix(host, w.suffix) {
+			return w.client
+		}
+	}
+	for _, h := range m.regexpHosts {
+		if h.pattern.MatchString(host) {
+			return h.client
+		}
+	}
+	return m.DefaultClient
+}
+
+// Evaluate validates the incoming request with the [Client] matching its host and returns the resulting [Decision].
+// When no client matches, the request is not validated and the [Decision] has the [SkipReasonHostNotRouted] reason.
+func (m *MultiClient) Evaluate(r *http.Request) (*Decision, error) {
+	c := m.Client(r)
+	if c == nil {
+		return &Decision{SkipReason: SkipReasonHostNotRouted}, nil
+	}
+	return c.Evaluate(r)
+}
+
+// handler validates the incoming request with the [Client] matching its host.
+// The requests of unknown hosts are sent to next when no default client is defined.
+func (m *MultiClient) handler(w http.ResponseWriter, r *http.Request, next http.Handler) (bool, error) {
+	c := m.Client(r)
+	if c == nil {
+		if next != nil {
+			next.ServeHTTP(w, r)
+		}
+		return false, nil
+	}
+	return c.handler(w, r, next)
+}
+
+// DatadomeHandler implements the [http.Handler] interface
+func (m *MultiClient) DatadomeHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := m.handler(w, r, next)
+		if err != nil {
+			panic(err)
+		}
+	})
+}
+
+// DatadomeProtect validates the incoming request
+func (m *MultiClient) DatadomeProtect(rw http.ResponseWriter, r *http.Request) (isBlocked bool, err error) {
+	return m.handler(rw, r, nil)
+}
+
+// parseHostPattern returns the normalized host pattern and whether it is a wildcard such as `*.example.com`.
+// An error is returned when the pattern is neither an exact host nor a wildcard.
+func parseHostPattern(pattern string) (string, bool, error) {
+	host := normalizeHost(pattern)
+	if strings.HasPrefix(host, "*.") && !strings.Contains(host[1:], "*") {
+		return host, true, nil
+	}
+	if host == "" || strings.Contains(host, "*") {
+		return "", false, fmt.Errorf("host %q must be an exact host or a wildcard", pattern)
+	}
+	return host, false, nil
+}
+
+// matchHostPattern reports whether the normalized host matches the pattern returned by [parseHostPattern].
+func matchHostPattern(pattern string, wildcard bool, host string) bool {
+	if wildcard {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+	return host == pattern
+}
+
+// normalizeHost returns the lowercased host without the port.
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
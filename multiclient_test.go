@@ -0,0 +1,154 @@
+package modulego
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClient(t *testing.T, serverSideKey string, options ...Option) *Client {
+	client, err := NewClient(serverSideKey, options...)
+	assert.Nil(t, err)
+	return client
+}
+
+func TestMultiClient_Client(t *testing.T) {
+	exact := newTestClient(t, "exact")
+	wildcard := newTestClient(t, "wildcard")
+	subWildcard := newTestClient(t, "sub-wildcard")
+	regexp := newTestClient(t, "regexp")
+	fallback := newTestClient(t, "default")
+	m, err := NewMultiClient(
+		WithHostRegexp(`^shop-[a-z]+\.example\.org$`, regexp),
+		WithHost("*.example.com", wildcard),
+		WithHost("*.eu.example.com", subWildcard),
+		WithHost("WWW.example.com", exact),
+		WithDefaultClient(fallback),
+	)
+	assert.Nil(t, err)
+
+	testCases := []struct {
+		host     string
+		expected *Client
+	}{
+		{"www.example.com", exact},
+		{"www.example.com:8443", exact},
+		{"WWW.EXAMPLE.COM.", exact},
+		{"api.example.com", wildcard},
+		{"a.b.example.com", wildcard},
+		{"shop.eu.example.com", subWildcard},
+		{"example.com", fallback},
+		{"shop-fr.example.org", regexp},
+		{"shop-fr.example.org:80", regexp},
+		{"shop-42.example.org", fallback},
+		{"[::1]:8080", fallback},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.host, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Host = tc.host
+
+			assert.Same(t, tc.expected, m.Client(r))
+		})
+	}
+
+	t.Run("With X-Forwarded-Host", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Host = "internal.local"
+		r.Header.Set("X-Forwarded-Host", "www.example.com")
+
+		assert.Same(t, fallback, m.Client(r))
+
+		m.UseXForwardedHost = true
+		defer func() { m.UseXForwardedHost = false }()
+		assert.Same(t, exact, m.Client(r))
+	})
+
+	t.Run("Without default client", func(t *testing.T) {
+		m, err := NewMultiClient(WithHost("www.example.com", exact))
+		assert.Nil(t, err)
+
+		assert.Nil(t, m.Client(httptest.NewRequest(http.MethodGet, "http://unknown.com/", nil)))
+	})
+}
+
+func TestNewMultiClient(t *testing.T) {
+	client := newTestClient(t, "your-api-key")
+
+	m, err := NewMultiClient(
+		WithHost("www.example.com", client),
+		WithHost("WWW.EXAMPLE.COM", client),
+		WithHost("*.", client),
+		WithHost("www.*.com", client),
+		WithHost("*.example.com", nil),
+		WithHostRegexp(`(invalid`, client),
+		WithMultiClientXForwardedHost(true),
+	)
+
+	assert.Nil(t, m)
+	var configErr *ConfigError
+	assert.True(t, errors.As(err, &configErr))
+	assert.Equal(t, []string{
+		`host "WWW.EXAMPLE.COM" must not be routed twice`,
+		`host "*." must be an exact host or a wildcard`,
+		`host "www.*.com" must be an exact host or a wildcard`,
+		`client of host "*.example.com" must be defined`,
+		"host \"(invalid\" must be a valid RegExp: error parsing regexp: missing closing ): `(invalid`",
+	}, errorMessages(configErr.Errors))
+}
+
+func TestMultiClient_DatadomeHandler(t *testing.T) {
+	// the Protection API blocks the requests of the brand-a account only
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.PostForm.Get("Key") == "brand-a" {
+			w.Header().Set("X-Datadomeresponse", "403")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = io.WriteString(w, "blocked")
+			return
+		}
+		w.Header().Set("X-Datadomeresponse", "200")
+	}))
+	defer api.Close()
+
+	m, err := NewMultiClient(
+		WithHost("brand-a.com", newTestClient(t, "brand-a", WithEndpoint(api.URL))),
+		WithHost("brand-b.com", newTestClient(t, "brand-b", WithEndpoint(api.URL))),
+	)
+	assert.Nil(t, err)
+	handler := m.DatadomeHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "upstream")
+	}))
+
+	testCases := []struct {
+		url          string
+		expectedCode int
+		expectedBody string
+	}{
+		{"http://brand-a.com/", http.StatusForbidden, "blocked"},
+		{"http://brand-b.com/", http.StatusOK, "upstream"},
+		{"http://brand-a.com/picture.jpg", http.StatusOK, "upstream"},
+		{"http://unknown.com/", http.StatusOK, "upstream"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.url, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.url, nil))
+
+			assert.Equal(t, tc.expectedCode, w.Code)
+			assert.Equal(t, tc.expectedBody, w.Body.String())
+		})
+	}
+
+	t.Run("Evaluate an unknown host", func(t *testing.T) {
+		decision, err := m.Evaluate(httptest.NewRequest(http.MethodGet, "http://unknown.com/", nil))
+
+		assert.Nil(t, err)
+		assert.Equal(t, SkipReasonHostNotRouted, decision.SkipReason)
+	})
+}
@@ -0,0 +1,58 @@
+// Package traefik is a Traefik middleware plugin protecting the services with DataDome.
+//
+// The plugin is interpreted by Yaegi: it must only rely on the standard library and on the modulego package,
+// which must not use the generic functions of the standard library, such as the ones of the slices package.
+//
+// The manifest of the plugin (.traefik.yml) is at the root of the repository, the plugin module being
+// github.com/andynuge/datadome-go. Example of static configuration:
+//
+//	experimental:
+//	  plugins:
+//	    datadome:
+//	      moduleName: github.com/andynuge/datadome-go
+//	      version: vX.Y.Z # release tag of the repository
+//
+// In local mode, the repository is copied to ./plugins-local/src/github.com/andynuge/datadome-go
+// and declared in experimental.localPlugins with the same moduleName.
+//
+// Example of dynamic configuration:
+//
+//	http:
+//	  middlewares:
+//	    datadome:
+//	      plugin:
+//	        datadome:
+//	          serverSideKey: your-server-side-key
+//	          timeout: 150
+//	          urlPatternInclusion: "^/(api|account)/"
+//
+// The URL patterns are matched against the path and the query of the requests:
+// the host is not part of the URI of the requests received by Traefik.
+package traefik
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	modulego "github.com/andynuge/datadome-go"
+)
+
+// Config is the configuration of the plugin.
+// The fields of [modulego.Config] are set with the keys of its JSON encoding, such as `serverSideKey`.
+type Config = modulego.Config
+
+// CreateConfig returns the default configuration of the plugin.
+func CreateConfig() *Config {
+	return modulego.DefaultConfig()
+}
+
+// New returns the middleware validating the requests with DataDome before calling next.
+// It behaves as [modulego.Client.DatadomeHandler].
+func New(ctx context.Context, next http.Handler, config *Config, name string) (http.Handler, error) {
+	client, err := modulego.NewClientFromConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return client.DatadomeHandler(next), nil
+}
@@ -0,0 +1,93 @@
+package traefik
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	modulego "github.com/andynuge/datadome-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupAPI starts a fake Protection API blocking the requests to the /blocked path.
+func setupAPI(t *testing.T) string {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.PostForm.Get("Request") == "/blocked" {
+			w.Header().Set("X-Datadomeresponse", "403")
+			w.Header().Set("X-Datadome-Headers", "Set-Cookie")
+			w.Header().Set("Set-Cookie", "datadome=blocked")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = io.WriteString(w, "blocked")
+			return
+		}
+		w.Header().Set("X-Datadomeresponse", "200")
+		w.Header().Set("X-Datadome-Headers", "X-Datadome")
+		w.Header().Set("X-Datadome", "protected")
+	}))
+	t.Cleanup(api.Close)
+	return api.URL + "/validate-request"
+}
+
+func TestNew(t *testing.T) {
+	endpoint := setupAPI(t)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "next")
+	})
+
+	config := CreateConfig()
+	config.ServerSideKey = "your-api-key"
+	config.Endpoint = endpoint
+	plugin, err := New(context.Background(), next, config, "datadome")
+	assert.Nil(t, err)
+
+	client, err := modulego.NewClient("your-api-key", modulego.WithEndpoint(endpoint))
+	assert.Nil(t, err)
+	reference := client.DatadomeHandler(next)
+
+	for _, path := range []string{"/ping", "/blocked", "/picture.jpg"} {
+		t.Run(path, func(t *testing.T) {
+			want := httptest.NewRecorder()
+			reference.ServeHTTP(want, httptest.NewRequest(http.MethodGet, path, nil))
+			got := httptest.NewRecorder()
+			plugin.ServeHTTP(got, httptest.NewRequest(http.MethodGet, path, nil))
+
+			assert.Equal(t, want.Code, got.Code)
+			assert.Equal(t, want.Header(), got.Header())
+			assert.Equal(t, want.Body.String(), got.Body.String())
+		})
+	}
+
+	t.Run("Settings of the module configuration", func(t *testing.T) {
+		config := CreateConfig()
+		config.ServerSideKey = "your-api-key"
+		config.Endpoint = endpoint
+		config.DenyList = []string{"192.0.2.1"}
+		config.DenyStatusCode = http.StatusTooManyRequests
+		plugin, err := New(context.Background(), next, config, "datadome")
+		assert.Nil(t, err)
+
+		got := httptest.NewRecorder()
+		plugin.ServeHTTP(got, httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+		assert.Equal(t, http.StatusTooManyRequests, got.Code)
+	})
+
+	t.Run("Invalid configuration", func(t *testing.T) {
+		plugin, err := New(context.Background(), next, CreateConfig(), "datadome")
+
+		assert.Nil(t, plugin)
+		assert.EqualError(t, err, "datadome: invalid configuration: ServerSideKey must be defined")
+	})
+}
+
+func TestCreateConfig(t *testing.T) {
+	config := CreateConfig()
+
+	assert.Equal(t, modulego.DefaultEndpointValue, config.Endpoint)
+	assert.Equal(t, modulego.DefaultTimeoutValue, config.Timeout)
+	assert.Equal(t, modulego.DefaultMaximumBodySizeValue, config.MaximumBodySize)
+	assert.Equal(t, modulego.DefaultUrlPatternExclusionValue, config.UrlPatternExclusion)
+}
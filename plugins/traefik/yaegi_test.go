@@ -0,0 +1,59 @@
+package traefik
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/traefik/yaegi/interp"
+	"github.com/traefik/yaegi/stdlib"
+)
+
+// TestYaegi loads the plugin with the Yaegi interpreter like Traefik does.
+func TestYaegi(t *testing.T) {
+	if testing.Short() {
+		t.Skip("the interpretation of the module is slow")
+	}
+	root, err := filepath.Abs(filepath.Join("..", ".."))
+	assert.Nil(t, err)
+	gopath := t.TempDir()
+	dir := filepath.Join(gopath, "src", "github.com", "andynuge")
+	assert.Nil(t, os.MkdirAll(dir, 0o755))
+	if err := os.Symlink(root, filepath.Join(dir, "datadome-go")); err != nil {
+		t.Skip("symbolic links are not supported: ", err)
+	}
+
+	i := interp.New(interp.Options{GoPath: gopath})
+	assert.Nil(t, i.Use(stdlib.Symbols))
+	_, err = i.Eval(`import "github.com/andynuge/datadome-go/plugins/traefik"`)
+	if !assert.Nil(t, err) {
+		return
+	}
+	createConfig, err := i.Eval("traefik.CreateConfig")
+	assert.Nil(t, err)
+	newPlugin, err := i.Eval("traefik.New")
+	assert.Nil(t, err)
+
+	config := createConfig.Call(nil)[0]
+	config.Elem().FieldByName("ServerSideKey").SetString("your-api-key")
+	config.Elem().FieldByName("Endpoint").SetString(setupAPI(t))
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "next")
+	})
+	results := newPlugin.Call([]reflect.Value{reflect.ValueOf(context.Background()), reflect.ValueOf(next), config, reflect.ValueOf("datadome")})
+	assert.True(t, results[1].IsNil())
+	plugin := results[0].Interface().(http.Handler)
+
+	for path, code := range map[string]int{"/ping": http.StatusOK, "/blocked": http.StatusForbidden, "/picture.jpg": http.StatusOK} {
+		rr := httptest.NewRecorder()
+		plugin.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+
+		assert.Equal(t, code, rr.Code, path)
+	}
+}
@@ -0,0 +1,90 @@
+// Command tyk is a Tyk Go plugin protecting the APIs with DataDome.
+//
+// Build the plugin with the same Go version and dependencies as the Tyk Gateway:
+//
+//	go build -buildmode=plugin -o datadome.so ./plugins/tyk
+//
+// Then declare the DatadomeMiddleware function as a `pre` custom middleware of the API definition:
+//
+//	"custom_middleware": {
+//	  "driver": "goplugin",
+//	  "pre": [{"name": "DatadomeMiddleware", "path": "/opt/tyk-gateway/middleware/datadome.so"}]
+//	}
+//
+// The plugin is configured with the environment variables of the gateway:
+//
+//	DATADOME_SERVER_SIDE_KEY                  DataDome server-side key (required)
+//	DATADOME_SERVER_SIDE_KEY_FILE             file containing the server-side key
+//	DATADOME_ENDPOINT                         Protection API endpoint
+//	DATADOME_TIMEOUT                          Protection API timeout in milliseconds
+//	DATADOME_MAXIMUM_BODY_SIZE                maximum body size read for GraphQL requests
+//	DATADOME_ENABLE_GRAPHQL_SUPPORT           enable the GraphQL support
+//	DATADOME_ENABLE_REFERRER_RESTORATION      enable the referrer restoration
+//	DATADOME_ENABLE_SERVER_SIDE_KEY_FALLBACK  use the secondary key when the server-side key is rejected
+//	DATADOME_SECONDARY_SERVER_SIDE_KEY        key used when the server-side key is rejected
+//	DATADOME_URL_PATTERN_EXCLUSION            regular expression of the excluded URLs
+//	DATADOME_URL_PATTERN_INCLUSION            regular expression of the included URLs
+//	DATADOME_USE_X_FORWARDED_HOST             use the X-Forwarded-Host header as host
+//	DATADOME_BYPASS_SECRET                    secret of the bypass signatures of the internal requests
+//	DATADOME_BYPASS_HEADER                    header containing the bypass signature
+//	DATADOME_BYPASS_WINDOW                    validity of the bypass signatures in seconds
+//	DATADOME_BYPASS_CLIENT_CA_FILE            file containing the CAs of the bypass client certificates
+//	DATADOME_ALLOW_LIST_FILE                  file listing the allowed IPs and CIDRs
+//	DATADOME_DENY_BODY                        body of the denied requests
+//	DATADOME_DENY_LIST_FILE                   file listing the denied IPs, CIDRs and client IDs
+//	DATADOME_DENY_STATUS_CODE                 status code of the denied requests
+//	DATADOME_GRAPHQL_ENDPOINT_PATTERN         regular expression of the GraphQL endpoint paths
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"sync"
+
+	modulego "github.com/andynuge/datadome-go"
+)
+
+var (
+	client    *modulego.Client
+	clientErr error
+	once      sync.Once
+)
+
+// getClient returns the client built from the environment variables on the first call.
+// The error of the first call is logged once.
+func getClient() (*modulego.Client, error) {
+	once.Do(func() {
+		client, clientErr = newClient(os.LookupEnv)
+		if clientErr != nil {
+			log.Println("[DataDome] ERROR: fail to instantiate the DataDome client, the requests are not protected:", clientErr)
+		}
+	})
+	return client, clientErr
+}
+
+// newClient returns a client configured with the `DATADOME_*` environment variables returned by lookupEnv.
+func newClient(lookupEnv func(string) (string, bool)) (*modulego.Client, error) {
+	config := modulego.DefaultConfig()
+	if err := config.ApplyEnv(lookupEnv); err != nil {
+		return nil, err
+	}
+	return modulego.NewClientFromConfig(config)
+}
+
+// DatadomeMiddleware is the entrypoint of the plugin.
+// Blocked requests receive the response of the Protection API, which stops the middleware chain of Tyk.
+// Allowed requests are enriched with the DataDome headers and continue to the upstream.
+// It behaves as [modulego.Client.DatadomeHandler].
+//
+// The middleware fails open: when the configuration is invalid, the error is logged on the first request
+// and every request continues to the upstream without being validated.
+func DatadomeMiddleware(rw http.ResponseWriter, r *http.Request) {
+	c, err := getClient()
+	if err != nil {
+		return
+	}
+	_, _ = c.DatadomeProtect(rw, r)
+}
+
+func main() {}
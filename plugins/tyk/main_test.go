@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	modulego "github.com/andynuge/datadome-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupAPI starts a fake Protection API blocking the requests to the /blocked path.
+func setupAPI(t *testing.T) string {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.PostForm.Get("Request") == "/blocked" {
+			w.Header().Set("X-Datadomeresponse", "403")
+			w.Header().Set("X-Datadome-Headers", "Set-Cookie")
+			w.Header().Set("Set-Cookie", "datadome=blocked")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = io.WriteString(w, "blocked")
+			return
+		}
+		w.Header().Set("X-Datadomeresponse", "200")
+		w.Header().Set("X-Datadome-Headers", "X-Datadome")
+		w.Header().Set("X-Datadome", "protected")
+		w.Header().Set("X-Datadome-Request-Headers", "X-Datadome-Botname")
+		w.Header().Set("X-Datadome-Botname", "none")
+	}))
+	t.Cleanup(api.Close)
+	return api.URL + "/validate-request"
+}
+
+// tykChain emulates the middleware chain of Tyk: the upstream is called unless the plugin wrote a response.
+type tykChain struct {
+	upstream http.Handler
+}
+
+func (c tykChain) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec := httptest.NewRecorder()
+	DatadomeMiddleware(rec, r)
+	for name, values := range rec.Header() {
+		w.Header()[name] = values
+	}
+	if rec.Code != http.StatusOK || rec.Body.Len() > 0 {
+		w.WriteHeader(rec.Code)
+		_, _ = w.Write(rec.Body.Bytes())
+		return
+	}
+	c.upstream.ServeHTTP(w, r)
+}
+
+func TestDatadomeMiddleware(t *testing.T) {
+	endpoint := setupAPI(t)
+	t.Setenv("DATADOME_SERVER_SIDE_KEY", "your-api-key")
+	t.Setenv("DATADOME_ENDPOINT", endpoint)
+
+	upstream := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "upstream "+r.Header.Get("X-Datadome-Botname"))
+	})
+
+	client, err := modulego.NewClient("your-api-key", modulego.WithEndpoint(endpoint))
+	assert.Nil(t, err)
+	reference := client.DatadomeHandler(upstream)
+	plugin := tykChain{upstream: upstream}
+
+	for _, path := range []string{"/ping", "/blocked", "/picture.jpg"} {
+		t.Run(path, func(t *testing.T) {
+			want := httptest.NewRecorder()
+			reference.ServeHTTP(want, httptest.NewRequest(http.MethodGet, path, nil))
+			got := httptest.NewRecorder()
+			plugin.ServeHTTP(got, httptest.NewRequest(http.MethodGet, path, nil))
+
+			assert.Equal(t, want.Code, got.Code)
+			assert.Equal(t, want.Header(), got.Header())
+			assert.Equal(t, want.Body.String(), got.Body.String())
+		})
+	}
+}
+
+func TestDatadomeMiddleware_InvalidConfiguration(t *testing.T) {
+	t.Setenv("DATADOME_SERVER_SIDE_KEY", "")
+	once = sync.Once{}
+	t.Cleanup(func() { once = sync.Once{} })
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	upstream := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "upstream")
+	})
+	plugin := tykChain{upstream: upstream}
+
+	for i := 0; i < 3; i++ {
+		got := httptest.NewRecorder()
+		plugin.ServeHTTP(got, httptest.NewRequest(http.MethodGet, "/blocked", nil))
+
+		// the requests fail open
+		assert.Equal(t, "upstream", got.Body.String())
+	}
+	assert.Equal(t, 1, strings.Count(logs.String(), "fail to instantiate the DataDome client"))
+}
+
+func TestNewClient(t *testing.T) {
+	env := map[string]string{
+		"DATADOME_SERVER_SIDE_KEY":        "your-api-key",
+		"DATADOME_TIMEOUT":                "300",
+		"DATADOME_ENABLE_GRAPHQL_SUPPORT": "true",
+	}
+	lookupEnv := func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+
+	client, err := newClient(lookupEnv)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "your-api-key", client.ServerSideKey)
+	assert.Equal(t, 300, client.Timeout)
+	assert.True(t, client.EnableGraphQLSupport)
+	assert.Equal(t, modulego.DefaultEndpointValue, client.Endpoint)
+
+	t.Run("Invalid value", func(t *testing.T) {
+		env["DATADOME_TIMEOUT"] = "fast"
+
+		client, err := newClient(lookupEnv)
+
+		assert.Nil(t, client)
+		assert.ErrorContains(t, err, "DATADOME_TIMEOUT must be an integer")
+	})
+}
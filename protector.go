@@ -0,0 +1,96 @@
+package modulego
+
+import (
+	"context"
+	"net/http"
+)
+
+// Protector is the interface implemented by [Client] and [MultiClient] to validate incoming requests.
+// Application code may depend on it instead of [*Client] to replace DataDome in unit tests.
+//
+// Methods:
+//   - Evaluate: Returns the [Decision] for the request without writing anything.
+//   - DatadomeHandler: Wraps an [http.Handler] that is not called when the request is blocked.
+//   - DatadomeProtect: Applies the [Decision] to the response and reports whether the request is blocked.
+type Protector interface {
+	Evaluate(r *http.Request) (*Decision, error)
+	DatadomeHandler(next http.Handler) http.Handler
+	DatadomeProtect(rw http.ResponseWriter, r *http.Request) (isBlocked bool, err error)
+}
+
+var _ Protector = (*Client)(nil)
+
+// EvaluatorFunc is an adapter to allow the use of ordinary functions as a [Protector].
+// DatadomeHandler and DatadomeProtect apply the [Decision] returned by the function like [Client] does.
+type EvaluatorFunc func(r *http.Request) (*Decision, error)
+
+// Evaluate calls f(r).
+func (f EvaluatorFunc) Evaluate(r *http.Request) (*Decision, error) {
+	return f(r)
+}
+
+// DatadomeHandler implements the [http.Handler] interface
+func (f EvaluatorFunc) DatadomeHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := protect(f, nil, w, r, next)
+		if err != nil {
+			panic(err)
+		}
+	})
+}
+
+// DatadomeProtect validates the incoming request
+func (f EvaluatorFunc) DatadomeProtect(rw http.ResponseWriter, r *http.Request) (isBlocked bool, err error) {
+	return protect(f, nil, rw, r, nil)
+}
+
+// decisionObserverKey is the context key of the function observing the evaluations of the handlers.
+type decisionObserverKey struct{}
+
+// ContextWithDecisionObserver returns a copy of ctx with a function called with the result of the evaluation
+// of the requests handled with this context by the DatadomeHandler and DatadomeProtect methods of the protectors
+// of this package, before the [Decision] is applied. The observers already present in ctx are called first.
+// It lets a decorator of a [Protector] observe its decisions while delegating the handling of the requests to it.
+func ContextWithDecisionObserver(ctx context.Context, observe func(r *http.Request, decision *Decision, err error)) context.Context {
+	if previous, ok := ctx.Value(decisionObserverKey{}).(func(*http.Request, *Decision, error)); ok {
+		next := observe
+		observe = func(r *http.Request, decision *Decision, err error) {
+			previous(r, decision, err)
+			next(r, decision, err)
+		}
+	}
+	return context.WithValue(ctx, decisionObserverKey{}, observe)
+}
+
+// protect evaluates the request and applies the resulting [Decision] to the response.
+//
+// When next is defined, it is called with the original response writer unless the request is blocked.
+// Errors are logged when a logger is defined and the request is allowed (fail open).
+func protect(evaluate func(*http.Request) (*Decision, error), logger Logger, w http.ResponseWriter, r *http.Request, next http.Handler) (bool, error) {
+	sendNext := func(isBlocked bool, err error) (bool, error) {
+		if next == nil {
+			return isBlocked, err
+		}
+		if !isBlocked {
+			next.ServeHTTP(w, r)
+		}
+		return isBlocked, nil
+	}
+
+	decision, err := evaluate(r)
+	if observe, ok := r.Context().Value(decisionObserverKey{}).(func(*http.Request, *Decision, error)); ok {
+		observe(r, decision, err)
+	}
+	if err != nil {
+		return sendNext(false, err)
+	}
+
+	err = decision.apply(w, r)
+	if err != nil {
+		if logger != nil {
+			logger.Error("error when writing the response: %v", err)
+		}
+		return sendNext(decision.Blocked, err)
+	}
+	return sendNext(decision.Blocked, nil)
+}
@@ -0,0 +1,102 @@
+package modulego
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluatorFunc(t *testing.T) {
+	nextCalled := false
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nextCalled = true
+		w.Header().Set("X-Botname", r.Header.Get("X-Datadome-Botname"))
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name           string
+		decision       *Decision
+		err            error
+		expectedStatus int
+		expectedNext   bool
+	}{
+		{
+			name: "Allowed request",
+			decision: &Decision{
+				StatusCode:      http.StatusOK,
+				RequestHeaders:  http.Header{"X-Datadome-Botname": {"none"}},
+				ResponseHeaders: http.Header{"Set-Cookie": {"datadome=abc"}},
+			},
+			expectedStatus: http.StatusOK,
+			expectedNext:   true,
+		},
+		{
+			name:           "Blocked request",
+			decision:       &Decision{Blocked: true, StatusCode: http.StatusForbidden, Body: []byte("blocked")},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Skipped request",
+			decision:       &Decision{SkipReason: SkipReasonUrlPatternExclusion},
+			expectedStatus: http.StatusOK,
+			expectedNext:   true,
+		},
+		{
+			name:           "Error",
+			err:            errors.New("unavailable"),
+			expectedStatus: http.StatusOK,
+			expectedNext:   true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var p Protector = EvaluatorFunc(func(r *http.Request) (*Decision, error) {
+				return tc.decision, tc.err
+			})
+
+			nextCalled = false
+			rw := httptest.NewRecorder()
+			p.DatadomeHandler(nextHandler).ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			assert.Equal(t, tc.expectedStatus, rw.Code)
+			assert.Equal(t, tc.expectedNext, nextCalled)
+			if tc.decision != nil {
+				assert.Equal(t, tc.decision.RequestHeaders.Get("X-Datadome-Botname"), rw.Header().Get("X-Botname"))
+				assert.Equal(t, tc.decision.ResponseHeaders.Get("Set-Cookie"), rw.Header().Get("Set-Cookie"))
+			}
+
+			rw = httptest.NewRecorder()
+			isBlocked, err := p.DatadomeProtect(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			assert.Equal(t, tc.err, err)
+			assert.Equal(t, !tc.expectedNext, isBlocked)
+			assert.Equal(t, tc.expectedStatus, rw.Code)
+		})
+	}
+}
+
+func TestContextWithDecisionObserver(t *testing.T) {
+	var observed []string
+	ctx := ContextWithDecisionObserver(context.Background(), func(r *http.Request, decision *Decision, err error) {
+		observed = append(observed, "outer "+r.URL.Path)
+	})
+	ctx = ContextWithDecisionObserver(ctx, func(r *http.Request, decision *Decision, err error) {
+		observed = append(observed, "inner "+r.URL.Path)
+		assert.True(t, decision.Blocked)
+	})
+	f := EvaluatorFunc(func(r *http.Request) (*Decision, error) {
+		return &Decision{Blocked: true, StatusCode: http.StatusForbidden}, nil
+	})
+	r := httptest.NewRequest(http.MethodGet, "/login", nil).WithContext(ctx)
+
+	isBlocked, err := f.DatadomeProtect(httptest.NewRecorder(), r)
+
+	assert.Nil(t, err)
+	assert.True(t, isBlocked)
+	assert.Equal(t, []string{"outer /login", "inner /login"}, observed)
+}
@@ -0,0 +1,176 @@
+package modulego
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimitKey describes how the sources of the requests are identified by the [FallbackRateLimit].
+type RateLimitKey string
+
+const (
+	// RateLimitByIP limits the requests of each IP.
+	RateLimitByIP RateLimitKey = "ip"
+	// RateLimitByClientID limits the requests of each client ID, or of each IP when the request has no client ID.
+	RateLimitByClientID RateLimitKey = "clientID"
+	// RateLimitByIPAndClientID limits the requests of each pair of IP and client ID.
+	RateLimitByIPAndClientID RateLimitKey = "ipAndClientID"
+)
+
+// DefaultRateLimitMaxSources is the default number of sources tracked by the [FallbackRateLimit].
+const DefaultRateLimitMaxSources = 10000
+
+// FallbackRateLimit describes the local token-bucket rate limiter applied while the Protection API calls fail.
+// The requests of a source exceeding the limit are blocked with a 429 status code instead of failing open.
+// The limiter is reset when the settings are updated with another limit.
+type FallbackRateLimit struct {
+	// Rate is the number of requests per second allowed for each source.
+	Rate float64 `json:"rate"`
+	// Burst is the number of requests a source may send at once.
+	// It defaults to the Rate, rounded up.
+	Burst int `json:"burst,omitempty"`
+	// Key defaults to [RateLimitByIP].
+	Key RateLimitKey `json:"key,omitempty"`
+	// MaxSources is the number of sources tracked at once, the least recently seen ones being evicted.
+	// It defaults to [DefaultRateLimitMaxSources].
+	MaxSources int `json:"maxSources,omitempty"`
+}
+
+// WithFallbackRateLimit is a functional option to limit the requests of each source while the Protection API calls fail.
+func WithFallbackRateLimit(limit FallbackRateLimit) Option {
+	return func(c *Client) {
+		c.FallbackRateLimit = &limit
+	}
+}
+
+// withFallbackRateLimit is a functional option to set the [FallbackRateLimit], or to remove it when limit is nil.
+func withFallbackRateLimit(limit *FallbackRateLimit) Option {
+	return func(c *Client) {
+		c.FallbackRateLimit = limit
+	}
+}
+
+// rateLimiter is a token-bucket rate limiter keeping the buckets of a bounded number of sources.
+type rateLimiter struct {
+	rate       float64
+	burst      float64
+	key        RateLimitKey
+	maxSources int
+
+	mu sync.Mutex
+	// sources lists the *tokenBucket from the most to the least recently seen.
+	sources *list.List
+	buckets map[string]*list.Element
+}
+
+// tokenBucket holds the tokens of a source at a given time.
+type tokenBucket struct {
+	source string
+	tokens float64
+	time   time.Time
+}
+
+// newRateLimiter validates the limit and returns the matching limiter, or nil when limit is nil.
+func newRateLimiter(limit *FallbackRateLimit) (*rateLimiter, []error) {
+	if limit == nil {
+		return nil, nil
+	}
+	var errs []error
+	l := &rateLimiter{
+		rate:       limit.Rate,
+		burst:      float64(limit.Burst),
+		key:        limit.Key,
+		maxSources: limit.MaxSources,
+		sources:    list.New(),
+		buckets:    map[string]*list.Element{},
+	}
+	if limit.Rate <= 0 || math.IsInf(limit.Rate, 0) || math.IsNaN(limit.Rate) {
+		errs = append(errs, fmt.Errorf("FallbackRateLimit.Rate must be a positive number"))
+	}
+	if limit.Burst < 0 {
+		errs = append(errs, fmt.Errorf("FallbackRateLimit.Burst must be a positive integer"))
+	} else if limit.Burst == 0 {
+		l.burst = math.Ceil(limit.Rate)
+	}
+	switch limit.Key {
+	case "":
+		l.key = RateLimitByIP
+	case RateLimitByIP, RateLimitByClientID, RateLimitByIPAndClientID:
+	default:
+		errs = append(errs, fmt.Errorf("FallbackRateLimit.Key must be one of %q, %q or %q: %q", RateLimitByIP, RateLimitByClientID, RateLimitByIPAndClientID, limit.Key))
+	}
+	if limit.MaxSources < 0 {
+		errs = append(errs, fmt.Errorf("FallbackRateLimit.MaxSources must be a positive integer"))
+	} else if limit.MaxSources == 0 {
+		l.maxSources = DefaultRateLimitMaxSources
+	}
+	return l, errs
+}
+
+// source returns the identifier of the source of the request.
+func (l *rateLimiter) source(r *http.Request) string {
+	ip, _ := getIP(r)
+	switch l.key {
+	case RateLimitByClientID:
+		if clientID := getClientId(r); clientID != "" {
+			return "clientID:" + clientID
+		}
+	case RateLimitByIPAndClientID:
+		return ip + " " + getClientId(r)
+	}
+	return ip
+}
+
+// allow consumes a token of the source at the given time.
+// It returns false and the delay before the next token when the bucket of the source is empty.
+func (l *rateLimiter) allow(source string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var b *tokenBucket
+	if e, ok := l.buckets[source]; ok {
+		l.sources.MoveToFront(e)
+		b = e.Value.(*tokenBucket)
+		if elapsed := now.Sub(b.time).Seconds(); elapsed > 0 {
+			b.tokens = math.Min(l.burst, b.tokens+elapsed*l.rate)
+		}
+		b.time = now
+	} else {
+		b = &tokenBucket{source: source, tokens: l.burst, time: now}
+		l.buckets[source] = l.sources.PushFront(b)
+		if l.sources.Len() > l.maxSources {
+			oldest := l.sources.Remove(l.sources.Back()).(*tokenBucket)
+			delete(l.buckets, oldest.source)
+		}
+	}
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// fallback returns the outcome of the request whose Protection API call failed with err.
+// The request is blocked when its source exceeds the [FallbackRateLimit], otherwise err is returned.
+func (s *settings) fallback(r *http.Request, err error) (*Decision, error) {
+	if s.rateLimiter == nil {
+		return nil, err
+	}
+	allowed, delay := s.rateLimiter.allow(s.rateLimiter.source(r), time.Now())
+	if allowed {
+		return nil, err
+	}
+	s.logger.Warn("FallbackRateLimit exceeded while the Protection API call fails, blocking.")
+	return &Decision{
+		Blocked:         true,
+		StatusCode:      http.StatusTooManyRequests,
+		RateLimited:     true,
+		ResponseHeaders: http.Header{"Retry-After": {strconv.Itoa(int(math.Ceil(delay.Seconds())))}},
+	}, nil
+}
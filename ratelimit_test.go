@@ -0,0 +1,246 @@
+package modulego
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	l, errs := newRateLimiter(&FallbackRateLimit{Rate: 2, Burst: 3})
+	assert.Empty(t, errs)
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		allowed, _ := l.allow("10.0.0.1", now)
+		assert.True(t, allowed, i)
+	}
+	allowed, delay := l.allow("10.0.0.1", now)
+	assert.False(t, allowed)
+	assert.Equal(t, 500*time.Millisecond, delay)
+
+	t.Run("Other source", func(t *testing.T) {
+		allowed, _ := l.allow("10.0.0.2", now)
+
+		assert.True(t, allowed)
+	})
+
+	t.Run("Refill", func(t *testing.T) {
+		allowed, _ := l.allow("10.0.0.1", now.Add(500*time.Millisecond))
+		assert.True(t, allowed)
+		allowed, _ = l.allow("10.0.0.1", now.Add(500*time.Millisecond))
+		assert.False(t, allowed)
+
+		// the bucket does not exceed the burst
+		for i := 0; i < 3; i++ {
+			allowed, _ = l.allow("10.0.0.1", now.Add(time.Hour))
+			assert.True(t, allowed, i)
+		}
+		allowed, _ = l.allow("10.0.0.1", now.Add(time.Hour))
+		assert.False(t, allowed)
+	})
+}
+
+func TestRateLimiterEviction(t *testing.T) {
+	l, errs := newRateLimiter(&FallbackRateLimit{Rate: 1, MaxSources: 2})
+	assert.Empty(t, errs)
+	now := time.Now()
+
+	l.allow("a", now)
+	l.allow("b", now)
+	l.allow("a", now)
+	// c evicts b, the least recently seen source
+	l.allow("c", now)
+
+	assert.Equal(t, 2, l.sources.Len())
+	assert.Len(t, l.buckets, 2)
+	assert.NotContains(t, l.buckets, "b")
+	allowed, _ := l.allow("b", now)
+	assert.True(t, allowed)
+	assert.NotContains(t, l.buckets, "a")
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		l, errs := newRateLimiter(&FallbackRateLimit{Rate: 0.5})
+
+		assert.Empty(t, errs)
+		assert.Equal(t, float64(1), l.burst)
+		assert.Equal(t, RateLimitByIP, l.key)
+		assert.Equal(t, DefaultRateLimitMaxSources, l.maxSources)
+	})
+
+	t.Run("Without limit", func(t *testing.T) {
+		l, errs := newRateLimiter(nil)
+
+		assert.Empty(t, errs)
+		assert.Nil(t, l)
+	})
+
+	t.Run("Invalid limit", func(t *testing.T) {
+		_, errs := newRateLimiter(&FallbackRateLimit{Rate: math.Inf(1), Burst: -1, Key: "host", MaxSources: -1})
+
+		assert.Equal(t, []string{
+			"FallbackRateLimit.Rate must be a positive number",
+			"FallbackRateLimit.Burst must be a positive integer",
+			`FallbackRateLimit.Key must be one of "ip", "clientID" or "ipAndClientID": "host"`,
+			"FallbackRateLimit.MaxSources must be a positive integer",
+		}, errorMessages(errs))
+	})
+}
+
+func TestRateLimiterSource(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-DataDome-ClientID", "client-id")
+	anonymous := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	anonymous.RemoteAddr = "10.0.0.1:1234"
+
+	testCases := []struct {
+		key       RateLimitKey
+		expected  string
+		anonymous string
+	}{
+		{RateLimitByIP, "10.0.0.1", "10.0.0.1"},
+		{RateLimitByClientID, "clientID:client-id", "10.0.0.1"},
+		{RateLimitByIPAndClientID, "10.0.0.1 client-id", "10.0.0.1 "},
+	}
+	for _, tc := range testCases {
+		t.Run(string(tc.key), func(t *testing.T) {
+			l, _ := newRateLimiter(&FallbackRateLimit{Rate: 1, Key: tc.key})
+
+			assert.Equal(t, tc.expected, l.source(r))
+			assert.Equal(t, tc.anonymous, l.source(anonymous))
+		})
+	}
+}
+
+func TestEvaluate_FallbackRateLimit(t *testing.T) {
+	// the Protection API fails while failing is set
+	var failing atomic.Bool
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("X-Datadomeresponse", "200")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer api.Close()
+
+	logger := &syncLogger{}
+	client, err := NewClient("your-api-key",
+		WithLogger(logger),
+		WithEndpoint(api.URL),
+		WithFallbackRateLimit(FallbackRateLimit{Rate: 0.5, Burst: 2}),
+		WithRoutes(Route{PathPrefix: "/api/", Action: RouteMonitor}, Route{PathPrefix: "/checkout", FailurePolicy: FailClosed}),
+	)
+	assert.Nil(t, err)
+
+	newRequest := func(path string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		return r
+	}
+
+	t.Run("Available Protection API", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			decision, err := client.Evaluate(newRequest("/ping"))
+
+			assert.Nil(t, err)
+			assert.False(t, decision.Blocked)
+		}
+	})
+
+	failing.Store(true)
+
+	t.Run("Failing Protection API", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			decision, err := client.Evaluate(newRequest("/ping"))
+
+			// the request fails open
+			assert.NotNil(t, err)
+			assert.Nil(t, decision)
+		}
+
+		decision, err := client.Evaluate(newRequest("/ping"))
+
+		assert.Nil(t, err)
+		assert.True(t, decision.Blocked)
+		assert.True(t, decision.RateLimited)
+		assert.Equal(t, http.StatusTooManyRequests, decision.StatusCode)
+		retryAfter, _ := strconv.Atoi(decision.ResponseHeaders.Get("Retry-After"))
+		assert.InDelta(t, 2, retryAfter, 1)
+		assert.Equal(t, "WARN FallbackRateLimit exceeded while the Protection API call fails, blocking.", logger.last())
+	})
+
+	t.Run("Monitored route", func(t *testing.T) {
+		decision, err := client.Evaluate(newRequest("/api/users"))
+
+		assert.Nil(t, err)
+		assert.True(t, decision.Blocked)
+		assert.True(t, decision.RateLimited)
+		assert.False(t, decision.Monitored)
+	})
+
+	t.Run("Route failing closed", func(t *testing.T) {
+		decision, err := client.Evaluate(newRequest("/checkout"))
+
+		assert.Nil(t, err)
+		assert.False(t, decision.RateLimited)
+		assert.Equal(t, http.StatusForbidden, decision.StatusCode)
+	})
+
+	t.Run("Limiter is kept by the updates with the same limit", func(t *testing.T) {
+		assert.Nil(t, client.Update(WithTimeout(200)))
+
+		decision, err := client.Evaluate(newRequest("/ping"))
+
+		assert.Nil(t, err)
+		assert.True(t, decision.RateLimited)
+	})
+
+	t.Run("Rate limited request is blocked by the handler", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		blocked, err := client.DatadomeProtect(rr, newRequest("/ping"))
+
+		assert.Nil(t, err)
+		assert.True(t, blocked)
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+	})
+
+	t.Run("Limit is decoded from the configuration", func(t *testing.T) {
+		c := DefaultConfig()
+		c.ServerSideKey = "your-api-key"
+		err := c.Decode(strings.NewReader(`{"fallbackRateLimit": {"rate": 10, "burst": 20, "key": "clientID"}}`))
+		assert.Nil(t, err)
+
+		client, err := NewClientFromConfig(c)
+
+		assert.Nil(t, err)
+		assert.Equal(t, &FallbackRateLimit{Rate: 10, Burst: 20, Key: RateLimitByClientID}, client.FallbackRateLimit)
+	})
+}
+
+func BenchmarkRateLimiterAllow(b *testing.B) {
+	l, _ := newRateLimiter(&FallbackRateLimit{Rate: 100, MaxSources: 1000})
+	sources := make([]string, 2000)
+	for i := range sources {
+		sources[i] = "10.0." + strconv.Itoa(i/256) + "." + strconv.Itoa(i%256)
+	}
+	now := time.Now()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.allow(sources[i%len(sources)], now)
+	}
+}
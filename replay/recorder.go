@@ -0,0 +1,128 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	modulego "github.com/andynuge/datadome-go"
+)
+
+// Recorder is an [http.RoundTripper] writing the exchanges with the Protection API as JSONL.
+// It is safe for concurrent use.
+type Recorder struct {
+	Logger         modulego.Logger
+	RedactedFields []string
+	Transport      http.RoundTripper
+
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// RecorderOption is a functional option to customize the [Recorder].
+type RecorderOption func(*Recorder)
+
+// WithLogger is a functional option to set a custom Logger for the Recorder.
+func WithLogger(logger modulego.Logger) RecorderOption {
+	return func(rec *Recorder) {
+		rec.Logger = logger
+	}
+}
+
+// WithRedactedFields is a functional option to redact payload fields in addition to [DefaultRedactedFields].
+func WithRedactedFields(fields ...string) RecorderOption {
+	return func(rec *Recorder) {
+		rec.RedactedFields = sortedFields(rec.RedactedFields, fields)
+	}
+}
+
+// WithTransport is a functional option to set the [http.RoundTripper] performing the calls.
+// The [http.DefaultTransport] is used by default.
+func WithTransport(transport http.RoundTripper) RecorderOption {
+	return func(rec *Recorder) {
+		rec.Transport = transport
+	}
+}
+
+// NewRecorder instantiates a new [Recorder] writing the exchanges to w.
+func NewRecorder(w io.Writer, options ...RecorderOption) *Recorder {
+	rec := &Recorder{
+		Logger:         modulego.NewDefaultLogger(),
+		RedactedFields: sortedFields(DefaultRedactedFields),
+		encoder:        json.NewEncoder(w),
+	}
+	for _, opt := range options {
+		opt(rec)
+	}
+	return rec
+}
+
+// RoundTrip performs the call and records the exchange.
+// Failures to record are logged and do not affect the call.
+func (rec *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("fail to read the request body: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	transport := rec.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	exchangeTime := time.Now()
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("fail to read the response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	payload, err := url.ParseQuery(string(body))
+	if err != nil {
+		rec.Logger.Warn("fail to parse the payload, the exchange is not recorded: ", err)
+		return resp, nil
+	}
+	for _, name := range rec.RedactedFields {
+		if payload.Has(name) {
+			payload.Set(name, RedactedValue)
+		}
+	}
+
+	exchange := Exchange{
+		Time: exchangeTime,
+		Request: Request{
+			Method:  req.Method,
+			URL:     req.URL.String(),
+			Header:  req.Header,
+			Payload: payload,
+		},
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header,
+			Body:       string(respBody),
+		},
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if err := rec.encoder.Encode(exchange); err != nil {
+		rec.Logger.Warn("fail to record the exchange: ", err)
+	}
+	return resp, nil
+}
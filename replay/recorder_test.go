@@ -0,0 +1,106 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	modulego "github.com/andynuge/datadome-go"
+	"github.com/andynuge/datadome-go/datadometest"
+	"github.com/stretchr/testify/assert"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(args ...interface{}) {}
+func (nopLogger) Info(args ...interface{})  {}
+func (nopLogger) Warn(args ...interface{})  {}
+func (nopLogger) Error(args ...interface{}) {}
+
+func newRequest(path, userAgent string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "http://example.org"+path, nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	r.Header.Set("User-Agent", userAgent)
+	return r
+}
+
+// record evaluates the requests through a Recorder and returns the JSONL output.
+func record(t *testing.T, requests []*http.Request, options ...RecorderOption) []byte {
+	t.Helper()
+	s := datadometest.NewServer(
+		datadometest.WithRule(datadometest.MatchUserAgent("BadBot"), datadometest.Block(http.StatusForbidden)),
+	)
+	defer s.Close()
+
+	buf := &bytes.Buffer{}
+	client, err := modulego.NewClient(
+		"secret-key",
+		modulego.WithEndpoint(s.Endpoint()),
+		modulego.WithLogger(nopLogger{}),
+		modulego.WithTransport(NewRecorder(buf, append([]RecorderOption{WithLogger(nopLogger{})}, options...)...)),
+	)
+	assert.Nil(t, err)
+	for _, r := range requests {
+		_, err := client.Evaluate(r)
+		assert.Nil(t, err)
+	}
+	return buf.Bytes()
+}
+
+func TestRecorder(t *testing.T) {
+	out := record(t, []*http.Request{newRequest("/", "Mozilla"), newRequest("/login", "BadBot/1.0")})
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	assert.Len(t, lines, 2)
+	assert.NotContains(t, string(out), "secret-key")
+
+	var e Exchange
+	assert.Nil(t, json.Unmarshal([]byte(lines[1]), &e))
+	assert.Equal(t, http.MethodPost, e.Request.Method)
+	assert.True(t, strings.HasSuffix(e.Request.URL, "/validate-request"))
+	assert.Equal(t, "DataDome", e.Request.Header.Get("User-Agent"))
+	assert.Equal(t, RedactedValue, e.Request.Payload.Get("Key"))
+	assert.Equal(t, "/login", e.Request.Payload.Get("Request"))
+	assert.Equal(t, "BadBot/1.0", e.Request.Payload.Get("UserAgent"))
+	assert.Equal(t, http.StatusForbidden, e.Response.StatusCode)
+	assert.Equal(t, "403", e.Response.Header.Get("X-Datadomeresponse"))
+	assert.Contains(t, e.Response.Body, "captcha")
+	assert.False(t, e.Time.IsZero())
+}
+
+func TestRecorderWithRedactedFields(t *testing.T) {
+	r := newRequest("/", "Mozilla")
+	r.AddCookie(&http.Cookie{Name: "datadome", Value: "client-id"})
+
+	out := record(t, []*http.Request{r}, WithRedactedFields("ClientID", "IP"))
+
+	var e Exchange
+	assert.Nil(t, json.Unmarshal(out, &e))
+	assert.Equal(t, RedactedValue, e.Request.Payload.Get("Key"))
+	assert.Equal(t, RedactedValue, e.Request.Payload.Get("ClientID"))
+	assert.Equal(t, RedactedValue, e.Request.Payload.Get("IP"))
+	assert.NotContains(t, string(out), "client-id")
+}
+
+func TestRecorderTransportError(t *testing.T) {
+	buf := &bytes.Buffer{}
+	rec := NewRecorder(buf, WithTransport(roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return nil, http.ErrHandlerTimeout
+	})))
+
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost/validate-request", strings.NewReader("Key=k"))
+	resp, err := rec.RoundTrip(req)
+
+	assert.Nil(t, resp)
+	assert.Equal(t, http.ErrHandlerTimeout, err)
+	assert.Empty(t, buf.String())
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
@@ -0,0 +1,100 @@
+// Package replay records the exchanges with the DataDome Protection API and replays them offline.
+//
+// The [Recorder] is an [http.RoundTripper] writing every payload sent by the [modulego.Client] and the
+// response of the Protection API to a JSONL file, one [Exchange] per line.
+// The server-side key is redacted before writing.
+//
+//	f, err := os.Create("exchanges.jsonl")
+//	recorder := replay.NewRecorder(f)
+//	client, err := modulego.NewClient(serverSideKey, modulego.WithTransport(recorder))
+//
+// The [Replayer] is an [http.RoundTripper] serving the recorded responses without network access,
+// allowing to build regression suites from production samples.
+//
+//	f, err := os.Open("exchanges.jsonl")
+//	replayer, err := replay.NewReplayer(f)
+//	client, err := modulego.NewClient("test-key", modulego.WithTransport(replayer))
+package replay
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// RedactedValue replaces the values of the redacted fields.
+	RedactedValue = "REDACTED"
+)
+
+var (
+	// DefaultRedactedFields are the payload fields redacted by the [Recorder].
+	DefaultRedactedFields = []string{"Key"}
+	// DefaultIgnoredFields are the payload fields ignored by the [Replayer] to match the exchanges,
+	// in addition to the redacted fields.
+	DefaultIgnoredFields = []string{"TimeRequest"}
+)
+
+// Exchange is a call to the Protection API.
+type Exchange struct {
+	Time     time.Time `json:"time"`
+	Request  Request   `json:"request"`
+	Response Response  `json:"response"`
+}
+
+// Request is the request sent to the Protection API.
+type Request struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Header  http.Header `json:"header,omitempty"`
+	Payload url.Values  `json:"payload"`
+}
+
+// Response is the response of the Protection API.
+type Response struct {
+	StatusCode int         `json:"status"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// unorderedListFields are the payload fields listing names in an order that changes between the runs,
+// the [http.Header] map being walked to build them.
+var unorderedListFields = []string{"HeadersList"}
+
+// matchingKey returns the key used to match the payloads, ignoring the given fields.
+// The names of the unordered list fields are sorted.
+func matchingKey(payload url.Values, ignoredFields []string) string {
+	values := url.Values{}
+	for name, v := range payload {
+		values[name] = v
+	}
+	for _, name := range ignoredFields {
+		values.Del(name)
+	}
+	for _, name := range unorderedListFields {
+		if list, ok := values[name]; ok && len(list) > 0 {
+			names := strings.Split(list[0], ",")
+			sort.Strings(names)
+			values.Set(name, strings.Join(names, ","))
+		}
+	}
+	return values.Encode()
+}
+
+// sortedFields returns the union of the given field lists, sorted.
+func sortedFields(lists ...[]string) []string {
+	seen := map[string]bool{}
+	var fields []string
+	for _, list := range lists {
+		for _, name := range list {
+			if !seen[name] {
+				seen[name] = true
+				fields = append(fields, name)
+			}
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
@@ -0,0 +1,146 @@
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+)
+
+// ErrNoExchange is returned by the [Replayer] when no recorded exchange matches the payload.
+var ErrNoExchange = errors.New("no recorded exchange matches the payload")
+
+// Replayer is an [http.RoundTripper] serving the responses of recorded exchanges.
+//
+// The payloads are matched on all their fields except the ignored ones ([DefaultIgnoredFields] and
+// [DefaultRedactedFields] by default), the order of the names of the HeadersList field being ignored.
+// Identical payloads receive the recorded responses in order,
+// the last one being repeated once they are exhausted.
+// It is safe for concurrent use.
+type Replayer struct {
+	IgnoredFields []string
+
+	mu        sync.Mutex
+	exchanges []Exchange
+	responses map[string][]Response
+}
+
+// ReplayerOption is a functional option to customize the [Replayer].
+type ReplayerOption func(*Replayer)
+
+// WithIgnoredFields is a functional option to ignore payload fields in addition to the default ones.
+func WithIgnoredFields(fields ...string) ReplayerOption {
+	return func(rep *Replayer) {
+		rep.IgnoredFields = sortedFields(rep.IgnoredFields, fields)
+	}
+}
+
+// NewReplayer instantiates a new [Replayer] serving the exchanges read from the JSONL reader.
+// It returns an error if a line is not a valid [Exchange].
+func NewReplayer(r io.Reader, options ...ReplayerOption) (*Replayer, error) {
+	exchanges, err := ReadExchanges(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayerFromExchanges(exchanges, options...), nil
+}
+
+// NewReplayerFromExchanges instantiates a new [Replayer] serving the given exchanges.
+func NewReplayerFromExchanges(exchanges []Exchange, options ...ReplayerOption) *Replayer {
+	rep := &Replayer{
+		IgnoredFields: sortedFields(DefaultIgnoredFields, DefaultRedactedFields),
+		exchanges:     exchanges,
+	}
+	for _, opt := range options {
+		opt(rep)
+	}
+
+	rep.responses = make(map[string][]Response, len(exchanges))
+	for _, e := range exchanges {
+		key := matchingKey(e.Request.Payload, rep.IgnoredFields)
+		rep.responses[key] = append(rep.responses[key], e.Response)
+	}
+	return rep
+}
+
+// Exchanges returns the exchanges served by the replayer.
+func (rep *Replayer) Exchanges() []Exchange {
+	return rep.exchanges
+}
+
+// RoundTrip returns the recorded response matching the payload of the request.
+// [ErrNoExchange] is returned when no exchange matches.
+func (rep *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("fail to read the request body: %w", err)
+		}
+	}
+	payload, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("fail to parse the payload: %w", err)
+	}
+	key := matchingKey(payload, rep.IgnoredFields)
+
+	rep.mu.Lock()
+	responses := rep.responses[key]
+	if len(responses) == 0 {
+		rep.mu.Unlock()
+		return nil, ErrNoExchange
+	}
+	resp := responses[0]
+	if len(responses) > 1 {
+		rep.responses[key] = responses[1:]
+	}
+	rep.mu.Unlock()
+
+	header := resp.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(resp.StatusCode) + " " + http.StatusText(resp.StatusCode),
+		StatusCode:    resp.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader([]byte(resp.Body))),
+		ContentLength: int64(len(resp.Body)),
+		Request:       req,
+	}, nil
+}
+
+// ReadExchanges reads the exchanges from a JSONL reader.
+// Empty lines are ignored.
+func ReadExchanges(r io.Reader) ([]Exchange, error) {
+	var exchanges []Exchange
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var e Exchange
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("invalid exchange on line %d: %w", line, err)
+		}
+		exchanges = append(exchanges, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("fail to read the exchanges: %w", err)
+	}
+	return exchanges, nil
+}
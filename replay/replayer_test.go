@@ -0,0 +1,133 @@
+package replay
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	modulego "github.com/andynuge/datadome-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func newReplayClient(t *testing.T, rep *Replayer) *modulego.Client {
+	t.Helper()
+	client, err := modulego.NewClient(
+		"another-key",
+		modulego.WithEndpoint("http://replay.invalid/validate-request"),
+		modulego.WithLogger(nopLogger{}),
+		modulego.WithTransport(rep),
+	)
+	assert.Nil(t, err)
+	return client
+}
+
+func TestReplayer(t *testing.T) {
+	out := record(t, []*http.Request{newRequest("/", "Mozilla"), newRequest("/login", "BadBot/1.0")})
+
+	rep, err := NewReplayer(bytes.NewReader(out))
+	assert.Nil(t, err)
+	assert.Len(t, rep.Exchanges(), 2)
+	client := newReplayClient(t, rep)
+
+	t.Run("Blocked request", func(t *testing.T) {
+		decision, err := client.Evaluate(newRequest("/login", "BadBot/1.0"))
+
+		assert.Nil(t, err)
+		assert.True(t, decision.Blocked)
+		assert.Equal(t, http.StatusForbidden, decision.StatusCode)
+		assert.Contains(t, decision.ResponseHeaders.Get("Set-Cookie"), "datadome=blocked")
+	})
+
+	t.Run("Allowed request replayed twice", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			decision, err := client.Evaluate(newRequest("/", "Mozilla"))
+
+			assert.Nil(t, err)
+			assert.False(t, decision.Blocked)
+			assert.Equal(t, http.StatusOK, decision.StatusCode)
+		}
+	})
+
+	t.Run("Unknown payload", func(t *testing.T) {
+		decision, err := client.Evaluate(newRequest("/unknown", "Mozilla"))
+
+		assert.Nil(t, decision)
+		assert.ErrorIs(t, err, ErrNoExchange)
+	})
+}
+
+func TestReplayerWithSeveralHeaders(t *testing.T) {
+	newRequest := func() *http.Request {
+		r := newRequest("/", "Mozilla")
+		r.Header.Set("Accept", "text/html")
+		r.Header.Set("Accept-Encoding", "gzip")
+		r.Header.Set("Accept-Language", "en")
+		r.Header.Set("Cache-Control", "no-cache")
+		r.Header.Set("Referer", "https://example.org/")
+		return r
+	}
+	out := record(t, []*http.Request{newRequest()})
+
+	rep, err := NewReplayer(bytes.NewReader(out))
+	assert.Nil(t, err)
+	client := newReplayClient(t, rep)
+
+	// the header list is built from a map, its order changes between the evaluations
+	for i := 0; i < 50; i++ {
+		decision, err := client.Evaluate(newRequest())
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, decision.StatusCode)
+	}
+}
+
+func TestReplayerOrder(t *testing.T) {
+	payload := url.Values{"Key": {RedactedValue}, "Request": {"/"}, "TimeRequest": {"1"}}
+	rep := NewReplayerFromExchanges([]Exchange{
+		{Request: Request{Payload: payload}, Response: Response{StatusCode: http.StatusOK}},
+		{Request: Request{Payload: payload}, Response: Response{StatusCode: http.StatusForbidden}},
+	})
+
+	roundTrip := func(body string) int {
+		req, _ := http.NewRequest(http.MethodPost, "http://replay.invalid", strings.NewReader(body))
+		resp, err := rep.RoundTrip(req)
+		assert.Nil(t, err)
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusOK, roundTrip("Key=k&Request=%2F&TimeRequest=2"))
+	assert.Equal(t, http.StatusForbidden, roundTrip("Request=%2F&TimeRequest=3&Key=k"))
+	// the last response is repeated
+	assert.Equal(t, http.StatusForbidden, roundTrip("Key=k&Request=%2F&TimeRequest=4"))
+}
+
+func TestReplayerWithIgnoredFields(t *testing.T) {
+	rep := NewReplayerFromExchanges([]Exchange{
+		{Request: Request{Payload: url.Values{"Request": {"/"}, "IP": {"192.0.2.1"}}}, Response: Response{StatusCode: http.StatusOK}},
+	}, WithIgnoredFields("IP"))
+
+	req, _ := http.NewRequest(http.MethodPost, "http://replay.invalid", strings.NewReader("Request=%2F&IP=198.51.100.1"))
+	resp, err := rep.RoundTrip(req)
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestReadExchanges(t *testing.T) {
+	t.Run("With empty lines", func(t *testing.T) {
+		exchanges, err := ReadExchanges(strings.NewReader(`{"request":{"payload":{"Request":["/"]}},"response":{"status":200}}` + "\n\n"))
+
+		assert.Nil(t, err)
+		assert.Len(t, exchanges, 1)
+		assert.Equal(t, http.StatusOK, exchanges[0].Response.StatusCode)
+	})
+
+	t.Run("With an invalid line", func(t *testing.T) {
+		exchanges, err := ReadExchanges(strings.NewReader("{}\nnot json\n"))
+
+		assert.Nil(t, exchanges)
+		assert.ErrorContains(t, err, "invalid exchange on line 2")
+	})
+}
@@ -0,0 +1,376 @@
+package modulego
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RouteAction describes how the requests matching a [Route] are processed.
+type RouteAction string
+
+const (
+	// RouteEnforce validates the requests with the Protection API and blocks them when required.
+	RouteEnforce RouteAction = "enforce"
+	// RouteMonitor validates the requests with the Protection API but never blocks them on its response.
+	// The failure policy and the FallbackRateLimit still apply when the Protection API call fails.
+	RouteMonitor RouteAction = "monitor"
+	// RouteSkip does not send the requests to the Protection API.
+	RouteSkip RouteAction = "skip"
+)
+
+// FailurePolicy describes how the requests are processed when the Protection API call fails.
+type FailurePolicy string
+
+const (
+	// FailOpen allows the request when the Protection API call fails.
+	FailOpen FailurePolicy = "open"
+	// FailClosed blocks the request with the response of the DenyList (see [WithDenyResponse]),
+	// a 403 status code by default, when the Protection API call fails.
+	FailClosed FailurePolicy = "closed"
+)
+
+// Route describes the policy applied to the requests matching every defined criterion.
+// The routes are evaluated in order and the first matching one is applied:
+// when none matches, the UrlPatternExclusion and UrlPatternInclusion settings are used.
+// The routes are indexed by the literal prefix or the extension of their path,
+// so that the criteria of a route are only evaluated on the requests it may match.
+type Route struct {
+	// Host is an exact host, such as `www.example.com`, or a wildcard, such as `*.example.com`.
+	Host string `json:"host,omitempty"`
+	// Method is the HTTP method of the requests.
+	Method string `json:"method,omitempty"`
+	// PathPrefix is the prefix of the path of the requests.
+	PathPrefix string `json:"pathPrefix,omitempty"`
+	// PathGlob is a glob matching the whole path of the requests:
+	// `*` and `?` match any characters but `/`, `**` matches any characters.
+	PathGlob string `json:"pathGlob,omitempty"`
+	// Pattern is a pattern of [http.ServeMux], such as `POST /api/{version}/graphql`.
+	Pattern string `json:"pattern,omitempty"`
+
+	// Action defaults to [RouteEnforce].
+	Action RouteAction `json:"action,omitempty"`
+	// FailurePolicy defaults to [FailOpen].
+	FailurePolicy FailurePolicy `json:"failurePolicy,omitempty"`
+	// Timeout of the Protection API call in milliseconds.
+	// The Timeout of the [Client] is used when it is 0.
+	Timeout int `json:"timeout,omitempty"`
+	// EnableGraphQLSupport overrides the GraphQL support of the [Client] when defined.
+	EnableGraphQLSupport *bool `json:"enableGraphQLSupport,omitempty"`
+}
+
+// WithRoutes is a functional option to set the route policy table.
+func WithRoutes(routes ...Route) Option {
+	return func(c *Client) {
+		c.Routes = routes
+	}
+}
+
+// compiledRoute is a [Route] ready to be matched against requests.
+type compiledRoute struct {
+	method       string
+	host         string
+	wildcardHost bool
+	pathPrefix   string
+	pathGlob     *regexp.Regexp
+	pattern      *http.ServeMux
+
+	action        RouteAction
+	failurePolicy FailurePolicy
+	// settings are the settings of the [Client] with the overrides of the route.
+	settings *settings
+
+	// literalPrefix is a prefix of the path of every matching request, derived from the criteria.
+	literalPrefix string
+	// extension is the extension of the path of every matching request, derived from the PathGlob.
+	extension string
+}
+
+// routeMatch is the handler registered on the [http.ServeMux] of a route pattern.
+// It identifies the matches among the redirections and the not found handler returned by [http.ServeMux.Handler].
+type routeMatch struct{}
+
+func (routeMatch) ServeHTTP(http.ResponseWriter, *http.Request) {}
+
+// compileRoutes validates the routes and returns the matching compiled routes.
+// Every invalid field is listed by the returned errors.
+func compileRoutes(routes []Route, s *settings) ([]*compiledRoute, []error) {
+	var compiled []*compiledRoute
+	var errs []error
+	for i, route := range routes {
+		cr, routeErrs := compileRoute(route, s)
+		for _, err := range routeErrs {
+			errs = append(errs, fmt.Errorf("Routes[%d].%w", i, err))
+		}
+		compiled = append(compiled, cr)
+	}
+	return compiled, errs
+}
+
+// compileRoute validates the route and returns the matching compiled route.
+// The settings of the route are copied from s.
+func compileRoute(route Route, s *settings) (*compiledRoute, []error) {
+	var errs []error
+	cr := &compiledRoute{
+		method:        strings.ToUpper(route.Method),
+		pathPrefix:    route.PathPrefix,
+		action:        route.Action,
+		failurePolicy: route.FailurePolicy,
+	}
+
+	cr.literalPrefix = route.PathPrefix
+	if route.Host != "" {
+		host, wildcard, err := parseHostPattern(route.Host)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Host must be an exact host or a wildcard: %q", route.Host))
+		}
+		cr.host, cr.wildcardHost = host, wildcard
+	}
+	if route.PathGlob != "" {
+		r, err := compileGlob(route.PathGlob)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("PathGlob must be a valid glob: %w", err))
+		}
+		cr.pathGlob = r
+		cr.literalPrefix = longest(cr.literalPrefix, globPrefix(route.PathGlob))
+		cr.extension = globExtension(route.PathGlob)
+	}
+	if route.Pattern != "" {
+		mux, err := compilePattern(route.Pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Pattern must be a valid ServeMux pattern: %v", err))
+		}
+		cr.pattern = mux
+		if err == nil {
+			cr.literalPrefix = longest(cr.literalPrefix, patternPrefix(route.Pattern))
+		}
+	}
+
+	switch cr.action {
+	case "":
+		cr.action = RouteEnforce
+	case RouteEnforce, RouteMonitor, RouteSkip:
+	default:
+		errs = append(errs, fmt.Errorf("Action must be enforce, monitor or skip: %q", route.Action))
+	}
+	switch cr.failurePolicy {
+	case "":
+		cr.failurePolicy = FailOpen
+	case FailOpen, FailClosed:
+	default:
+		errs = append(errs, fmt.Errorf("FailurePolicy must be open or closed: %q", route.FailurePolicy))
+	}
+	if route.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("Timeout must be a positive integer"))
+	}
+
+	rs := *s
+	rs.routes = nil
+	if route.Timeout > 0 {
+		rs.timeout = route.Timeout
+		rs.httpClient = newHTTPClient(route.Timeout, s.transport)
+	}
+	if route.EnableGraphQLSupport != nil {
+		rs.enableGraphQLSupport = *route.EnableGraphQLSupport
+	}
+	cr.settings = &rs
+
+	return cr, errs
+}
+
+// compileGlob returns the regular expression matching the whole path with the glob.
+func compileGlob(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch glob[i] {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// globPrefix returns the characters of the glob before its first wildcard.
+func globPrefix(glob string) string {
+	if i := strings.IndexAny(glob, "*?"); i >= 0 {
+		return glob[:i]
+	}
+	return glob
+}
+
+// globExtension returns the extension ending the glob, such as `css` for `/static/**.css`,
+// or an empty string when the glob does not end with a literal extension.
+func globExtension(glob string) string {
+	i := strings.LastIndexByte(glob, '.')
+	if i < 0 || !isAlphanumeric(glob[i+1:]) {
+		return ""
+	}
+	return glob[i+1:]
+}
+
+// patternPrefix returns the literal characters of the path of the [http.ServeMux] pattern before its first wildcard.
+// The patterns with escaped characters have no prefix, their path being unescaped by segment.
+func patternPrefix(pattern string) string {
+	if i := strings.IndexAny(pattern, " \t"); i >= 0 {
+		pattern = strings.TrimLeft(pattern[i:], " \t")
+	}
+	i := strings.IndexByte(pattern, '/')
+	if i < 0 || strings.IndexByte(pattern, '%') >= 0 {
+		return ""
+	}
+	path := pattern[i:]
+	if j := strings.IndexByte(path, '{'); j >= 0 {
+		path = path[:j]
+	}
+	return path
+}
+
+// longest returns the longest of a and b.
+func longest(a, b string) string {
+	if len(b) > len(a) {
+		return b
+	}
+	return a
+}
+
+// compilePattern returns a [http.ServeMux] matching the pattern only.
+// The panic of [http.ServeMux.Handle] on invalid patterns is returned as an error.
+func compilePattern(pattern string) (mux *http.ServeMux, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	mux = http.NewServeMux()
+	mux.Handle(pattern, routeMatch{})
+	return mux, nil
+}
+
+// match reports whether the request matches every criterion of the route.
+// host is the normalized host of the request.
+func (cr *compiledRoute) match(r *http.Request, host string) bool {
+	if cr.method != "" && cr.method != r.Method {
+		return false
+	}
+	if cr.host != "" && !matchHostPattern(cr.host, cr.wildcardHost, host) {
+		return false
+	}
+	if cr.pathPrefix != "" && !strings.HasPrefix(r.URL.Path, cr.pathPrefix) {
+		return false
+	}
+	if cr.pathGlob != nil && !cr.pathGlob.MatchString(r.URL.Path) {
+		return false
+	}
+	if cr.pattern != nil {
+		if h, _ := cr.pattern.Handler(r); h != (routeMatch{}) {
+			return false
+		}
+	}
+	return true
+}
+
+// routeIndex selects the routes that may match a request from the literal prefix or the extension of their path,
+// so that the criteria of the other routes are not evaluated.
+type routeIndex struct {
+	routes []*compiledRoute
+	// prefixes are the routes by literal prefix, and prefixLengths the sorted lengths of these prefixes.
+	prefixes      map[string][]int
+	prefixLengths []int
+	// extensions are the routes by extension.
+	extensions map[string][]int
+	// scanned are the routes without literal prefix nor extension.
+	scanned []int
+}
+
+// newRouteIndex returns the [routeIndex] of the routes, or nil when there is no route.
+// Every list of the index is sorted in the order of the routes.
+func newRouteIndex(routes []*compiledRoute) *routeIndex {
+	if len(routes) == 0 {
+		return nil
+	}
+	ix := &routeIndex{
+		routes:     routes,
+		prefixes:   map[string][]int{},
+		extensions: map[string][]int{},
+	}
+	lengths := map[int]bool{}
+	for i, cr := range routes {
+		switch {
+		case len(cr.literalPrefix) > 1:
+			if _, ok := ix.prefixes[cr.literalPrefix]; !ok && !lengths[len(cr.literalPrefix)] {
+				lengths[len(cr.literalPrefix)] = true
+				ix.prefixLengths = append(ix.prefixLengths, len(cr.literalPrefix))
+			}
+			ix.prefixes[cr.literalPrefix] = append(ix.prefixes[cr.literalPrefix], i)
+		case cr.extension != "":
+			ix.extensions[cr.extension] = append(ix.extensions[cr.extension], i)
+		default:
+			ix.scanned = append(ix.scanned, i)
+		}
+	}
+	sort.Ints(ix.prefixLengths)
+	return ix
+}
+
+// match returns the first route matching the request, or nil when none matches.
+// host is the normalized host of the request.
+func (ix *routeIndex) match(r *http.Request, host string) *compiledRoute {
+	path := r.URL.Path
+	best := len(ix.routes)
+	for _, n := range ix.prefixLengths {
+		if n > len(path) {
+			break
+		}
+		best = ix.first(ix.prefixes[path[:n]], best, r, host)
+	}
+	if i := strings.LastIndexByte(path, '.'); i >= 0 && len(ix.extensions) > 0 {
+		best = ix.first(ix.extensions[path[i+1:]], best, r, host)
+	}
+	best = ix.first(ix.scanned, best, r, host)
+
+	if best == len(ix.routes) {
+		return nil
+	}
+	return ix.routes[best]
+}
+
+// first returns the index of the first candidate route matching the request,
+// or best when none of the candidates before it matches.
+func (ix *routeIndex) first(candidates []int, best int, r *http.Request, host string) int {
+	for _, i := range candidates {
+		if i >= best {
+			break
+		}
+		if ix.routes[i].match(r, host) {
+			return i
+		}
+	}
+	return best
+}
+
+// route returns the first route matching the request, or nil when none matches.
+func (s *settings) route(r *http.Request) *compiledRoute {
+	if s.routes == nil {
+		return nil
+	}
+	host := r.Host
+	if s.useXForwardedHost {
+		host = getHost(r)
+	}
+	host = normalizeHost(host)
+
+	return s.routes.match(r, host)
+}
@@ -0,0 +1,280 @@
+package modulego
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompiledRouteMatch(t *testing.T) {
+	testCases := []struct {
+		name     string
+		route    Route
+		method   string
+		url      string
+		expected bool
+	}{
+		{"Empty route", Route{}, http.MethodGet, "http://example.com/", true},
+		{"Method", Route{Method: "post"}, http.MethodPost, "http://example.com/", true},
+		{"Other method", Route{Method: "POST"}, http.MethodGet, "http://example.com/", false},
+		{"Exact host", Route{Host: "example.com"}, http.MethodGet, "http://EXAMPLE.com:8080/", true},
+		{"Other host", Route{Host: "example.com"}, http.MethodGet, "http://www.example.com/", false},
+		{"Wildcard host", Route{Host: "*.example.com"}, http.MethodGet, "http://www.example.com/", true},
+		{"Path prefix", Route{PathPrefix: "/api/"}, http.MethodGet, "http://example.com/api/users", true},
+		{"Other path prefix", Route{PathPrefix: "/api/"}, http.MethodGet, "http://example.com/apis", false},
+		{"Glob", Route{PathGlob: "/users/*/orders"}, http.MethodGet, "http://example.com/users/42/orders", true},
+		{"Glob on several segments", Route{PathGlob: "/users/*/orders"}, http.MethodGet, "http://example.com/users/42/43/orders", false},
+		{"Double star glob", Route{PathGlob: "/static/**.css"}, http.MethodGet, "http://example.com/static/a/b/site.css", true},
+		{"Question mark glob", Route{PathGlob: "/v?/login"}, http.MethodGet, "http://example.com/v2/login", true},
+		{"Glob matching the whole path", Route{PathGlob: "/login"}, http.MethodGet, "http://example.com/login/reset", false},
+		{"Pattern", Route{Pattern: "POST /api/{version}/graphql"}, http.MethodPost, "http://example.com/api/v1/graphql", true},
+		{"Pattern with other method", Route{Pattern: "POST /api/{version}/graphql"}, http.MethodGet, "http://example.com/api/v1/graphql", false},
+		{"Pattern with host", Route{Pattern: "example.com/login"}, http.MethodGet, "http://example.com/login", true},
+		{"Pattern with other host", Route{Pattern: "example.com/login"}, http.MethodGet, "http://example.org/login", false},
+		{"Pattern with trailing slash", Route{Pattern: "/account/"}, http.MethodGet, "http://example.com/account/settings", true},
+		{"Pattern redirection", Route{Pattern: "/account/"}, http.MethodGet, "http://example.com/account", false},
+		{"Exact pattern", Route{Pattern: "/{$}"}, http.MethodGet, "http://example.com/home", false},
+		{"Every criterion", Route{Method: "POST", Host: "*.example.com", PathPrefix: "/api/", PathGlob: "**/login", Pattern: "/api/{rest...}"}, http.MethodPost, "http://www.example.com/api/v1/login", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cr, errs := compileRoute(tc.route, &settings{})
+			assert.Empty(t, errs)
+			r := httptest.NewRequest(tc.method, tc.url, nil)
+
+			assert.Equal(t, tc.expected, cr.match(r, normalizeHost(r.Host)))
+		})
+	}
+}
+
+func TestRouteIndex(t *testing.T) {
+	client, err := NewClient("your-api-key", WithRoutes(
+		Route{PathPrefix: "/api/v1/", Action: RouteSkip},
+		Route{PathGlob: "/**.css", Action: RouteMonitor},
+		Route{Method: http.MethodPost},
+		Route{Pattern: "/api/{version}/graphql", FailurePolicy: FailClosed},
+		Route{PathGlob: "/api/*/users", Action: RouteSkip},
+		Route{PathPrefix: "/", Action: RouteMonitor},
+	))
+	assert.Nil(t, err)
+	s := client.current()
+
+	testCases := []struct {
+		method   string
+		path     string
+		expected int
+	}{
+		{http.MethodGet, "/api/v1/graphql", 0},
+		{http.MethodGet, "/api/v1/site.css", 0},
+		{http.MethodGet, "/api/v2/site.css", 1},
+		{http.MethodPost, "/api/v2/graphql", 2},
+		{http.MethodGet, "/api/v2/graphql", 3},
+		{http.MethodGet, "/api/v2/users", 4},
+		{http.MethodGet, "/api", 5},
+		{http.MethodGet, "/site.CSS", 5},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, tc.path, nil)
+
+			assert.Same(t, s.routes.routes[tc.expected], s.route(r))
+		})
+	}
+
+	t.Run("No matching route", func(t *testing.T) {
+		client, err := NewClient("your-api-key", WithRoutes(Route{PathPrefix: "/api/"}, Route{PathGlob: "/**.css"}))
+		assert.Nil(t, err)
+
+		assert.Nil(t, client.current().route(httptest.NewRequest(http.MethodGet, "/login", nil)))
+	})
+}
+
+func TestCompileRoutes(t *testing.T) {
+	enabled := true
+	s, err := newSettings(&Client{
+		DenyStatusCode:  DefaultDenyStatusCodeValue,
+		Endpoint:        DefaultEndpointValue,
+		ServerSideKey:   "your-api-key",
+		Timeout:         150,
+		MaximumBodySize: 1024,
+	})
+	assert.Nil(t, err)
+
+	t.Run("With valid routes", func(t *testing.T) {
+		routes, errs := compileRoutes([]Route{
+			{PathPrefix: "/api/"},
+			{PathPrefix: "/graphql", Action: RouteMonitor, FailurePolicy: FailClosed, Timeout: 50, EnableGraphQLSupport: &enabled},
+		}, s)
+
+		assert.Empty(t, errs)
+		assert.Equal(t, RouteEnforce, routes[0].action)
+		assert.Equal(t, FailOpen, routes[0].failurePolicy)
+		assert.Same(t, s.httpClient, routes[0].settings.httpClient)
+		assert.False(t, routes[0].settings.enableGraphQLSupport)
+		assert.Equal(t, RouteMonitor, routes[1].action)
+		assert.Equal(t, FailClosed, routes[1].failurePolicy)
+		assert.Equal(t, 50*time.Millisecond, routes[1].settings.httpClient.Timeout)
+		assert.True(t, routes[1].settings.enableGraphQLSupport)
+		assert.Equal(t, "your-api-key", routes[1].settings.serverSideKey)
+	})
+
+	t.Run("Every invalid field is listed", func(t *testing.T) {
+		_, errs := compileRoutes([]Route{
+			{Host: "www.*.com", Action: "block"},
+			{PathPrefix: "/api/"},
+			{Pattern: "GET", FailurePolicy: "retry", Timeout: -1},
+		}, s)
+
+		assert.Equal(t, []string{
+			`Routes[0].Host must be an exact host or a wildcard: "www.*.com"`,
+			`Routes[0].Action must be enforce, monitor or skip: "block"`,
+			`Routes[2].Pattern must be a valid ServeMux pattern: parsing "GET": at offset 0: host/path missing /`,
+			`Routes[2].FailurePolicy must be open or closed: "retry"`,
+			"Routes[2].Timeout must be a positive integer",
+		}, errorMessages(errs))
+	})
+
+	t.Run("Invalid routes are rejected by NewClient", func(t *testing.T) {
+		client, err := NewClient("your-api-key", WithRoutes(Route{Action: "block"}))
+
+		assert.Nil(t, client)
+		var configErr *ConfigError
+		assert.True(t, errors.As(err, &configErr))
+	})
+
+	t.Run("Routes are decoded from the configuration", func(t *testing.T) {
+		c := DefaultConfig()
+		c.ServerSideKey = "your-api-key"
+		err := c.Decode(strings.NewReader(`{"routes": [{"pathPrefix": "/health", "action": "skip"}, {"pattern": "/{$}", "action": "unknown"}]}`))
+		assert.Nil(t, err)
+
+		assert.Equal(t, Route{PathPrefix: "/health", Action: RouteSkip}, c.Routes[0])
+		assert.EqualError(t, c.Validate(), `invalid configuration: Routes[1].Action must be enforce, monitor or skip: "unknown"`)
+	})
+}
+
+func TestEvaluate_Routes(t *testing.T) {
+	// the Protection API blocks every request and fails for the /broken paths
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if strings.HasPrefix(r.PostForm.Get("Request"), "/broken") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("X-Datadomeresponse", "403")
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer api.Close()
+
+	client, err := NewClient("your-api-key",
+		WithEndpoint(api.URL),
+		WithRoutes(
+			Route{PathPrefix: "/health", Action: RouteSkip},
+			Route{PathPrefix: "/api/", Action: RouteMonitor},
+			Route{PathGlob: "/broken/checkout/**", FailurePolicy: FailClosed},
+			Route{Pattern: "/assets/{file}"},
+		),
+	)
+	assert.Nil(t, err)
+
+	testCases := []struct {
+		path     string
+		expected *Decision
+		err      bool
+	}{
+		{"/health", &Decision{SkipReason: SkipReasonRoute}, false},
+		{"/api/users", &Decision{Monitored: true, StatusCode: http.StatusForbidden}, false},
+		{"/broken/checkout/pay", &Decision{Blocked: true, StatusCode: http.StatusForbidden}, false},
+		{"/broken/search", nil, true},
+		{"/assets/logo.png", &Decision{Blocked: true, StatusCode: http.StatusForbidden}, false},
+		{"/logo.png", &Decision{SkipReason: SkipReasonUrlPatternExclusion}, false},
+		{"/login", &Decision{Blocked: true, StatusCode: http.StatusForbidden}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			decision, err := client.Evaluate(httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			assert.Equal(t, tc.err, err != nil)
+			if decision != nil {
+				decision.Body = nil
+				decision.ResponseHeaders = nil
+			}
+			assert.Equal(t, tc.expected, decision)
+		})
+	}
+}
+
+func TestEvaluateRouteFailClosedDenyResponse(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer api.Close()
+
+	client, err := NewClient("your-api-key",
+		WithEndpoint(api.URL),
+		WithDenyResponse(http.StatusUnavailableForLegalReasons, "denied"),
+		WithRoutes(Route{PathPrefix: "/checkout", FailurePolicy: FailClosed}),
+	)
+	assert.Nil(t, err)
+
+	rr := httptest.NewRecorder()
+	blocked, err := client.DatadomeProtect(rr, httptest.NewRequest(http.MethodGet, "/checkout", nil))
+
+	assert.Nil(t, err)
+	assert.True(t, blocked)
+	assert.Equal(t, http.StatusUnavailableForLegalReasons, rr.Code)
+	assert.Equal(t, "denied", rr.Body.String())
+}
+
+func BenchmarkRoute(b *testing.B) {
+	client, err := NewClient("your-api-key", WithRoutes(
+		Route{PathPrefix: "/health", Action: RouteSkip},
+		Route{Method: http.MethodPost, PathGlob: "/api/*/graphql"},
+		Route{Host: "*.example.com", PathPrefix: "/admin/"},
+		Route{Pattern: "GET /products/{id}", Action: RouteMonitor},
+	))
+	if err != nil {
+		b.Fatal(err)
+	}
+	s := client.current()
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/products/42", nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if s.route(r) == nil {
+			b.Fatal("no route matches")
+		}
+	}
+}
+
+func BenchmarkRoute_ManyRoutes(b *testing.B) {
+	var routes []Route
+	for i := 0; i < 100; i++ {
+		routes = append(routes,
+			Route{PathPrefix: fmt.Sprintf("/service%d/", i)},
+			Route{PathGlob: fmt.Sprintf("/static%d/**.css", i), Action: RouteSkip},
+		)
+	}
+	routes = append(routes, Route{Pattern: "GET /products/{id}", Action: RouteMonitor})
+	client, err := NewClient("your-api-key", WithRoutes(routes...))
+	if err != nil {
+		b.Fatal(err)
+	}
+	s := client.current()
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/products/42", nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if s.route(r) == nil {
+			b.Fatal("no route matches")
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package modulego
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// SkipRule describes requests that are not sent to the Protection API.
+// A request matches the rule when it matches every defined criterion.
+// The name of the first matching rule is reported in the logs and in [Decision.SkipRule].
+type SkipRule struct {
+	// Name identifies the rule in the logs and in the decisions.
+	Name string `json:"name"`
+	// Methods lists the HTTP methods of the requests, such as OPTIONS or HEAD.
+	Methods []string `json:"methods,omitempty"`
+	// Header is the name of a header the requests must contain.
+	Header string `json:"header,omitempty"`
+	// HeaderValue is the value the Header must have, compared in constant time.
+	// Any value is accepted when it is empty.
+	HeaderValue string `json:"headerValue,omitempty"`
+	// CIDRs lists the IP ranges, or the IPs, of the clients.
+	CIDRs []string `json:"cidrs,omitempty"`
+	// UserAgent is a regular expression matching the User-Agent header of the requests.
+	UserAgent string `json:"userAgent,omitempty"`
+}
+
+// WithSkipRules is a functional option to set the rules of the requests that are not sent to the Protection API.
+// The rules are evaluated before the routes and the URL patterns.
+func WithSkipRules(rules ...SkipRule) Option {
+	return func(c *Client) {
+		c.SkipRules = rules
+	}
+}
+
+// compiledSkipRule is a [SkipRule] ready to be matched against requests.
+type compiledSkipRule struct {
+	name        string
+	methods     []string
+	header      string
+	headerValue []byte
+	networks    []*net.IPNet
+	userAgent   *regexp.Regexp
+}
+
+// compileSkipRules validates the rules and returns the matching compiled rules.
+// Every invalid field is listed by the returned errors.
+func compileSkipRules(rules []SkipRule) ([]*compiledSkipRule, []error) {
+	var compiled []*compiledSkipRule
+	var errs []error
+	for i, rule := range rules {
+		cr, ruleErrs := compileSkipRule(rule)
+		for _, err := range ruleErrs {
+			errs = append(errs, fmt.Errorf("SkipRules[%d].%w", i, err))
+		}
+		compiled = append(compiled, cr)
+	}
+	return compiled, errs
+}
+
+// compileSkipRule validates the rule and returns the matching compiled rule.
+func compileSkipRule(rule SkipRule) (*compiledSkipRule, []error) {
+	var errs []error
+	cr := &compiledSkipRule{
+		name:   rule.Name,
+		header: http.CanonicalHeaderKey(rule.Header),
+	}
+	if rule.Name == "" {
+		errs = append(errs, fmt.Errorf("Name must be defined"))
+	}
+	if len(rule.Methods) == 0 && rule.Header == "" && len(rule.CIDRs) == 0 && rule.UserAgent == "" {
+		errs = append(errs, fmt.Errorf("Methods, Header, CIDRs or UserAgent must be defined"))
+	}
+
+	for _, method := range rule.Methods {
+		cr.methods = append(cr.methods, strings.ToUpper(method))
+	}
+	if rule.HeaderValue != "" {
+		if rule.Header == "" {
+			errs = append(errs, fmt.Errorf("Header must be defined with HeaderValue"))
+		}
+		cr.headerValue = []byte(rule.HeaderValue)
+	}
+	for _, cidr := range rule.CIDRs {
+		network, err := parseCIDR(cidr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("CIDRs must be valid IPs or CIDRs: %q", cidr))
+			continue
+		}
+		cr.networks = append(cr.networks, network)
+	}
+	if rule.UserAgent != "" {
+		r, err := regexp.Compile(rule.UserAgent)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("UserAgent must be a valid RegExp: %w", err))
+		}
+		cr.userAgent = r
+	}
+	return cr, errs
+}
+
+// parseCIDR returns the network of the CIDR, or the network of the single IP.
+func parseCIDR(cidr string) (*net.IPNet, error) {
+	if !strings.Contains(cidr, "/") {
+		ip := net.ParseIP(cidr)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP: %q", cidr)
+		}
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, network, err := net.ParseCIDR(cidr)
+	return network, err
+}
+
+// match reports whether the request matches every criterion of the rule.
+func (cr *compiledSkipRule) match(r *http.Request) bool {
+	if len(cr.methods) > 0 && !containsString(cr.methods, r.Method) {
+		return false
+	}
+	if cr.header != "" {
+		values, ok := r.Header[cr.header]
+		if !ok || len(values) == 0 {
+			return false
+		}
+		if cr.headerValue != nil && subtle.ConstantTimeCompare([]byte(values[0]), cr.headerValue) != 1 {
+			return false
+		}
+	}
+	if len(cr.networks) > 0 {
+		ip, err := getIP(r)
+		if err != nil || !containsIP(cr.networks, net.ParseIP(ip)) {
+			return false
+		}
+	}
+	if cr.userAgent != nil && !cr.userAgent.MatchString(r.Header.Get("User-Agent")) {
+		return false
+	}
+	return true
+}
+
+// skipRule returns the first skip rule matching the request, or nil when none matches.
+func (s *settings) skipRule(r *http.Request) *compiledSkipRule {
+	for _, cr := range s.skipRules {
+		if cr.match(r) {
+			return cr
+		}
+	}
+	return nil
+}
+
+// containsString reports whether values contains v.
+func containsString(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// containsIP reports whether one of the networks contains the IP.
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
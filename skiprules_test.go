@@ -0,0 +1,121 @@
+package modulego
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompiledSkipRuleMatch(t *testing.T) {
+	newRequest := func(method string, remoteAddr string, header http.Header) *http.Request {
+		r := httptest.NewRequest(method, "/ping", nil)
+		r.RemoteAddr = remoteAddr
+		for name, values := range header {
+			r.Header[name] = values
+		}
+		return r
+	}
+
+	testCases := []struct {
+		name     string
+		rule     SkipRule
+		request  *http.Request
+		expected bool
+	}{
+		{"Method", SkipRule{Methods: []string{"options", "HEAD"}}, newRequest(http.MethodOptions, "1.2.3.4:1234", nil), true},
+		{"Other method", SkipRule{Methods: []string{"OPTIONS"}}, newRequest(http.MethodGet, "1.2.3.4:1234", nil), false},
+		{"Header presence", SkipRule{Header: "x-internal-token"}, newRequest(http.MethodGet, "1.2.3.4:1234", http.Header{"X-Internal-Token": {""}}), true},
+		{"Missing header", SkipRule{Header: "X-Internal-Token"}, newRequest(http.MethodGet, "1.2.3.4:1234", nil), false},
+		{"Header value", SkipRule{Header: "X-Internal-Token", HeaderValue: "secret"}, newRequest(http.MethodGet, "1.2.3.4:1234", http.Header{"X-Internal-Token": {"secret"}}), true},
+		{"Other header value", SkipRule{Header: "X-Internal-Token", HeaderValue: "secret"}, newRequest(http.MethodGet, "1.2.3.4:1234", http.Header{"X-Internal-Token": {"secret2"}}), false},
+		{"CIDR", SkipRule{CIDRs: []string{"10.0.0.0/8"}}, newRequest(http.MethodGet, "10.1.2.3:1234", nil), true},
+		{"IP", SkipRule{CIDRs: []string{"192.168.1.1", "10.0.0.0/8"}}, newRequest(http.MethodGet, "192.168.1.1:1234", nil), true},
+		{"IPv6 CIDR", SkipRule{CIDRs: []string{"2001:db8::/32"}}, newRequest(http.MethodGet, "[2001:db8::1]:1234", nil), true},
+		{"Other IP", SkipRule{CIDRs: []string{"10.0.0.0/8", "192.168.1.1"}}, newRequest(http.MethodGet, "192.168.1.2:1234", nil), false},
+		{"Invalid remote address", SkipRule{CIDRs: []string{"10.0.0.0/8"}}, newRequest(http.MethodGet, "unknown", nil), false},
+		{"User-Agent", SkipRule{UserAgent: `^(kube-probe|ELB-HealthChecker)/`}, newRequest(http.MethodGet, "1.2.3.4:1234", http.Header{"User-Agent": {"kube-probe/1.29"}}), true},
+		{"Other User-Agent", SkipRule{UserAgent: `^kube-probe/`}, newRequest(http.MethodGet, "1.2.3.4:1234", http.Header{"User-Agent": {"Mozilla/5.0 kube-probe/1.29"}}), false},
+		{"Every criterion", SkipRule{Methods: []string{"GET"}, Header: "X-Probe", CIDRs: []string{"10.0.0.0/8"}, UserAgent: "probe"}, newRequest(http.MethodGet, "10.0.0.1:1234", http.Header{"X-Probe": {"1"}, "User-Agent": {"probe"}}), true},
+		{"Some criteria", SkipRule{Methods: []string{"GET"}, CIDRs: []string{"10.0.0.0/8"}}, newRequest(http.MethodGet, "11.0.0.1:1234", nil), false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.rule.Name = "rule"
+			cr, errs := compileSkipRule(tc.rule)
+			assert.Empty(t, errs)
+
+			assert.Equal(t, tc.expected, cr.match(tc.request))
+		})
+	}
+}
+
+func TestCompileSkipRules(t *testing.T) {
+	_, errs := compileSkipRules([]SkipRule{
+		{Name: "preflights", Methods: []string{"OPTIONS"}},
+		{},
+		{Name: "probes", HeaderValue: "secret", CIDRs: []string{"10.0.0.0/33", "localhost"}, UserAgent: `(invalid`},
+	})
+
+	assert.Equal(t, []string{
+		"SkipRules[1].Name must be defined",
+		"SkipRules[1].Methods, Header, CIDRs or UserAgent must be defined",
+		"SkipRules[2].Header must be defined with HeaderValue",
+		`SkipRules[2].CIDRs must be valid IPs or CIDRs: "10.0.0.0/33"`,
+		`SkipRules[2].CIDRs must be valid IPs or CIDRs: "localhost"`,
+		"SkipRules[2].UserAgent must be a valid RegExp: error parsing regexp: missing closing ): `(invalid`",
+	}, errorMessages(errs))
+
+	t.Run("Invalid rules are rejected by NewClient", func(t *testing.T) {
+		client, err := NewClient("your-api-key", WithSkipRules(SkipRule{Methods: []string{"OPTIONS"}}))
+
+		assert.Nil(t, client)
+		var configErr *ConfigError
+		assert.True(t, errors.As(err, &configErr))
+	})
+
+	t.Run("Rules are decoded from the configuration", func(t *testing.T) {
+		c := DefaultConfig()
+		c.ServerSideKey = "your-api-key"
+		err := c.Decode(strings.NewReader(`{"skipRules": [{"name": "probes", "cidrs": ["10.0.0.0/8"], "userAgent": "^kube-probe/"}]}`))
+		assert.Nil(t, err)
+
+		assert.Equal(t, []SkipRule{{Name: "probes", CIDRs: []string{"10.0.0.0/8"}, UserAgent: "^kube-probe/"}}, c.SkipRules)
+		assert.Nil(t, c.Validate())
+	})
+}
+
+func TestEvaluate_SkipRules(t *testing.T) {
+	logger := &syncLogger{}
+	client, err := NewClient("your-api-key",
+		WithLogger(logger),
+		WithEndpoint("http://127.0.0.1:1/validate-request"),
+		WithSkipRules(
+			SkipRule{Name: "preflights", Methods: []string{http.MethodOptions}},
+			SkipRule{Name: "internal", Header: "X-Internal-Token", HeaderValue: "secret"},
+		),
+		WithRoutes(Route{Action: RouteEnforce}),
+	)
+	assert.Nil(t, err)
+
+	t.Run("Matching request", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		r.Header.Set("X-Internal-Token", "secret")
+
+		decision, err := client.Evaluate(r)
+
+		assert.Nil(t, err)
+		assert.Equal(t, &Decision{SkipReason: SkipReasonSkipRule, SkipRule: "internal"}, decision)
+		assert.Equal(t, "INFO SkipRule internal matches request, skipping.", logger.last())
+	})
+
+	t.Run("Other request", func(t *testing.T) {
+		// the request is sent to the unreachable Protection API
+		_, err := client.Evaluate(httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+		assert.NotNil(t, err)
+	})
+}
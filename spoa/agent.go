@@ -0,0 +1,451 @@
+// Package spoa implements a HAProxy Stream Processing Offload Agent (SPOA) validating requests with DataDome.
+//
+// HAProxy sends the attributes of each request to the agent through the SPOP protocol.
+// The agent evaluates them with a [modulego.Client] and returns variables that HAProxy rules can act on.
+//
+// Example of SPOE configuration (datadome-spoe.conf):
+//
+//	[datadome]
+//	spoe-agent datadome-agent
+//	    messages datadome-request
+//	    option var-prefix datadome
+//	    option set-on-error error
+//	    timeout hello 2s
+//	    timeout idle 2m
+//	    timeout processing 500ms
+//	    use-backend datadome-spoa
+//
+//	spoe-message datadome-request
+//	    args method=method path=path query=query ip=src port=src_port ssl=ssl_fc headers=req.hdrs_bin
+//	    event on-frontend-http-request
+//
+// Example of HAProxy configuration:
+//
+//	frontend www
+//	    filter spoe engine datadome config /etc/haproxy/datadome-spoe.conf
+//	    http-request redirect location %[var(txn.datadome.res_location)] code 302 if { var(txn.datadome.decision) -m str block } { var(txn.datadome.status) -m int 301 302 }
+//	    http-request return status 403 content-type "text/html" lf-string "%[var(txn.datadome.body)]" hdr set-cookie "%[var(txn.datadome.res_set_cookie)]" if { var(txn.datadome.decision) -m str block }
+//	    http-request set-header X-DataDome-Botname %[var(txn.datadome.req_x_datadome_botname)] if { var(txn.datadome.req_x_datadome_botname) -m found }
+//	    http-response add-header Set-Cookie %[var(txn.datadome.res_set_cookie)] if { var(txn.datadome.res_set_cookie) -m found }
+//	    http-response add-header Set-Cookie %[var(txn.datadome.res_set_cookie_1)] if { var(txn.datadome.res_set_cookie_1) -m found }
+//
+//	backend datadome-spoa
+//	    mode tcp
+//	    server agent 127.0.0.1:12345
+//
+// The following variables are set in the transaction scope:
+//   - decision: "allow", "block", "skip" or "error"
+//   - status: the status code returned by the Protection API
+//   - body: the content of the response to send when the request is blocked,
+//     not set when the ACK frame would exceed the maximum frame size (the header variables are dropped next)
+//   - response_headers: the space-separated names of the headers to add to the response
+//   - request_headers: the space-separated names of the headers to add to the request
+//   - res_<header>: the value of each header to add to the response (e.g. res_set_cookie)
+//   - req_<header>: the value of each header to add to the request (e.g. req_x_datadome_botname)
+//   - res_<header>_<n>, req_<header>_<n>: the next values of the headers having several values (e.g. res_set_cookie_1)
+//
+// The arguments of the message are:
+//   - method, path, query: the request line (method defaults to GET and path to "/")
+//   - ip, port: the address of the client
+//   - ssl: whether the connection uses TLS
+//   - headers: the request headers, as returned by req.hdrs (string) or req.hdrs_bin (binary)
+//   - body: the request body, as returned by req.body (requires option http-buffer-request)
+package spoa
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	modulego "github.com/andynuge/datadome-go"
+)
+
+const (
+	DefaultMaxFrameSizeValue = 16380
+	DefaultMessageNameValue  = "datadome-request"
+
+	minFrameSize     = 256
+	supportedVersion = "2.0"
+)
+
+// Decisions reported through the `decision` variable.
+const (
+	DecisionAllow = "allow"
+	DecisionBlock = "block"
+	DecisionSkip  = "skip"
+	DecisionError = "error"
+)
+
+// Agent is a SPOA evaluating the requests received from HAProxy with a DataDome [modulego.Client].
+type Agent struct {
+	Logger       modulego.Logger
+	MaxFrameSize uint32
+	MessageName  string
+
+	client *modulego.Client
+}
+
+// Option is a functional option to customize the [Agent].
+type Option func(*Agent)
+
+// WithLogger is a functional option to set a custom Logger for the Agent.
+func WithLogger(logger modulego.Logger) Option {
+	return func(a *Agent) {
+		a.Logger = logger
+	}
+}
+
+// WithMaxFrameSize is a functional option to set the maximum size of the frames exchanged with HAProxy.
+// The value negotiated with HAProxy is the lowest of both values.
+func WithMaxFrameSize(maxFrameSize uint32) Option {
+	return func(a *Agent) {
+		a.MaxFrameSize = maxFrameSize
+	}
+}
+
+// WithMessageName is a functional option to set the name of the SPOE message to evaluate.
+// Other messages are acknowledged without any action.
+func WithMessageName(messageName string) Option {
+	return func(a *Agent) {
+		a.MessageName = messageName
+	}
+}
+
+// NewAgent instantiates a new [Agent] evaluating the requests with the given client.
+// It returns an error in case of invalid inputs in the options.
+func NewAgent(client *modulego.Client, options ...Option) (*Agent, error) {
+	a := &Agent{
+		Logger:       modulego.NewDefaultLogger(),
+		MaxFrameSize: DefaultMaxFrameSizeValue,
+		MessageName:  DefaultMessageNameValue,
+		client:       client,
+	}
+
+	for _, opt := range options {
+		opt(a)
+	}
+
+	if client == nil {
+		return nil, fmt.Errorf("Client must be defined")
+	}
+	if a.MaxFrameSize < minFrameSize {
+		return nil, fmt.Errorf("MaxFrameSize must be greater than or equal to %d", minFrameSize)
+	}
+	if a.MessageName == "" {
+		return nil, fmt.Errorf("MessageName must be defined")
+	}
+
+	return a, nil
+}
+
+// Serve accepts connections from HAProxy on the listener and handles each of them in a new goroutine.
+// It returns when the listener is closed.
+func (a *Agent) Serve(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go a.ServeConn(conn)
+	}
+}
+
+// ServeConn handles a connection from HAProxy until it is disconnected.
+// The connection is closed when the function returns.
+func (a *Agent) ServeConn(conn net.Conn) {
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := &session{agent: a, conn: conn, reader: bufio.NewReader(conn), maxFrameSize: a.MaxFrameSize}
+	if err := s.run(ctx); err != nil && !errors.Is(err, io.EOF) {
+		a.Logger.Error("SPOP session error: ", err)
+	}
+	s.wg.Wait()
+}
+
+// session describes a connection with HAProxy.
+type session struct {
+	agent        *Agent
+	conn         net.Conn
+	reader       *bufio.Reader
+	maxFrameSize uint32
+
+	mu sync.Mutex
+	wg sync.WaitGroup
+}
+
+// write sends a frame to HAProxy. It is safe for concurrent use.
+func (s *session) write(f *frame) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return writeFrame(s.conn, f)
+}
+
+// disconnect sends an AGENT-DISCONNECT frame with the given status.
+func (s *session) disconnect(status statusCode, message string) error {
+	e := &encoder{}
+	_ = e.putKV("status-code", uint32(status))
+	_ = e.putKV("message", message)
+	if err := s.write(&frame{typ: frameAgentDisconnect, flags: flagFin, payload: e.buf}); err != nil {
+		return err
+	}
+	if status != statusNormal {
+		return errors.New(message)
+	}
+	return nil
+}
+
+// run performs the handshake then processes the NOTIFY frames.
+func (s *session) run(ctx context.Context) error {
+	healthcheck, err := s.hello()
+	if err != nil || healthcheck {
+		return err
+	}
+
+	for {
+		f, err := readFrame(s.reader, s.maxFrameSize)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+			return s.disconnect(statusInvalid, err.Error())
+		}
+
+		switch f.typ {
+		case frameNotify:
+			if f.flags&flagFin == 0 {
+				return s.disconnect(statusFragmentation, "fragmentation is not supported")
+			}
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				if err := s.notify(ctx, f); err != nil {
+					s.agent.Logger.Error("fail to process NOTIFY frame: ", err)
+				}
+			}()
+		case frameHAProxyDisconnect:
+			return s.disconnect(statusNormal, "")
+		default:
+			return s.disconnect(statusInvalid, fmt.Sprintf("unexpected frame type %d", f.typ))
+		}
+	}
+}
+
+// hello handles the HAPROXY-HELLO frame and replies with the AGENT-HELLO frame.
+// It returns true if the connection is a health check and must be closed.
+func (s *session) hello() (bool, error) {
+	f, err := readFrame(s.reader, s.maxFrameSize)
+	if err != nil {
+		return false, err
+	}
+	if f.typ != frameHAProxyHello {
+		return false, s.disconnect(statusInvalid, "HAPROXY-HELLO frame expected")
+	}
+
+	kvs, err := (&decoder{buf: f.payload}).kvList()
+	if err != nil {
+		return false, s.disconnect(statusInvalid, err.Error())
+	}
+
+	versions, ok := kvs["supported-versions"].(string)
+	if !ok {
+		return false, s.disconnect(statusNoVersion, "supported-versions not found")
+	}
+	if !containsToken(versions, supportedVersion) {
+		return false, s.disconnect(statusBadVersion, "unsupported version "+versions)
+	}
+
+	maxFrameSize, ok := kvs["max-frame-size"].(uint32)
+	if !ok {
+		return false, s.disconnect(statusNoFrameSize, "max-frame-size not found")
+	}
+	if maxFrameSize < minFrameSize {
+		return false, s.disconnect(statusBadFrameSize, "max-frame-size is too small")
+	}
+	if maxFrameSize < s.maxFrameSize {
+		s.maxFrameSize = maxFrameSize
+	}
+
+	capabilities, ok := kvs["capabilities"].(string)
+	if !ok {
+		return false, s.disconnect(statusNoCapabilities, "capabilities not found")
+	}
+	agentCapabilities := ""
+	if containsToken(capabilities, "pipelining") {
+		agentCapabilities = "pipelining"
+	}
+
+	e := &encoder{}
+	_ = e.putKV("version", supportedVersion)
+	_ = e.putKV("max-frame-size", s.maxFrameSize)
+	_ = e.putKV("capabilities", agentCapabilities)
+	if err := s.write(&frame{typ: frameAgentHello, flags: flagFin, payload: e.buf}); err != nil {
+		return false, err
+	}
+
+	healthcheck, _ := kvs["healthcheck"].(bool)
+	return healthcheck, nil
+}
+
+// notify evaluates the messages of a NOTIFY frame and replies with the ACK frame.
+// When the ACK frame exceeds the negotiated frame size, the body variable is dropped,
+// then the header variables, HAProxy rejecting the frames that are too large.
+func (s *session) notify(ctx context.Context, f *frame) error {
+	d := &decoder{buf: f.payload}
+	var vars []variable
+
+	for !d.done() {
+		name, err := d.string()
+		if err != nil {
+			return err
+		}
+		nbArgs, err := d.byte()
+		if err != nil {
+			return err
+		}
+		args := make(map[string]any, nbArgs)
+		for i := 0; i < int(nbArgs); i++ {
+			key, v, err := d.kv()
+			if err != nil {
+				return err
+			}
+			args[key] = v
+		}
+
+		if name != s.agent.MessageName {
+			continue
+		}
+		vars = append(vars, s.agent.evaluate(ctx, args)...)
+	}
+
+	ack := &frame{typ: frameAck, flags: flagFin, streamID: f.streamID, frameID: f.frameID}
+	var err error
+	if ack.payload, err = encodeSetVars(vars); err != nil {
+		return err
+	}
+	for _, drop := range []func(name string) bool{
+		func(name string) bool { return name == "body" },
+		func(name string) bool { return name != "decision" && name != "status" },
+	} {
+		if ack.size() <= int(s.maxFrameSize) {
+			break
+		}
+		vars = slices.DeleteFunc(vars, func(v variable) bool { return drop(v.name) })
+		if ack.payload, err = encodeSetVars(vars); err != nil {
+			return err
+		}
+		s.agent.Logger.Warn("ACK frame exceeds the maximum frame size of ", s.maxFrameSize, " bytes, dropping variables")
+	}
+
+	return s.write(ack)
+}
+
+// encodeSetVars returns the payload of an ACK frame setting the variables.
+func encodeSetVars(vars []variable) ([]byte, error) {
+	e := &encoder{}
+	for _, v := range vars {
+		if err := putSetVar(e, v.name, v.value); err != nil {
+			return nil, err
+		}
+	}
+	return e.buf, nil
+}
+
+// variable is a variable to set in HAProxy.
+type variable struct {
+	name  string
+	value any
+}
+
+// putSetVar appends a SET-VAR action in the transaction scope.
+func putSetVar(e *encoder, name string, value any) error {
+	e.buf = append(e.buf, byte(actionSetVar), 3, byte(scopeTransaction))
+	e.putString(name)
+	return e.putValue(value)
+}
+
+// evaluate builds the request described by the message arguments, evaluates it and returns the variables to set.
+func (a *Agent) evaluate(ctx context.Context, args map[string]any) []variable {
+	r, err := newRequest(ctx, args)
+	if err != nil {
+		a.Logger.Error("fail to build request from SPOE message: ", err)
+		return []variable{{"decision", DecisionError}}
+	}
+
+	decision, err := a.client.Evaluate(r)
+	if err != nil {
+		return []variable{{"decision", DecisionError}}
+	}
+
+	vars := []variable{{"status", int32(decision.StatusCode)}}
+	switch {
+	case decision.SkipReason != "":
+		vars = append(vars, variable{"decision", DecisionSkip})
+	case decision.Blocked:
+		vars = append(vars, variable{"decision", DecisionBlock}, variable{"body", decision.Body})
+	default:
+		vars = append(vars, variable{"decision", DecisionAllow})
+	}
+	vars = append(vars, headerVariables("request_headers", "req_", decision.RequestHeaders)...)
+	vars = append(vars, headerVariables("response_headers", "res_", decision.ResponseHeaders)...)
+
+	return vars
+}
+
+// headerVariables returns a variable listing the header names and one variable per header value.
+// Header names are converted to valid HAProxy variable names (e.g. `Set-Cookie` becomes `set_cookie`),
+// the variables of the next values being suffixed with their index (e.g. `set_cookie_1`).
+func headerVariables(listName, prefix string, headers http.Header) []variable {
+	if len(headers) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(headers))
+	vars := make([]variable, 0, len(headers)+1)
+	for name, values := range headers {
+		names = append(names, name)
+		for i, value := range values {
+			varName := prefix + variableName(name)
+			if i > 0 {
+				varName += "_" + strconv.Itoa(i)
+			}
+			vars = append(vars, variable{varName, value})
+		}
+	}
+	return append(vars, variable{listName, strings.Join(names, " ")})
+}
+
+// variableName converts a header name into a valid HAProxy variable name.
+func variableName(headerName string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return '_'
+	}, headerName)
+}
+
+// containsToken indicates if the comma-separated list contains the token.
+func containsToken(list, token string) bool {
+	for _, item := range strings.Split(list, ",") {
+		if strings.TrimSpace(item) == token {
+			return true
+		}
+	}
+	return false
+}
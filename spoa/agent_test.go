@@ -0,0 +1,325 @@
+package spoa
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	modulego "github.com/andynuge/datadome-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// haproxy emulates the HAProxy side of a SPOP connection.
+type haproxy struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// dialAgent connects to the agent and performs the HELLO handshake.
+// It returns the connection and the content of the AGENT-HELLO frame.
+func dialAgent(t *testing.T, addr string, hello map[string]any) (*haproxy, map[string]any) {
+	conn, err := net.Dial("tcp", addr)
+	assert.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	h := &haproxy{t: t, conn: conn, reader: bufio.NewReader(conn)}
+
+	e := &encoder{}
+	for key, value := range hello {
+		assert.Nil(t, e.putKV(key, value))
+	}
+	h.send(&frame{typ: frameHAProxyHello, flags: flagFin, payload: e.buf})
+
+	f := h.receive()
+	assert.Equal(t, frameAgentHello, f.typ)
+	kvs, err := (&decoder{buf: f.payload}).kvList()
+	assert.Nil(t, err)
+
+	return h, kvs
+}
+
+func (h *haproxy) send(f *frame) {
+	assert.Nil(h.t, writeFrame(h.conn, f))
+}
+
+func (h *haproxy) receive() *frame {
+	f, err := readFrame(h.reader, DefaultMaxFrameSizeValue)
+	assert.Nil(h.t, err)
+	return f
+}
+
+// notify sends a NOTIFY frame with a single message and returns the variables set by the ACK frame.
+func (h *haproxy) notify(streamID, frameID uint64, message string, args map[string]any) map[string]any {
+	e := &encoder{}
+	e.putString(message)
+	e.buf = append(e.buf, byte(len(args)))
+	for key, value := range args {
+		assert.Nil(h.t, e.putKV(key, value))
+	}
+	h.send(&frame{typ: frameNotify, flags: flagFin, streamID: streamID, frameID: frameID, payload: e.buf})
+
+	f := h.receive()
+	assert.Equal(h.t, frameAck, f.typ)
+	assert.Equal(h.t, streamID, f.streamID)
+	assert.Equal(h.t, frameID, f.frameID)
+
+	vars := map[string]any{}
+	d := &decoder{buf: f.payload}
+	for !d.done() {
+		action, _ := d.byte()
+		nbArgs, _ := d.byte()
+		varScope, _ := d.byte()
+		assert.Equal(h.t, actionSetVar, actionType(action))
+		assert.Equal(h.t, byte(3), nbArgs)
+		assert.Equal(h.t, scopeTransaction, scope(varScope))
+		name, value, err := d.kv()
+		assert.Nil(h.t, err)
+		vars[name] = value
+	}
+	return vars
+}
+
+var defaultHello = map[string]any{
+	"supported-versions": "2.0",
+	"max-frame-size":     uint32(DefaultMaxFrameSizeValue),
+	"capabilities":       "pipelining,async",
+}
+
+func setupAgent(t *testing.T, apiHandler http.HandlerFunc) string {
+	api := httptest.NewServer(apiHandler)
+	t.Cleanup(api.Close)
+
+	client, err := modulego.NewClient("your-api-key", modulego.WithEndpoint(api.URL+"/validate-request"), modulego.WithLogger(nopLogger{}))
+	assert.Nil(t, err)
+	agent, err := NewAgent(client, WithLogger(nopLogger{}))
+	assert.Nil(t, err)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	t.Cleanup(func() { ln.Close() })
+	go agent.Serve(ln)
+
+	return ln.Addr().String()
+}
+
+func TestAgent(t *testing.T) {
+	var payload string
+	addr := setupAgent(t, func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		payload = r.PostForm.Encode()
+		if r.PostForm.Get("UserAgent") == "huge-page" {
+			w.Header().Set("X-Datadomeresponse", "403")
+			w.Header().Set("X-Datadome-Headers", "Set-Cookie")
+			w.Header().Set("Set-Cookie", "datadome=blocked")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = io.WriteString(w, strings.Repeat("a", DefaultMaxFrameSizeValue))
+			return
+		}
+		if r.PostForm.Get("UserAgent") == "bad-bot" {
+			w.Header().Set("X-Datadomeresponse", "403")
+			w.Header().Set("X-Datadome-Headers", "Set-Cookie")
+			w.Header().Set("Set-Cookie", "datadome=blocked")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = io.WriteString(w, "captcha")
+			return
+		}
+		w.Header().Set("X-Datadomeresponse", "200")
+		w.Header().Set("X-Datadome-Request-Headers", "X-Datadome-Botname")
+		w.Header().Set("X-Datadome-Botname", "none")
+	})
+
+	t.Run("Handshake", func(t *testing.T) {
+		_, hello := dialAgent(t, addr, defaultHello)
+
+		assert.Equal(t, "2.0", hello["version"])
+		assert.Equal(t, uint32(DefaultMaxFrameSizeValue), hello["max-frame-size"])
+		assert.Equal(t, "pipelining", hello["capabilities"])
+	})
+
+	t.Run("Allowed request", func(t *testing.T) {
+		h, _ := dialAgent(t, addr, defaultHello)
+
+		vars := h.notify(1, 1, DefaultMessageNameValue, map[string]any{
+			"method":  "GET",
+			"path":    "/ping",
+			"ip":      net.ParseIP("192.168.0.1").To4(),
+			"port":    int32(4242),
+			"headers": "Host: www.example.com\r\nUser-Agent: curl/8.0\r\n\r\n",
+		})
+
+		assert.Equal(t, DecisionAllow, vars["decision"])
+		assert.Equal(t, int32(200), vars["status"])
+		assert.Equal(t, "X-Datadome-Botname", vars["request_headers"])
+		assert.Equal(t, "none", vars["req_x_datadome_botname"])
+		assert.Contains(t, payload, "IP=192.168.0.1")
+		assert.Contains(t, payload, "Host=www.example.com")
+	})
+
+	t.Run("Blocked request", func(t *testing.T) {
+		h, _ := dialAgent(t, addr, defaultHello)
+
+		vars := h.notify(2, 1, DefaultMessageNameValue, map[string]any{
+			"path":    "/login",
+			"ip":      net.ParseIP("192.168.0.1").To4(),
+			"headers": "Host: www.example.com\r\nUser-Agent: bad-bot\r\n\r\n",
+		})
+
+		assert.Equal(t, DecisionBlock, vars["decision"])
+		assert.Equal(t, int32(403), vars["status"])
+		assert.Equal(t, []byte("captcha"), vars["body"])
+		assert.Equal(t, "datadome=blocked", vars["res_set_cookie"])
+	})
+
+	t.Run("Blocked request with a body exceeding the frame size", func(t *testing.T) {
+		h, _ := dialAgent(t, addr, defaultHello)
+
+		vars := h.notify(2, 2, DefaultMessageNameValue, map[string]any{
+			"path":    "/login",
+			"ip":      net.ParseIP("192.168.0.1").To4(),
+			"headers": "Host: www.example.com\r\nUser-Agent: huge-page\r\n\r\n",
+		})
+
+		assert.Equal(t, DecisionBlock, vars["decision"])
+		assert.Equal(t, int32(403), vars["status"])
+		assert.NotContains(t, vars, "body")
+		assert.Equal(t, "datadome=blocked", vars["res_set_cookie"])
+	})
+
+	t.Run("Excluded request", func(t *testing.T) {
+		h, _ := dialAgent(t, addr, defaultHello)
+
+		vars := h.notify(3, 1, DefaultMessageNameValue, map[string]any{
+			"path": "/picture.jpg",
+			"ip":   net.ParseIP("192.168.0.1").To4(),
+		})
+
+		assert.Equal(t, DecisionSkip, vars["decision"])
+	})
+
+	t.Run("Invalid message", func(t *testing.T) {
+		h, _ := dialAgent(t, addr, defaultHello)
+
+		vars := h.notify(4, 1, DefaultMessageNameValue, map[string]any{"path": "/ping"})
+
+		assert.Equal(t, DecisionError, vars["decision"])
+	})
+
+	t.Run("Unknown message is acknowledged without action", func(t *testing.T) {
+		h, _ := dialAgent(t, addr, defaultHello)
+
+		vars := h.notify(5, 1, "other-message", map[string]any{"path": "/ping"})
+
+		assert.Empty(t, vars)
+	})
+
+	t.Run("Pipelined frames on the same connection", func(t *testing.T) {
+		h, _ := dialAgent(t, addr, defaultHello)
+
+		for i := uint64(1); i <= 3; i++ {
+			vars := h.notify(10+i, i, DefaultMessageNameValue, map[string]any{
+				"path": "/ping",
+				"ip":   net.ParseIP("192.168.0.1").To4(),
+			})
+			assert.Equal(t, DecisionAllow, vars["decision"])
+		}
+	})
+
+	t.Run("Disconnection", func(t *testing.T) {
+		h, _ := dialAgent(t, addr, defaultHello)
+
+		e := &encoder{}
+		_ = e.putKV("status-code", uint32(statusNormal))
+		_ = e.putKV("message", "")
+		h.send(&frame{typ: frameHAProxyDisconnect, flags: flagFin, payload: e.buf})
+
+		f := h.receive()
+		assert.Equal(t, frameAgentDisconnect, f.typ)
+		kvs, err := (&decoder{buf: f.payload}).kvList()
+		assert.Nil(t, err)
+		assert.Equal(t, uint32(statusNormal), kvs["status-code"])
+	})
+
+	t.Run("Unsupported version", func(t *testing.T) {
+		conn, err := net.Dial("tcp", addr)
+		assert.Nil(t, err)
+		defer conn.Close()
+		h := &haproxy{t: t, conn: conn, reader: bufio.NewReader(conn)}
+
+		e := &encoder{}
+		_ = e.putKV("supported-versions", "1.0")
+		_ = e.putKV("max-frame-size", uint32(DefaultMaxFrameSizeValue))
+		_ = e.putKV("capabilities", "")
+		h.send(&frame{typ: frameHAProxyHello, flags: flagFin, payload: e.buf})
+
+		f := h.receive()
+		assert.Equal(t, frameAgentDisconnect, f.typ)
+		kvs, err := (&decoder{buf: f.payload}).kvList()
+		assert.Nil(t, err)
+		assert.Equal(t, uint32(statusBadVersion), kvs["status-code"])
+	})
+
+	t.Run("Health check", func(t *testing.T) {
+		hello := map[string]any{"healthcheck": true}
+		for key, value := range defaultHello {
+			hello[key] = value
+		}
+		h, _ := dialAgent(t, addr, hello)
+
+		_, err := h.reader.ReadByte()
+		assert.ErrorIs(t, err, io.EOF)
+	})
+}
+
+func TestHeaderVariables(t *testing.T) {
+	headers := http.Header{}
+	headers.Add("Set-Cookie", "datadome=blocked")
+	headers.Add("Set-Cookie", "datadome-session=1")
+
+	vars := headerVariables("response_headers", "res_", headers)
+
+	assert.Equal(t, []variable{
+		{"res_set_cookie", "datadome=blocked"},
+		{"res_set_cookie_1", "datadome-session=1"},
+		{"response_headers", "Set-Cookie"},
+	}, vars)
+	assert.Nil(t, headerVariables("response_headers", "res_", nil))
+}
+
+func TestNewAgent(t *testing.T) {
+	client, err := modulego.NewClient("your-api-key")
+	assert.Nil(t, err)
+
+	t.Run("With default values", func(t *testing.T) {
+		agent, err := NewAgent(client)
+
+		assert.Nil(t, err)
+		assert.Equal(t, uint32(DefaultMaxFrameSizeValue), agent.MaxFrameSize)
+		assert.Equal(t, DefaultMessageNameValue, agent.MessageName)
+	})
+
+	t.Run("With a too small frame size", func(t *testing.T) {
+		agent, err := NewAgent(client, WithMaxFrameSize(128))
+
+		assert.Nil(t, agent)
+		assert.EqualError(t, err, "MaxFrameSize must be greater than or equal to 256")
+	})
+
+	t.Run("Without client", func(t *testing.T) {
+		agent, err := NewAgent(nil)
+
+		assert.Nil(t, agent)
+		assert.EqualError(t, err, "Client must be defined")
+	})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(args ...interface{}) {}
+func (nopLogger) Info(args ...interface{})  {}
+func (nopLogger) Warn(args ...interface{})  {}
+func (nopLogger) Error(args ...interface{}) {}
@@ -0,0 +1,377 @@
+package spoa
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+// frameType describes the type of a SPOP frame.
+type frameType byte
+
+const (
+	frameHAProxyHello      frameType = 1
+	frameHAProxyDisconnect frameType = 2
+	frameNotify            frameType = 3
+	frameAgentHello        frameType = 101
+	frameAgentDisconnect   frameType = 102
+	frameAck               frameType = 103
+)
+
+const (
+	flagFin   uint32 = 0x00000001
+	flagAbort uint32 = 0x00000002
+)
+
+// dataType describes the type of a typed data.
+type dataType byte
+
+const (
+	dataNull   dataType = 0
+	dataBool   dataType = 1
+	dataInt32  dataType = 2
+	dataUint32 dataType = 3
+	dataInt64  dataType = 4
+	dataUint64 dataType = 5
+	dataIPv4   dataType = 6
+	dataIPv6   dataType = 7
+	dataString dataType = 8
+	dataBinary dataType = 9
+)
+
+// actionType describes the type of an action sent in an ACK frame.
+type actionType byte
+
+const (
+	actionSetVar   actionType = 1
+	actionUnsetVar actionType = 2
+)
+
+// scope describes the scope of a variable set by an action.
+type scope byte
+
+const (
+	scopeProcess     scope = 0
+	scopeSession     scope = 1
+	scopeTransaction scope = 2
+	scopeRequest     scope = 3
+	scopeResponse    scope = 4
+)
+
+// statusCode describes the reason of a disconnection.
+type statusCode uint32
+
+const (
+	statusNormal            statusCode = 0
+	statusIO                statusCode = 1
+	statusTimeout           statusCode = 2
+	statusTooBig            statusCode = 3
+	statusInvalid           statusCode = 4
+	statusNoVersion         statusCode = 5
+	statusNoFrameSize       statusCode = 6
+	statusNoCapabilities    statusCode = 7
+	statusBadVersion        statusCode = 8
+	statusBadFrameSize      statusCode = 9
+	statusFragmentation     statusCode = 10
+	statusInvalidInterlaced statusCode = 11
+	statusFrameIDNotFound   statusCode = 12
+	statusResource          statusCode = 13
+	statusUnknown           statusCode = 99
+)
+
+var errInvalidFrame = errors.New("invalid SPOP frame")
+
+// frame is a SPOP frame, without its length prefix.
+type frame struct {
+	typ      frameType
+	flags    uint32
+	streamID uint64
+	frameID  uint64
+	payload  []byte
+}
+
+// size returns the size of the encoded frame, without its length prefix.
+func (f *frame) size() int {
+	e := &encoder{}
+	e.putVarint(f.streamID)
+	e.putVarint(f.frameID)
+	return 1 + 4 + len(e.buf) + len(f.payload)
+}
+
+// readFrame reads a frame from the reader.
+// An error is returned if the frame is larger than maxFrameSize.
+func readFrame(r io.Reader, maxFrameSize uint32) (*frame, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxFrameSize {
+		return nil, fmt.Errorf("frame of %d bytes exceeds the maximum frame size of %d bytes", size, maxFrameSize)
+	}
+
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+
+	d := &decoder{buf: buf}
+	typ, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	flags, err := d.uint32()
+	if err != nil {
+		return nil, err
+	}
+	streamID, err := d.varint()
+	if err != nil {
+		return nil, err
+	}
+	frameID, err := d.varint()
+	if err != nil {
+		return nil, err
+	}
+
+	return &frame{
+		typ:      frameType(typ),
+		flags:    flags,
+		streamID: streamID,
+		frameID:  frameID,
+		payload:  d.buf[d.off:],
+	}, nil
+}
+
+// writeFrame writes the frame prefixed by its length to the writer.
+func writeFrame(w io.Writer, f *frame) error {
+	e := &encoder{buf: make([]byte, 4, 4+16+len(f.payload))}
+	e.buf = append(e.buf, byte(f.typ))
+	e.buf = binary.BigEndian.AppendUint32(e.buf, f.flags)
+	e.putVarint(f.streamID)
+	e.putVarint(f.frameID)
+	e.buf = append(e.buf, f.payload...)
+	binary.BigEndian.PutUint32(e.buf, uint32(len(e.buf)-4))
+
+	_, err := w.Write(e.buf)
+	return err
+}
+
+// encoder builds the payload of a frame.
+type encoder struct {
+	buf []byte
+}
+
+// putVarint appends an integer with the variable-length encoding of SPOP.
+func (e *encoder) putVarint(i uint64) {
+	if i < 240 {
+		e.buf = append(e.buf, byte(i))
+		return
+	}
+	e.buf = append(e.buf, byte(i)|240)
+	i = (i - 240) >> 4
+	for i >= 128 {
+		e.buf = append(e.buf, byte(i)|128)
+		i = (i - 128) >> 7
+	}
+	e.buf = append(e.buf, byte(i))
+}
+
+// putString appends a length-prefixed string.
+func (e *encoder) putString(s string) {
+	e.putVarint(uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+// putValue appends a typed data.
+// The supported Go types are nil, bool, int32, uint32, int64, uint64, int, net.IP, string and []byte.
+func (e *encoder) putValue(v any) error {
+	switch v := v.(type) {
+	case nil:
+		e.buf = append(e.buf, byte(dataNull))
+	case bool:
+		b := byte(dataBool)
+		if v {
+			b |= 0x10
+		}
+		e.buf = append(e.buf, b)
+	case int32:
+		e.buf = append(e.buf, byte(dataInt32))
+		e.putVarint(uint64(v))
+	case uint32:
+		e.buf = append(e.buf, byte(dataUint32))
+		e.putVarint(uint64(v))
+	case int64:
+		e.buf = append(e.buf, byte(dataInt64))
+		e.putVarint(uint64(v))
+	case int:
+		e.buf = append(e.buf, byte(dataInt64))
+		e.putVarint(uint64(v))
+	case uint64:
+		e.buf = append(e.buf, byte(dataUint64))
+		e.putVarint(v)
+	case net.IP:
+		if ip4 := v.To4(); ip4 != nil {
+			e.buf = append(e.buf, byte(dataIPv4))
+			e.buf = append(e.buf, ip4...)
+		} else if ip6 := v.To16(); ip6 != nil {
+			e.buf = append(e.buf, byte(dataIPv6))
+			e.buf = append(e.buf, ip6...)
+		} else {
+			return fmt.Errorf("invalid IP address: %v", v)
+		}
+	case string:
+		e.buf = append(e.buf, byte(dataString))
+		e.putString(v)
+	case []byte:
+		e.buf = append(e.buf, byte(dataBinary))
+		e.putVarint(uint64(len(v)))
+		e.buf = append(e.buf, v...)
+	default:
+		return fmt.Errorf("unsupported type %T", v)
+	}
+	return nil
+}
+
+// putKV appends a key-value item.
+func (e *encoder) putKV(key string, v any) error {
+	e.putString(key)
+	return e.putValue(v)
+}
+
+// decoder reads the payload of a frame.
+type decoder struct {
+	buf []byte
+	off int
+}
+
+// done indicates if the whole payload has been read.
+func (d *decoder) done() bool {
+	return d.off >= len(d.buf)
+}
+
+func (d *decoder) byte() (byte, error) {
+	if d.off >= len(d.buf) {
+		return 0, errInvalidFrame
+	}
+	b := d.buf[d.off]
+	d.off++
+	return b, nil
+}
+
+func (d *decoder) bytes(n uint64) ([]byte, error) {
+	if n > uint64(len(d.buf)-d.off) {
+		return nil, errInvalidFrame
+	}
+	b := d.buf[d.off : d.off+int(n)]
+	d.off += int(n)
+	return b, nil
+}
+
+func (d *decoder) uint32() (uint32, error) {
+	b, err := d.bytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
+// varint reads an integer with the variable-length encoding of SPOP.
+func (d *decoder) varint() (uint64, error) {
+	b, err := d.byte()
+	if err != nil {
+		return 0, err
+	}
+	i := uint64(b)
+	if i < 240 {
+		return i, nil
+	}
+	for shift := uint(4); ; shift += 7 {
+		if shift > 63 {
+			return 0, errInvalidFrame
+		}
+		b, err = d.byte()
+		if err != nil {
+			return 0, err
+		}
+		i += uint64(b) << shift
+		if b < 128 {
+			return i, nil
+		}
+	}
+}
+
+// string reads a length-prefixed string.
+func (d *decoder) string() (string, error) {
+	n, err := d.varint()
+	if err != nil {
+		return "", err
+	}
+	b, err := d.bytes(n)
+	return string(b), err
+}
+
+// value reads a typed data.
+// See [encoder.putValue] for the returned Go types.
+func (d *decoder) value() (any, error) {
+	b, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	switch dataType(b & 0x0F) {
+	case dataNull:
+		return nil, nil
+	case dataBool:
+		return b&0x10 != 0, nil
+	case dataInt32:
+		i, err := d.varint()
+		return int32(i), err
+	case dataUint32:
+		i, err := d.varint()
+		return uint32(i), err
+	case dataInt64:
+		i, err := d.varint()
+		return int64(i), err
+	case dataUint64:
+		return d.varint()
+	case dataIPv4:
+		ip, err := d.bytes(net.IPv4len)
+		return net.IP(ip), err
+	case dataIPv6:
+		ip, err := d.bytes(net.IPv6len)
+		return net.IP(ip), err
+	case dataString:
+		return d.string()
+	case dataBinary:
+		n, err := d.varint()
+		if err != nil {
+			return nil, err
+		}
+		return d.bytes(n)
+	}
+	return nil, errInvalidFrame
+}
+
+// kv reads a key-value item.
+func (d *decoder) kv() (string, any, error) {
+	key, err := d.string()
+	if err != nil {
+		return "", nil, err
+	}
+	v, err := d.value()
+	return key, v, err
+}
+
+// kvList reads key-value items until the end of the payload.
+func (d *decoder) kvList() (map[string]any, error) {
+	kvs := map[string]any{}
+	for !d.done() {
+		key, v, err := d.kv()
+		if err != nil {
+			return nil, err
+		}
+		kvs[key] = v
+	}
+	return kvs, nil
+}
@@ -0,0 +1,86 @@
+package spoa
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVarint(t *testing.T) {
+	tests := []struct {
+		input uint64
+		want  []byte
+	}{
+		{input: 0, want: []byte{0x00}},
+		{input: 239, want: []byte{0xEF}},
+		{input: 240, want: []byte{0xF0, 0x00}},
+		{input: 2287, want: []byte{0xFF, 0x7F}},
+		{input: 2288, want: []byte{0xF0, 0x80, 0x00}},
+		{input: 264431, want: []byte{0xFF, 0xFF, 0x7F}},
+	}
+
+	for _, tc := range tests {
+		e := &encoder{}
+		e.putVarint(tc.input)
+		assert.Equal(t, tc.want, e.buf)
+
+		got, err := (&decoder{buf: e.buf}).varint()
+		assert.Nil(t, err)
+		assert.Equal(t, tc.input, got)
+	}
+
+	t.Run("Truncated varint", func(t *testing.T) {
+		_, err := (&decoder{buf: []byte{0xF0, 0x80}}).varint()
+		assert.ErrorIs(t, err, errInvalidFrame)
+	})
+}
+
+func TestValue(t *testing.T) {
+	values := []any{
+		nil,
+		true,
+		false,
+		int32(-42),
+		uint32(42),
+		int64(-1 << 40),
+		uint64(1 << 40),
+		net.ParseIP("192.168.0.1").To4(),
+		net.ParseIP("2001:db8::1"),
+		"hello",
+		[]byte{0x00, 0x01, 0x02},
+	}
+
+	e := &encoder{}
+	for _, v := range values {
+		assert.Nil(t, e.putValue(v))
+	}
+
+	d := &decoder{buf: e.buf}
+	for _, want := range values {
+		got, err := d.value()
+		assert.Nil(t, err)
+		assert.Equal(t, want, got)
+	}
+	assert.True(t, d.done())
+}
+
+func TestFrame(t *testing.T) {
+	want := &frame{typ: frameNotify, flags: flagFin, streamID: 300, frameID: 7, payload: []byte("payload")}
+
+	var buf bytes.Buffer
+	assert.Nil(t, writeFrame(&buf, want))
+
+	got, err := readFrame(&buf, DefaultMaxFrameSizeValue)
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+
+	t.Run("Frame exceeding the maximum size", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.Nil(t, writeFrame(&buf, &frame{typ: frameNotify, payload: make([]byte, 512)}))
+
+		_, err := readFrame(&buf, minFrameSize)
+		assert.ErrorContains(t, err, "exceeds the maximum frame size")
+	})
+}
@@ -0,0 +1,158 @@
+package spoa
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// newRequest builds the [http.Request] described by the arguments of a SPOE message.
+// See the package documentation for the list of arguments.
+func newRequest(ctx context.Context, args map[string]any) (*http.Request, error) {
+	method := stringArg(args, "method")
+	if method == "" {
+		method = http.MethodGet
+	}
+	path := stringArg(args, "path")
+	if path == "" {
+		path = "/"
+	}
+	requestURI := path
+	if query := stringArg(args, "query"); query != "" {
+		requestURI += "?" + query
+	}
+	u, err := url.ParseRequestURI(requestURI)
+	if err != nil {
+		return nil, fmt.Errorf("fail to parse request URI: %w", err)
+	}
+
+	ip, ok := args["ip"].(net.IP)
+	if !ok {
+		return nil, fmt.Errorf("ip argument must be an IP address")
+	}
+	port, err := intArg(args, "port")
+	if err != nil {
+		return nil, err
+	}
+
+	var header http.Header
+	switch headers := args["headers"].(type) {
+	case nil:
+		header = http.Header{}
+	case string:
+		header, err = parseHeaders(headers)
+	case []byte:
+		header, err = parseBinaryHeaders(headers)
+	default:
+		err = fmt.Errorf("headers argument must be a string or a binary")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r := (&http.Request{
+		Method:     method,
+		URL:        u,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     header,
+		Host:       header.Get("Host"),
+		RemoteAddr: net.JoinHostPort(ip.String(), strconv.Itoa(port)),
+		RequestURI: requestURI,
+		Body:       http.NoBody,
+	}).WithContext(ctx)
+	header.Del("Host")
+
+	if ssl, _ := args["ssl"].(bool); ssl {
+		r.TLS = &tls.ConnectionState{}
+	}
+
+	var body []byte
+	switch b := args["body"].(type) {
+	case []byte:
+		body = b
+	case string:
+		body = []byte(b)
+	}
+	if len(body) > 0 {
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+	} else if contentLength, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64); err == nil {
+		r.ContentLength = contentLength
+	}
+
+	return r, nil
+}
+
+// stringArg returns the string value of an argument, or an empty string.
+func stringArg(args map[string]any, name string) string {
+	switch v := args[name].(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return ""
+}
+
+// intArg returns the integer value of an argument, or 0 when the argument is not set.
+func intArg(args map[string]any, name string) (int, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return 0, nil
+	case int32:
+		return int(v), nil
+	case uint32:
+		return int(v), nil
+	case int64:
+		return int(v), nil
+	case uint64:
+		return int(v), nil
+	}
+	return 0, fmt.Errorf("%s argument must be an integer", name)
+}
+
+// parseHeaders parses headers in the format of the `req.hdrs` sample fetch.
+func parseHeaders(headers string) (http.Header, error) {
+	if !strings.HasSuffix(headers, "\r\n\r\n") {
+		headers += "\r\n"
+	}
+	reader := textproto.NewReader(bufio.NewReader(strings.NewReader(headers)))
+	mimeHeader, err := reader.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("fail to parse headers: %w", err)
+	}
+	return http.Header(mimeHeader), nil
+}
+
+// parseBinaryHeaders parses headers in the format of the `req.hdrs_bin` sample fetch:
+// a list of length-prefixed names and values terminated by an empty name and an empty value.
+func parseBinaryHeaders(headers []byte) (http.Header, error) {
+	header := http.Header{}
+	d := &decoder{buf: headers}
+	for !d.done() {
+		name, err := d.string()
+		if err != nil {
+			return nil, fmt.Errorf("fail to parse binary headers: %w", err)
+		}
+		value, err := d.string()
+		if err != nil {
+			return nil, fmt.Errorf("fail to parse binary headers: %w", err)
+		}
+		if name == "" && value == "" {
+			break
+		}
+		header.Add(name, value)
+	}
+	return header, nil
+}
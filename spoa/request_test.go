@@ -0,0 +1,69 @@
+package spoa
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRequest(t *testing.T) {
+	t.Run("With text headers", func(t *testing.T) {
+		r, err := newRequest(context.Background(), map[string]any{
+			"method":  "POST",
+			"path":    "/graphql",
+			"query":   "foo=bar",
+			"ip":      net.ParseIP("192.168.0.1").To4(),
+			"port":    int32(4242),
+			"ssl":     true,
+			"headers": "Host: www.example.com\r\nUser-Agent: curl/8.0\r\nContent-Type: application/json\r\n\r\n",
+			"body":    []byte(`{"query":"{ todos }"}`),
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/graphql", r.URL.Path)
+		assert.Equal(t, "foo=bar", r.URL.RawQuery)
+		assert.Equal(t, "192.168.0.1:4242", r.RemoteAddr)
+		assert.Equal(t, "www.example.com", r.Host)
+		assert.Equal(t, "", r.Header.Get("Host"))
+		assert.Equal(t, "curl/8.0", r.Header.Get("User-Agent"))
+		assert.NotNil(t, r.TLS)
+		assert.Equal(t, int64(21), r.ContentLength)
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, `{"query":"{ todos }"}`, string(body))
+	})
+
+	t.Run("With binary headers", func(t *testing.T) {
+		e := &encoder{}
+		for _, s := range []string{"host", "www.example.com", "cookie", "datadome=abc", "", ""} {
+			e.putString(s)
+		}
+
+		r, err := newRequest(context.Background(), map[string]any{
+			"ip":      net.ParseIP("2001:db8::1"),
+			"headers": e.buf,
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/", r.URL.Path)
+		assert.Equal(t, "[2001:db8::1]:0", r.RemoteAddr)
+		assert.Equal(t, "www.example.com", r.Host)
+		assert.Equal(t, "datadome=abc", r.Header.Get("Cookie"))
+		assert.Nil(t, r.TLS)
+	})
+
+	t.Run("Without IP address", func(t *testing.T) {
+		_, err := newRequest(context.Background(), map[string]any{"path": "/"})
+
+		assert.EqualError(t, err, "ip argument must be an IP address")
+	})
+}
+
+func TestVariableName(t *testing.T) {
+	assert.Equal(t, "set_cookie", variableName("Set-Cookie"))
+	assert.Equal(t, "x_datadome_cid", variableName("X-DataDome-CID"))
+}
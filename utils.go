@@ -1,13 +1,11 @@
 package modulego
 
 import (
-	"bytes"
 	"fmt"
-	"io"
+	"mime"
 	"net"
 	"net/http"
 	"net/url"
-	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -15,9 +13,8 @@ import (
 )
 
 // getMicroTime returns the current unix timestamp in microseconds
-// This function needs to implement the time.UnixMicro function when Tyk will support the go version >= 1.18
 func getMicroTime() string {
-	return strconv.FormatInt(time.Now().UnixNano()/1000, 10)
+	return strconv.FormatInt(time.Now().UnixMicro(), 10)
 }
 
 // getIP returns the IP of the emitter from the RemoteAddr field of the request.
@@ -58,21 +55,12 @@ func getURL(r *http.Request) string {
 	}
 }
 
-// cut implementation from the official package (see https://cs.opensource.google/go/go/+/master:src/strings/strings.go;l=1278)
-func cut(s, sep string) (before, after string, found bool) {
-	if i := strings.Index(s, sep); i >= 0 {
-		return s[:i], s[i+len(sep):], true
-	}
-	return s, "", false
-}
-
 // getURI returns the URI without the query parameters nor the Fragments.
-// This function needs to implement the strings.Cut function when Tyk will support the go version >= 1.18
 func getURI(r *http.Request) string {
 	var finalPath string
 
-	pathWithoutQueryParams, _, _ := cut(r.URL.Path, "?")
-	finalPath, _, _ = cut(pathWithoutQueryParams, "#")
+	pathWithoutQueryParams, _, _ := strings.Cut(r.URL.Path, "?")
+	finalPath, _, _ = strings.Cut(pathWithoutQueryParams, "#")
 	finalUri := r.URL.Host + finalPath
 
 	return finalUri
@@ -170,108 +158,25 @@ func truncateValue(key ApiFields, value string) string {
 	return value
 }
 
-// isGraphQLRequest indicates if the incoming request is a GraphQL request.
-func isGraphQLRequest(r *http.Request) bool {
-	return r.Header.Get("Content-Type") == "application/json" && r.Method == "POST" && r.ContentLength > 0 && strings.Contains(r.URL.Path, "graphql")
-}
-
-// readBodyWithoutConsuming extracts the GraphQL query from the body.
-// When the body has been fully read, it is restored to the original request.
-func readBodyWithoutConsuming(r *http.Request, maximumBodySize int) ([]byte, error) {
-	readSize := 1024
-	regexp := regexp.MustCompile(`"query"\s*:\s*("(?:query|mutation|subscription)?\s*(?:[A-Za-z_][A-Za-z0-9_]*)?\s*[{(].*)`)
-	limitedReader := &io.LimitedReader{
-		R: r.Body,
-		N: int64(maximumBodySize),
+// isGraphQLRequest indicates if the incoming request is a GraphQL request sent to a path matching the endpoint:
+// a GET request with a `query` or an `extensions` URL parameter, or a POST request with a JSON, a GraphQL or a multipart body.
+// A body of unknown length, such as a chunked one, is accepted since its reading is bounded by the maximum body size.
+func isGraphQLRequest(r *http.Request, endpoint uriMatcher) bool {
+	if endpoint == nil || !endpoint.MatchString(r.URL.Path) {
+		return false
 	}
-
-	matchBuffer := make([]byte, 0, 2*readSize)
-	buffer := make([]byte, readSize)
-	var beginBody bytes.Buffer
-	var matchedBytes []byte
-	found := false
-
-	for {
-		n, err := limitedReader.Read(buffer)
-		if n > 0 {
-			beginBody.Write(buffer[:n])
-			if !found {
-				matchBuffer = append(matchBuffer, buffer[:n]...)
-				if len(matchBuffer) >= 2*readSize {
-					matchBuffer = matchBuffer[len(matchBuffer)-(2*readSize):]
-				}
-				matches := regexp.FindSubmatch(matchBuffer)
-				if len(matches) > 1 {
-					matchedBytes = matches[1]
-					found = true
-				}
-			}
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		return query.Has("query") || query.Has("extensions")
+	case http.MethodPost:
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case "application/json", "application/graphql", "multipart/form-data":
+			return r.ContentLength != 0
 		}
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	restBody, err := io.ReadAll(r.Body)
-	if err != nil {
-		return nil, err
 	}
-
-	r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(beginBody.Bytes()), bytes.NewReader(restBody)))
-
-	return matchedBytes, nil
-}
-
-// parseGraphQLQuery returns a [GraphQLData] structure with information extracted from the body.
-func parseGraphQLQuery(body string) *GraphQLData {
-	gqlData := &GraphQLData{
-		Count: 0,
-		Type:  Query,
-		Name:  "",
-	}
-	regex := regexp.MustCompile(`(?m)(?P<operationType>query|mutation|subscription)\s*(?P<operationName>[A-Za-z_][A-Za-z0-9_]*)?\s*[({@]`)
-	matches := regex.FindAllStringSubmatch(body, -1)
-	matchLength := len(matches)
-
-	if matchLength > 0 {
-		gqlData.Count = matchLength
-		firstMatch := matches[0]
-		operationTypeIndex := regex.SubexpIndex("operationType")
-		operationNameIndex := regex.SubexpIndex("operationName")
-
-		if operationTypeIndex != -1 && operationTypeIndex < len(firstMatch) {
-			gqlData.Type = OperationType(firstMatch[operationTypeIndex])
-		}
-		if operationNameIndex != -1 && operationNameIndex < len(firstMatch) {
-			gqlData.Name = firstMatch[operationNameIndex]
-		}
-	} else {
-		shorthandSyntaxRegex := regexp.MustCompile(`"(?:query|mutation|subscription)?\s*(?:[A-Za-z_][A-Za-z0-9_]*)?\s*[{]`)
-		shorthandMatches := shorthandSyntaxRegex.FindStringSubmatch(body)
-		gqlData.Count = len(shorthandMatches)
-	}
-
-	return gqlData
-}
-
-// getGraphQLData reads the body to extract the GraphQL query and parse it.
-// An error is returned if
-// - an error happened during the lecture of the body
-// - the GraphQL query was not found
-func getGraphQLData(r *http.Request, maximumBodySize int) (*GraphQLData, error) {
-	body, err := readBodyWithoutConsuming(r, maximumBodySize)
-	if err != nil {
-		return nil, fmt.Errorf("error while reading request body: %w", err)
-	}
-	if body == nil {
-		return nil, fmt.Errorf("query not found in the request body")
-	}
-	bodyStr := string(body)
-
-	return parseGraphQLQuery(bodyStr), nil
+	return false
 }
 
 // getHost returns the host of the request.
@@ -89,33 +89,6 @@ func TestGetURI(t *testing.T) {
 	}
 }
 
-// TestParseGraphQLQuery with different type of GraphQL JSON body
-// 1. Shorthand Syntax
-// 2. Mutation
-// 3. Query
-// 4. Multiple operations
-// 5. Wrong GraphQL query
-func TestParseGraphQLQuery(t *testing.T) {
-	tests := []struct {
-		want  GraphQLData
-		input string
-	}{
-		{want: GraphQLData{Count: 1, Name: "", Type: Query}, input: `{"query":"{ todos { title }}"}`},
-		{want: GraphQLData{Count: 1, Name: "LoginV2", Type: Mutation}, input: `{"query":"mutation LoginV2($loginV2LoginName2: String!, $loginV2Password2: String!, $loginV2ServiceLocationId3: ID!) { loginV2(loginName: $loginV2LoginName2, password: $loginV2Password2, serviceLocationId: $loginV2ServiceLocationId3) { cookieAdapterToken msg }}","variables":{"loginV2LoginName2":"alpha@staging.com","loginV2Password2":"Test1234","loginV2ServiceLocationId3":"50000059"}}`},
-		{want: GraphQLData{Count: 1, Name: "Coupons", Type: Query}, input: `{"query":"query Coupons($couponsServiceLocationId3: ID!) { coupons(serviceLocationId: $couponsServiceLocationId3) { coupons { name title circularId couponType endDate } } }","variables":{"couponsServiceLocationId3":"50000059"}}`},
-		{want: GraphQLData{Count: 4, Name: "One", Type: Mutation}, input: `{"query":"mutation One { # Do something ...}mutation Two { # Do something ...}query Three @depends(on: [\"One\", \"Two\"]) { # Do something ...}query Four @depends(on: \"Three\") { # Do something ...}"}`},
-		{want: GraphQLData{Count: 1, Name: "", Type: Query}, input: `{"query":"query { todos { title }}"}`},
-		{want: GraphQLData{Count: 0, Name: "", Type: Query}, input: `{"query":"mutatio RefreshTokenV2 $serviceLocationId: ID!) { refreshTokenV2(serviceLocationId: $serviceLocationId) { cookieAdapterToken msg }}","variables":{"serviceLocationId":""}}`},
-	}
-
-	for _, tc := range tests {
-		got := parseGraphQLQuery(tc.input)
-		assert.Equal(t, tc.want.Count, got.Count)
-		assert.Equal(t, tc.want.Name, got.Name)
-		assert.Equal(t, tc.want.Type, got.Type)
-	}
-}
-
 func TestTruncateValue(t *testing.T) {
 	type Header struct {
 		Key   ApiFields
@@ -179,6 +152,52 @@ func TestGetProtocol(t *testing.T) {
 	}
 }
 
+func TestIsGraphQLRequest(t *testing.T) {
+	newRequest := func(method string, target string, contentType string, body string) *http.Request {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		if contentType != "" {
+			r.Header.Set("Content-Type", contentType)
+		}
+		return r
+	}
+	endpoint, _ := compileURIPattern(DefaultGraphQLEndpointPatternValue)
+	chunked := newRequest(http.MethodPost, "/graphql", "application/json", `{"query":"{ a }"}`)
+	chunked.ContentLength = -1
+	chunked.TransferEncoding = []string{"chunked"}
+
+	tests := []struct {
+		name  string
+		want  bool
+		input *http.Request
+	}{
+		{"JSON", true, newRequest(http.MethodPost, "/graphql", "application/json", `{"query":"{ a }"}`)},
+		{"JSON with charset", true, newRequest(http.MethodPost, "/api/graphql", "Application/JSON; charset=utf-8", `{"query":"{ a }"}`)},
+		{"GraphQL document", true, newRequest(http.MethodPost, "/graphql", "application/graphql", `{ a }`)},
+		{"Multipart", true, newRequest(http.MethodPost, "/graphql", "multipart/form-data; boundary=x", `--x--`)},
+		{"GET query", true, newRequest(http.MethodGet, "/graphql?query=%7B+a+%7D", "", "")},
+		{"GET persisted query", true, newRequest(http.MethodGet, "/graphql?extensions=%7B%7D", "", "")},
+		{"GET without query", false, newRequest(http.MethodGet, "/graphql", "", "")},
+		{"Chunked body", true, chunked},
+		{"Empty body", false, newRequest(http.MethodPost, "/graphql", "application/json", "")},
+		{"Form", false, newRequest(http.MethodPost, "/graphql", "application/x-www-form-urlencoded", "query=a")},
+		{"Other path", false, newRequest(http.MethodPost, "/api", "application/json", `{"query":"{ a }"}`)},
+		{"Query string", false, newRequest(http.MethodPost, "/api?graphql", "application/json", `{"query":"{ a }"}`)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, isGraphQLRequest(tc.input, endpoint))
+		})
+	}
+
+	t.Run("Endpoint pattern", func(t *testing.T) {
+		endpoint, _ := compileURIPattern(`^/(api|query)$`)
+
+		assert.True(t, isGraphQLRequest(newRequest(http.MethodPost, "/query", "application/json", `{"query":"{ a }"}`), endpoint))
+		assert.False(t, isGraphQLRequest(newRequest(http.MethodPost, "/graphql", "application/json", `{"query":"{ a }"}`), endpoint))
+	})
+}
+
 func TestIsMatchingReferer(t *testing.T) {
 	reqWithoutReferrer := httptest.NewRequest(http.MethodGet, "http://example.com/foo?dd_referrer=", nil)
 	reqWithNotMatchingReferrer := httptest.NewRequest(http.MethodGet, "http://example.com/foo?dd_referrer=", nil)
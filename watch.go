@@ -0,0 +1,56 @@
+package modulego
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// WatchConfigFile checks the JSON configuration file every interval and updates the client when the file changes.
+// The file is loaded with [LoadConfig], so the `DATADOME_*` environment variables still override it,
+// and applied with [Client.UpdateConfig] along with the options.
+// An invalid or unreadable file is logged and the current settings are kept.
+//
+// WatchConfigFile blocks until ctx is done and returns its error.
+// An error is returned immediately when the file cannot be read on start.
+func (c *Client) WatchConfigFile(ctx context.Context, path string, interval time.Duration, options ...Option) error {
+	if interval <= 0 {
+		return fmt.Errorf("interval must be a positive duration")
+	}
+	if _, err := c.load(); err != nil {
+		return err
+	}
+	last, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("fail to read configuration file: %w", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			c.current().logger.Error("fail to read configuration file: ", err)
+			continue
+		}
+		if info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+			continue
+		}
+		last = info
+
+		cfg, err := LoadConfig(path)
+		if err == nil {
+			err = c.UpdateConfig(cfg, options...)
+		}
+		if err != nil {
+			c.current().logger.Error("fail to update the configuration, keeping the current one: ", err)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package modulego
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// syncLogger records the logged messages and can be used concurrently.
+type syncLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *syncLogger) log(level string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, level+" "+fmt.Sprint(args...))
+}
+
+func (l *syncLogger) Debug(args ...interface{}) { l.log("DEBUG", args...) }
+func (l *syncLogger) Info(args ...interface{})  { l.log("INFO", args...) }
+func (l *syncLogger) Warn(args ...interface{})  { l.log("WARN", args...) }
+func (l *syncLogger) Error(args ...interface{}) { l.log("ERROR", args...) }
+
+func (l *syncLogger) last() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.messages) == 0 {
+		return ""
+	}
+	return l.messages[len(l.messages)-1]
+}
+
+// writeConfigFile writes the configuration file with a modification time in the future,
+// so that successive writes are detected whatever the precision of the file system.
+func writeConfigFile(t *testing.T, path string, content string, age time.Duration) {
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0o600))
+	modTime := time.Now().Add(age)
+	assert.Nil(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestWatchConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "datadome.json")
+	writeConfigFile(t, path, `{"serverSideKey": "your-api-key", "timeout": 300}`, 0)
+	logger := &syncLogger{}
+	client, err := NewClient("your-api-key", WithTimeout(300), WithLogger(logger))
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- client.WatchConfigFile(ctx, path, 5*time.Millisecond, WithLogger(logger))
+	}()
+
+	t.Run("A valid change is applied", func(t *testing.T) {
+		// the file is written until the change is applied as the watcher may not be started yet
+		age := time.Duration(0)
+		assert.Eventually(t, func() bool {
+			if client.current().timeout == 500 {
+				return true
+			}
+			age += time.Minute
+			writeConfigFile(t, path, `{"serverSideKey": "another-api-key", "timeout": 500}`, age)
+			return false
+		}, time.Second, 20*time.Millisecond)
+		assert.Equal(t, "another-api-key", client.current().serverSideKey)
+		assert.Equal(t, logger, client.current().logger)
+	})
+
+	t.Run("An invalid change is rejected", func(t *testing.T) {
+		s := client.current()
+		writeConfigFile(t, path, `{"serverSideKey": "another-api-key", "timeout": 0}`, time.Hour)
+
+		assert.Eventually(t, func() bool {
+			return logger.last() == "ERROR fail to update the configuration, keeping the current one: invalid configuration: Timeout must be a positive integer"
+		}, time.Second, 5*time.Millisecond)
+		assert.Same(t, s, client.current())
+	})
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestWatchConfigFile_Errors(t *testing.T) {
+	client, err := NewClient("your-api-key")
+	assert.Nil(t, err)
+
+	t.Run("With a missing file", func(t *testing.T) {
+		err := client.WatchConfigFile(context.Background(), filepath.Join(t.TempDir(), "missing.json"), time.Second)
+
+		assert.ErrorContains(t, err, "fail to read configuration file")
+	})
+
+	t.Run("With an invalid interval", func(t *testing.T) {
+		err := client.WatchConfigFile(context.Background(), "datadome.json", 0)
+
+		assert.EqualError(t, err, "interval must be a positive duration")
+	})
+}
@@ -0,0 +1,354 @@
+package modulego
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// WithGraphQLWebSocketMessages is a functional option to evaluate the first subscribe (graphql-transport-ws)
+// or start (graphql-ws) messages of the GraphQL WebSocket connections with the Protection API, reporting their operation.
+// The connection is closed with the policy violation code (1008) when a message is blocked,
+// and with the message too big code (1009) when a text message larger than the MaximumBodySize
+// is sent before the messages are inspected, so that it cannot hide an operation.
+// The messages are only inspected by [Client.DatadomeHandler], once the upgrade request is evaluated,
+// when the GraphQL support is enabled and the path matches the GraphQLEndpointPattern.
+// It defaults to 0, no message being inspected.
+func WithGraphQLWebSocketMessages(messages int) Option {
+	return func(c *Client) {
+		c.GraphQLWebSocketMessages = messages
+	}
+}
+
+// isGraphQLWebSocket reports whether the request upgrades to a GraphQL WebSocket connection whose messages are inspected.
+func (s *settings) isGraphQLWebSocket(r *http.Request) bool {
+	if s.graphQLWebSocketMessages <= 0 || !s.enableGraphQLSupport || r.Method != http.MethodGet {
+		return false
+	}
+	if !headerContainsToken(r.Header, "Connection", "upgrade") || !headerContainsToken(r.Header, "Upgrade", "websocket") {
+		return false
+	}
+	if s.graphQLEndpoint == nil || !s.graphQLEndpoint.MatchString(r.URL.Path) {
+		return false
+	}
+	return headerContainsToken(r.Header, "Sec-WebSocket-Protocol", "graphql-transport-ws") ||
+		headerContainsToken(r.Header, "Sec-WebSocket-Protocol", "graphql-ws")
+}
+
+// headerContainsToken reports whether the comma-separated values of the header contain the token, ignoring the case.
+func headerContainsToken(header http.Header, name string, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, v := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// webSocketInspector is the [http.ResponseWriter] passed to the next handler of a GraphQL WebSocket upgrade request.
+// Once enabled, the connection returned by Hijack inspects the messages of the client.
+type webSocketInspector struct {
+	http.ResponseWriter
+	enabled  bool
+	messages int
+	// maxMessageSize is the size of the largest message inspected.
+	maxMessageSize   int
+	persistedQueries map[string]PersistedQuery
+	// evaluate returns the decision of the upgrade request reporting the GraphQL operation, or nil when it fails.
+	evaluate func(data *GraphQLData) *Decision
+	logger   Logger
+}
+
+// Hijack hijacks the connection of the underlying [http.ResponseWriter].
+func (i *webSocketInspector) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := i.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("the response writer does not implement http.Hijacker")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil || !i.enabled {
+		return conn, rw, err
+	}
+	if err := rw.Flush(); err != nil {
+		return nil, nil, err
+	}
+	ic := &inspectedConn{
+		Conn:      conn,
+		source:    rw.Reader,
+		inspector: i,
+		remaining: i.messages,
+	}
+	return ic, bufio.NewReadWriter(bufio.NewReader(ic), bufio.NewWriter(ic)), nil
+}
+
+// Unwrap returns the underlying [http.ResponseWriter], for [http.ResponseController].
+func (i *webSocketInspector) Unwrap() http.ResponseWriter {
+	return i.ResponseWriter
+}
+
+// errWebSocketBlocked is returned to the application reading a connection closed by the inspection of its messages.
+var errWebSocketBlocked = fmt.Errorf("GraphQL WebSocket connection blocked by DataDome")
+
+// errWebSocketMessageTooBig is returned to the application reading a connection closed on a message too large to be inspected.
+var errWebSocketMessageTooBig = fmt.Errorf("GraphQL WebSocket message too big to be inspected by DataDome")
+
+// WebSocket opcodes of the frames.
+const (
+	webSocketContinuation = 0x0
+	webSocketText         = 0x1
+	webSocketClose        = 0x8
+)
+
+// Status codes of the close frames.
+const (
+	// webSocketPolicyViolation is sent when a message is blocked.
+	webSocketPolicyViolation = 1008
+	// webSocketMessageTooBig is sent when a text message is too large to be inspected.
+	webSocketMessageTooBig = 1009
+)
+
+// inspectedConn is a hijacked connection holding the frames of the client until the first messages are evaluated.
+// The reads are expected from a single goroutine; the writes are serialized with the close frame sent on block.
+type inspectedConn struct {
+	net.Conn
+	source    io.Reader
+	inspector *webSocketInspector
+	remaining int
+
+	// held holds the frames of the message being read, pending the frames ready to be read by the application.
+	held    []byte
+	pending []byte
+	// message is the payload of the fragmented text message being read.
+	message    []byte
+	fragmented bool
+	// passthrough is the size of the payload left to be read by the application without being inspected.
+	passthrough uint64
+	err         error
+
+	mu sync.Mutex
+}
+
+// Read reads the frames of the client, once they are inspected.
+func (c *inspectedConn) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		if c.err != nil {
+			return 0, c.err
+		}
+		if c.remaining <= 0 {
+			return c.source.Read(p)
+		}
+		if c.passthrough > 0 {
+			if uint64(len(p)) > c.passthrough {
+				p = p[:c.passthrough]
+			}
+			n, err := c.source.Read(p)
+			c.passthrough -= uint64(n)
+			return n, err
+		}
+		c.nextFrame()
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+// Write writes to the connection, serialized with the close frame.
+func (c *inspectedConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Conn.Write(p)
+}
+
+// nextFrame reads the next frame of the client and inspects the message it completes.
+// The frames of a fragmented text message are held until it is complete.
+// The payload of the other frames larger than the maximum message size is passed through,
+// but the connection is closed on the text messages larger than it.
+func (c *inspectedConn) nextFrame() {
+	frame, err := readWebSocketFrame(c.source, c.inspector.maxMessageSize-len(c.message))
+	c.held = append(c.held, frame.raw...)
+	if err != nil {
+		c.err = err
+		c.release()
+		return
+	}
+	switch {
+	case frame.payload == nil && (frame.opcode == webSocketText || c.fragmented):
+		c.inspector.logger.Warn("GraphQL WebSocket message larger than MaximumBodySize, closing the connection.")
+		c.close(webSocketMessageTooBig, "Message Too Big", errWebSocketMessageTooBig)
+		return
+	case frame.payload == nil:
+		c.passthrough = frame.length
+	case frame.opcode >= webSocketClose:
+		// control frames may be interleaved with the fragments of a message
+	case frame.opcode == webSocketText && !c.fragmented, frame.opcode == webSocketContinuation && c.fragmented:
+		c.message = append(c.message, frame.payload...)
+		c.fragmented = !frame.fin
+		if frame.fin {
+			message := c.message
+			c.message = c.message[:0]
+			if c.inspect(message) {
+				return
+			}
+		}
+	}
+	if !c.fragmented {
+		c.release()
+	}
+}
+
+// release makes the held frames ready to be read.
+func (c *inspectedConn) release() {
+	c.pending = append(c.pending, c.held...)
+	c.held = c.held[:0]
+}
+
+// inspect evaluates the message when it starts a GraphQL operation, and closes the connection when it is blocked.
+// It reports whether the connection is closed.
+func (c *inspectedConn) inspect(message []byte) bool {
+	var m struct {
+		Type    string          `json:"type"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if json.Unmarshal(message, &m) != nil || (m.Type != "subscribe" && m.Type != "start") {
+		return false
+	}
+	c.remaining--
+	requests, _ := decodeGraphQLBody(bytes.NewReader(m.Payload))
+	data := aggregateGraphQLRequests(requests, c.inspector.persistedQueries)
+	if data == nil {
+		return false
+	}
+	decision := c.inspector.evaluate(data)
+	if decision == nil || !decision.Blocked {
+		return false
+	}
+
+	c.inspector.logger.Info("GraphQL WebSocket operation ", data.Name, " blocked, closing the connection.")
+	c.close(webSocketPolicyViolation, http.StatusText(decision.StatusCode), errWebSocketBlocked)
+	return true
+}
+
+// close sends the close frame with the status code and the reason, and closes the connection.
+// The held frames are dropped and err is returned by the next reads of the application.
+func (c *inspectedConn) close(code int, reason string, err error) {
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	frame := append([]byte{0x80 | webSocketClose, byte(2 + len(reason)), byte(code >> 8), byte(code)}, reason...)
+	c.mu.Lock()
+	_, _ = c.Conn.Write(frame)
+	c.mu.Unlock()
+	_ = c.Conn.Close()
+	c.held = nil
+	c.message = nil
+	c.fragmented = false
+	c.err = err
+}
+
+// webSocketFrame is a frame sent by a WebSocket client.
+type webSocketFrame struct {
+	// raw holds the bytes read.
+	raw    []byte
+	fin    bool
+	opcode byte
+	length uint64
+	// payload is the unmasked payload, nil when it is larger than the maximum size and not read.
+	payload []byte
+}
+
+// readWebSocketFrame reads the next frame of the client from r.
+// When its payload is larger than maxPayloadSize, only the header is read.
+func readWebSocketFrame(r io.Reader, maxPayloadSize int) (webSocketFrame, error) {
+	var frame webSocketFrame
+	var err error
+	if frame.raw, err = readAppend(r, make([]byte, 0, 14), 2); err != nil {
+		return frame, err
+	}
+	frame.fin = frame.raw[0]&0x80 != 0
+	frame.opcode = frame.raw[0] & 0x0f
+	masked := frame.raw[1]&0x80 != 0
+	frame.length = uint64(frame.raw[1] & 0x7f)
+	switch frame.length {
+	case 126:
+		if frame.raw, err = readAppend(r, frame.raw, 2); err != nil {
+			return frame, err
+		}
+		frame.length = uint64(frame.raw[2])<<8 | uint64(frame.raw[3])
+	case 127:
+		if frame.raw, err = readAppend(r, frame.raw, 8); err != nil {
+			return frame, err
+		}
+		frame.length = 0
+		for _, b := range frame.raw[2:10] {
+			frame.length = frame.length<<8 | uint64(b)
+		}
+	}
+	var mask []byte
+	if masked {
+		if frame.raw, err = readAppend(r, frame.raw, 4); err != nil {
+			return frame, err
+		}
+		mask = frame.raw[len(frame.raw)-4:]
+	}
+	if maxPayloadSize < 0 || frame.length > uint64(maxPayloadSize) {
+		return frame, nil
+	}
+
+	header := len(frame.raw)
+	if frame.raw, err = readAppend(r, frame.raw, int(frame.length)); err != nil {
+		return frame, err
+	}
+	frame.payload = make([]byte, frame.length)
+	copy(frame.payload, frame.raw[header:])
+	if mask != nil {
+		for i := range frame.payload {
+			frame.payload[i] ^= mask[i%4]
+		}
+	}
+	return frame, nil
+}
+
+// readAppend reads n bytes from r and appends them to buf, along with the bytes read before an error.
+func readAppend(r io.Reader, buf []byte, n int) ([]byte, error) {
+	start := len(buf)
+	buf = append(buf, make([]byte, n)...)
+	read, err := io.ReadFull(r, buf[start:])
+	return buf[:start+read], err
+}
+
+// protectGraphQLWebSocket evaluates the upgrade request and calls next with a response writer inspecting the messages
+// of the hijacked connection, unless the request is blocked or skipped.
+func (c *Client) protectGraphQLWebSocket(s *settings, w http.ResponseWriter, r *http.Request, next http.Handler) (bool, error) {
+	inspector := &webSocketInspector{
+		ResponseWriter:   w,
+		messages:         s.graphQLWebSocketMessages,
+		maxMessageSize:   s.maximumBodySize,
+		persistedQueries: s.graphQLPersistedQueries,
+		logger:           s.logger,
+	}
+	// the connection outlives the upgrade request
+	ctx := context.WithoutCancel(r.Context())
+	upgrade := r.Clone(ctx)
+	inspector.evaluate = func(data *GraphQLData) *Decision {
+		decision, err := c.Evaluate(upgrade.Clone(ContextWithGraphQLData(ctx, *data)))
+		if err != nil {
+			return nil
+		}
+		return decision
+	}
+	evaluate := func(r *http.Request) (*Decision, error) {
+		decision, err := c.Evaluate(r)
+		inspector.enabled = err == nil && decision.SkipReason == ""
+		return decision, err
+	}
+	return protect(evaluate, s.logger, inspector, r, next)
+}
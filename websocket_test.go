@@ -0,0 +1,290 @@
+package modulego
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// clientFrame returns a frame masked like the frames sent by a WebSocket client.
+func clientFrame(opcode byte, fin bool, payload string) []byte {
+	b := opcode
+	if fin {
+		b |= 0x80
+	}
+	frame := []byte{b}
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, 0x80|byte(len(payload)))
+	case len(payload) < 1<<16:
+		frame = append(frame, 0x80|126, byte(len(payload)>>8), byte(len(payload)))
+	default:
+		frame = append(frame, 0x80|127, 0, 0, 0, 0, byte(len(payload)>>24), byte(len(payload)>>16), byte(len(payload)>>8), byte(len(payload)))
+	}
+	mask := []byte{0x12, 0x34, 0x56, 0x78}
+	frame = append(frame, mask...)
+	for i := 0; i < len(payload); i++ {
+		frame = append(frame, payload[i]^mask[i%4])
+	}
+	return frame
+}
+
+// subscribeMessage returns a graphql-transport-ws subscribe message.
+func subscribeMessage(query string) string {
+	return fmt.Sprintf(`{"id":"1","type":"subscribe","payload":{"query":%q}}`, query)
+}
+
+// recordingConn is a [net.Conn] recording the bytes written and whether it is closed.
+type recordingConn struct {
+	net.Conn
+	written bytes.Buffer
+	closed  bool
+}
+
+func (c *recordingConn) Write(p []byte) (int, error) {
+	return c.written.Write(p)
+}
+
+func (c *recordingConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestInspectedConn(t *testing.T) {
+	newConn := func(stream []byte, evaluated *[]string) (*inspectedConn, *recordingConn) {
+		conn := &recordingConn{}
+		inspector := &webSocketInspector{
+			maxMessageSize: 128,
+			logger:         &syncLogger{},
+			evaluate: func(data *GraphQLData) *Decision {
+				*evaluated = append(*evaluated, string(data.Type)+" "+data.Name)
+				return &Decision{Blocked: data.Name == "Secret", StatusCode: http.StatusForbidden}
+			},
+		}
+		return &inspectedConn{Conn: conn, source: bytes.NewReader(stream), inspector: inspector, remaining: 2}, conn
+	}
+
+	t.Run("First messages are evaluated", func(t *testing.T) {
+		message := subscribeMessage("subscription OnPrice { price }")
+		stream := bytes.Join([][]byte{
+			clientFrame(0x9, true, "ping"),
+			clientFrame(webSocketText, true, `{"type":"connection_init"}`),
+			clientFrame(webSocketText, false, message[:20]),
+			clientFrame(0x9, true, "ping"),
+			clientFrame(webSocketContinuation, true, message[20:]),
+			clientFrame(webSocketText, true, `{"id":"2","type":"start","payload":{"query":"mutation Pay { pay }"}}`),
+			clientFrame(webSocketText, true, subscribeMessage("subscription Secret { secret }")),
+		}, nil)
+		var evaluated []string
+		ic, conn := newConn(stream, &evaluated)
+
+		got, err := io.ReadAll(ic)
+
+		assert.Nil(t, err)
+		assert.Equal(t, stream, got)
+		assert.Equal(t, []string{"subscription OnPrice", "mutation Pay"}, evaluated)
+		assert.False(t, conn.closed)
+	})
+
+	t.Run("Blocked message closes the connection", func(t *testing.T) {
+		stream := bytes.Join([][]byte{
+			clientFrame(webSocketText, true, subscribeMessage("subscription Secret { secret }")),
+			clientFrame(webSocketText, true, subscribeMessage("subscription OnPrice { price }")),
+		}, nil)
+		var evaluated []string
+		ic, conn := newConn(stream, &evaluated)
+
+		got, err := io.ReadAll(ic)
+
+		assert.ErrorIs(t, err, errWebSocketBlocked)
+		assert.Empty(t, got)
+		assert.Equal(t, append([]byte{0x88, 11, 0x03, 0xf0}, "Forbidden"...), conn.written.Bytes())
+		assert.True(t, conn.closed)
+	})
+
+	t.Run("Large binary frame is passed through", func(t *testing.T) {
+		stream := bytes.Join([][]byte{
+			clientFrame(0x2, true, strings.Repeat("a", 1000)),
+			clientFrame(webSocketText, true, subscribeMessage("subscription Secret { secret }")),
+		}, nil)
+		var evaluated []string
+		ic, _ := newConn(stream, &evaluated)
+
+		_, err := io.ReadAll(ic)
+
+		assert.ErrorIs(t, err, errWebSocketBlocked)
+		assert.Equal(t, []string{"subscription Secret"}, evaluated)
+	})
+
+	t.Run("Large text message closes the connection", func(t *testing.T) {
+		stream := bytes.Join([][]byte{
+			clientFrame(webSocketText, false, strings.Repeat("a", 100)),
+			clientFrame(webSocketContinuation, true, strings.Repeat("a", 100)),
+			clientFrame(webSocketText, true, subscribeMessage("subscription Secret { secret }")),
+		}, nil)
+		var evaluated []string
+		ic, conn := newConn(stream, &evaluated)
+
+		got, err := io.ReadAll(ic)
+
+		assert.ErrorIs(t, err, errWebSocketMessageTooBig)
+		assert.Empty(t, got)
+		assert.Empty(t, evaluated)
+		assert.Equal(t, append([]byte{0x88, 17, 0x03, 0xf1}, "Message Too Big"...), conn.written.Bytes())
+		assert.True(t, conn.closed)
+	})
+
+	t.Run("Large text message after the inspected messages", func(t *testing.T) {
+		stream := bytes.Join([][]byte{
+			clientFrame(webSocketText, true, subscribeMessage("subscription OnPrice { price }")),
+			clientFrame(webSocketText, true, subscribeMessage("subscription OnRate { rate }")),
+			clientFrame(webSocketText, true, strings.Repeat("a", 1000)),
+		}, nil)
+		var evaluated []string
+		ic, conn := newConn(stream, &evaluated)
+
+		got, err := io.ReadAll(ic)
+
+		assert.Nil(t, err)
+		assert.Equal(t, stream, got)
+		assert.Equal(t, []string{"subscription OnPrice", "subscription OnRate"}, evaluated)
+		assert.False(t, conn.closed)
+	})
+
+	t.Run("Truncated frame", func(t *testing.T) {
+		stream := clientFrame(webSocketText, true, subscribeMessage("subscription OnPrice { price }"))
+		var evaluated []string
+		ic, _ := newConn(stream[:10], &evaluated)
+
+		got, err := io.ReadAll(ic)
+
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Equal(t, stream[:10], got)
+		assert.Empty(t, evaluated)
+	})
+}
+
+func TestEvaluate_GraphQLWebSocket(t *testing.T) {
+	// the Protection API blocks the Secret operation and the bot
+	var mu sync.Mutex
+	var operations []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		mu.Lock()
+		operations = append(operations, r.PostForm.Get("GraphQLOperationType")+" "+r.PostForm.Get("GraphQLOperationName"))
+		mu.Unlock()
+		if r.PostForm.Get("GraphQLOperationName") == "Secret" || r.PostForm.Get("UserAgent") == "bot" {
+			w.Header().Set("X-Datadomeresponse", "403")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("X-Datadomeresponse", "200")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer api.Close()
+
+	logger := &syncLogger{}
+	client, err := NewClient("your-api-key",
+		WithLogger(logger),
+		WithEndpoint(api.URL),
+		WithGraphQLSupport(true),
+		WithGraphQLWebSocketMessages(1),
+	)
+	assert.Nil(t, err)
+
+	// the application upgrades the connection and reports the text messages
+	received := make(chan string, 10)
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		_ = rw.Flush()
+		for {
+			frame, err := readWebSocketFrame(rw, 1024)
+			if err != nil {
+				close(received)
+				return
+			}
+			received <- string(frame.payload)
+		}
+	})
+	server := httptest.NewServer(client.DatadomeHandler(app))
+	defer server.Close()
+
+	dial := func(userAgent string) (net.Conn, *bufio.Reader, *http.Response) {
+		conn, err := net.Dial("tcp", server.Listener.Addr().String())
+		assert.Nil(t, err)
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/graphql", nil)
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Sec-WebSocket-Protocol", "graphql-transport-ws")
+		req.Header.Set("User-Agent", userAgent)
+		assert.Nil(t, req.Write(conn))
+		br := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(br, req)
+		assert.Nil(t, err)
+		return conn, br, resp
+	}
+
+	t.Run("Blocked upgrade request", func(t *testing.T) {
+		conn, _, resp := dial("bot")
+		defer conn.Close()
+
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Blocked subscription", func(t *testing.T) {
+		conn, br, resp := dial("browser")
+		defer conn.Close()
+		assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+		_, err := conn.Write(clientFrame(webSocketText, true, subscribeMessage("subscription Secret { secret }")))
+		assert.Nil(t, err)
+
+		frame, err := readWebSocketFrame(br, 125)
+		assert.Nil(t, err)
+		assert.Equal(t, byte(webSocketClose), frame.opcode)
+		assert.Equal(t, append([]byte{0x03, 0xf0}, "Forbidden"...), frame.payload)
+		_, open := <-received
+		assert.False(t, open)
+		assert.Equal(t, "INFO GraphQL WebSocket operation Secret blocked, closing the connection.", logger.last())
+		mu.Lock()
+		assert.Contains(t, operations, "subscription Secret")
+		mu.Unlock()
+	})
+
+	t.Run("Not a GraphQL WebSocket", func(t *testing.T) {
+		s := client.current()
+		r := httptest.NewRequest(http.MethodGet, "/graphql", nil)
+		r.Header.Set("Connection", "keep-alive, Upgrade")
+		r.Header.Set("Upgrade", "websocket")
+		assert.False(t, s.isGraphQLWebSocket(r))
+
+		r.Header.Set("Sec-WebSocket-Protocol", "chat, graphql-ws")
+		assert.True(t, s.isGraphQLWebSocket(r))
+
+		r.URL.Path = "/chat"
+		assert.False(t, s.isGraphQLWebSocket(r))
+	})
+
+	t.Run("Invalid number of messages", func(t *testing.T) {
+		_, err := NewClient("your-api-key", WithGraphQLWebSocketMessages(-1))
+
+		assert.EqualError(t, err, "GraphQLWebSocketMessages must be a positive integer")
+	})
+}